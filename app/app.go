@@ -1,11 +1,14 @@
 package app
 
 import (
+	"github.com/bytelang/kplayer/core"
 	"github.com/bytelang/kplayer/module"
 	outputm "github.com/bytelang/kplayer/module/output"
 	playm "github.com/bytelang/kplayer/module/play"
 	pluginm "github.com/bytelang/kplayer/module/plugin"
 	resourcem "github.com/bytelang/kplayer/module/resource"
+	kptypes "github.com/bytelang/kplayer/types"
+	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 )
 
@@ -16,31 +19,44 @@ const (
 	ConfigVersion         = "2.0.0"
 )
 
-var (
-	ModuleManager = newModuleManager()
-)
+// NewModuleManager return the module manager of the built-in modules and the modules registered, running on the engine.
+// it must be called after the downstream modules registered
+func NewModuleManager(engine core.Engine) module.ModuleManager {
+	playProvider := playm.NewAppModule(engine)
+	outputProvider := outputm.NewAppModule(engine)
+	resourceProvider := resourcem.NewAppModule(engine, playProvider)
+	pluginProvider := pluginm.NewAppModule(engine)
+	modules := []module.AppModule{playProvider, outputProvider, resourceProvider, pluginProvider}
+
+	// modules registered by downstream binaries
+	for _, factory := range module.GetRegisteredFactories() {
+		modules = append(modules, factory(engine))
+	}
+
+	mm, err := module.NewModuleManager(modules...)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// PlayConfig dumps the effective config of all modules, PlaySaveConfig and persist mode write it back
+	playProvider.SetConfigDumper(func(ctx *kptypes.ClientContext) ([]byte, error) {
+		return DumpConfig(ctx, mm, "json", false)
+	})
+	playProvider.SetConfigSaver(func(ctx *kptypes.ClientContext) ([]string, error) {
+		return SaveConfig(ctx, mm)
+	})
+
+	// PlayPrevious and PlayJump select the resource of resource module
+	playProvider.SetResourceJumper(resourceProvider.JumpResource)
 
-func newModuleManager() module.ModuleManager {
-	playProvider := playm.NewAppModule()
-	outputProvider := outputm.NewAppModule()
-	resourceProvider := resourcem.NewAppModule(playProvider)
-	pluginProvider := pluginm.NewAppModule()
-	mm := module.NewModuleManager(
-		playProvider, outputProvider, resourceProvider, pluginProvider,
-	)
-
-	mm.SetOrderInitConfig(
-		playProvider.GetModuleName(),
-		outputProvider.GetModuleName(),
-		resourceProvider.GetModuleName(),
-		pluginProvider.GetModuleName(),
-	)
+	// PlayStatus reports the playback mode of resource module
+	playProvider.SetResourceStatusReporter(resourceProvider.PlaybackStatus)
 
 	return mm
 }
 
-func AddModuleCommands(rootCmd *cobra.Command) {
-	for _, m := range ModuleManager.Modules {
+func AddModuleCommands(rootCmd *cobra.Command, mm module.ModuleManager) {
+	for _, m := range mm.Modules {
 		if cmd := m.GetCommand(); cmd != nil {
 			rootCmd.AddCommand(cmd)
 		}
@@ -57,3 +73,17 @@ func AddInitCommands() *cobra.Command {
 
 	return cmd
 }
+
+func AddConfigCommands(mm module.ModuleManager) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "config",
+		Short: "Config file tools",
+	}
+	cmd.AddCommand(addConfigValidateCommands())
+	cmd.AddCommand(addConfigSchemaCommands())
+	cmd.AddCommand(addConfigMigrateCommands())
+	cmd.AddCommand(addConfigDumpCommands(mm))
+	cmd.AddCommand(addConfigSaveCommands())
+
+	return cmd
+}
@@ -0,0 +1,266 @@
+package app
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	kptypes "github.com/bytelang/kplayer/types"
+	"github.com/bytelang/kplayer/types/config"
+	errortypes "github.com/bytelang/kplayer/types/error"
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+	"github.com/tidwall/gjson"
+	"google.golang.org/protobuf/types/known/anypb"
+)
+
+// LoadedConfig config read from the config file
+type LoadedConfig struct {
+	Viper  *viper.Viper
+	Config *config.KPConfig
+
+	// settings of the config file. the sections of registered modules are not a part of KPConfig
+	RawSettings map[string]interface{}
+
+	// absolute paths of the config file, included files and conf.d files in merged order
+	Files []string
+}
+
+// NewConfigViper return viper of the config file in working directory
+func NewConfigViper(configFileName string) *viper.Viper {
+	v := viper.New()
+	v.AddConfigPath(".")
+	v.SetConfigType("json")
+	if !kptypes.FileExists(configFileName) && !kptypes.FileExists(configFileName+".json") {
+		v.SetConfigType("yaml")
+	}
+	v.SetConfigName(configFileName)
+
+	return v
+}
+
+// LoadConfig read and validate the config file, set the default value and unpack the resource list.
+// the generate cache mode only push to file
+func LoadConfig(configFileName string, generateCache bool) (*LoadedConfig, error) {
+	loadedConfig, err := readConfig(configFileName, generateCache)
+	if err != nil {
+		return nil, err
+	}
+
+	// validate global config
+	if err := ValidateConfig(loadedConfig.Config); err != nil {
+		return nil, err
+	}
+
+	return loadedConfig, nil
+}
+
+func readConfig(configFileName string, generateCache bool) (*LoadedConfig, error) {
+	v := NewConfigViper(configFileName)
+
+	// load config context in file
+	if err := v.ReadInConfig(); err != nil {
+		return nil, err
+	}
+
+	// merge the included files and conf.d directory, interpolate the environment variables
+	settings, files, err := readLayeredConfig(v.ConfigFileUsed())
+	if err != nil {
+		return nil, err
+	}
+	layeredConfig, err := json.Marshal(settings)
+	if err != nil {
+		return nil, err
+	}
+	v.SetConfigType("json")
+	if err := v.ReadConfig(bytes.NewBuffer(layeredConfig)); err != nil {
+		return nil, err
+	}
+
+	// set default value
+	setDefaultConfig(v)
+
+	// KPLAYER_* environment overrides
+	bindConfigEnv(v)
+
+	// sections of registered modules are not a part of the global config
+	rawSettings := v.AllSettings()
+
+	// refill resource list proto.any
+	// viper decode not support protobuf any unpack
+	// prepare constructing a resource list
+	var resourceLists []*anypb.Any
+	items, _ := v.Get("resource.lists").([]interface{})
+	for _, item := range items {
+		res, err := unpackResourceItem(item)
+		if err != nil {
+			log.WithField("content", item).Warn("unrecognized resource structure")
+			continue
+		}
+		any, err := ptypes.MarshalAny(res)
+		if err != nil {
+			return nil, fmt.Errorf("unmarshal any failed. error: %s", err)
+		}
+		resourceLists = append(resourceLists, any)
+	}
+	v.Set("resource.lists", map[string]interface{}{})
+
+	// unmarshal config
+	cfg := &config.KPConfig{}
+	if err := v.Unmarshal(cfg); err != nil {
+		return nil, err
+	}
+
+	// set resource list
+	cfg.Resource.Lists = resourceLists
+
+	// custom config
+	if generateCache {
+		cfg.Play.PlayModel = strings.ToLower(config.PLAY_MODEL_name[int32(config.PLAY_MODEL_LIST)])
+		cfg.Play.EncodeModel = strings.ToLower(config.ENCODE_MODEL_name[int32(config.ENCODE_MODEL_FILE)])
+		cfg.Output.Lists = nil
+		cfg.Play.CacheOn = true
+	}
+
+	reInitConfig, err := json.Marshal(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if err := v.ReadConfig(bytes.NewBuffer(reInitConfig)); err != nil {
+		return nil, err
+	}
+
+	return &LoadedConfig{
+		Viper:       v,
+		Config:      cfg,
+		RawSettings: rawSettings,
+		Files:       files,
+	}, nil
+}
+
+// unpackResourceItem return the single, mix or directory resource of the resource list item in config file
+func unpackResourceItem(item interface{}) (proto.Message, error) {
+	switch itemResource := item.(type) {
+	case string:
+		return &config.SingleResource{
+			Path: itemResource,
+		}, nil
+	case map[string]interface{}:
+		bytes, err := json.Marshal(itemResource)
+		if err != nil {
+			return nil, err
+		}
+
+		// single resource
+		singleResource := &config.SingleResource{}
+		if err := kptypes.UnmarshalProtoMessageContinue(string(bytes), singleResource); err == nil {
+			return singleResource, nil
+		}
+
+		// mix resource
+		mixResource := &config.MixResource{}
+		if err := kptypes.UnmarshalProtoMessageContinue(string(bytes), mixResource); err == nil {
+			return mixResource, nil
+		}
+
+		// directory resource
+		directoryResource := &config.DirectoryResource{}
+		if err := kptypes.UnmarshalProtoMessageContinue(string(bytes), directoryResource); err == nil {
+			return directoryResource, nil
+		}
+	}
+
+	return nil, fmt.Errorf("unrecognized resource structure")
+}
+
+// GetModuleSection return the config section of module
+func (c *LoadedConfig) GetModuleSection(name string) (json.RawMessage, error) {
+	d, err := json.Marshal(c.Config)
+	if err != nil {
+		return nil, err
+	}
+
+	section := gjson.Parse(string(d)).Get(name).String()
+	if rawSection, ok := c.RawSettings[name]; ok && section == "" {
+		raw, err := json.Marshal(rawSection)
+		if err != nil {
+			return nil, err
+		}
+		section = string(raw)
+	}
+
+	return json.RawMessage(section), nil
+}
+
+func ValidateConfig(config *config.KPConfig) error {
+	if config.Version != ConfigVersion {
+		if _, ok := getMigration(config.Version); ok || config.Version == "" {
+			return fmt.Errorf("%s. expected: %s, current: %s. run `%s config migrate` to upgrade config file", errortypes.VersionInvalidMainError, ConfigVersion, config.Version, AppName)
+		}
+		return errortypes.VersionInvalidMainError
+	}
+
+	// load user token file
+	if config.TokenPath != "" {
+		if !kptypes.FileExists(config.TokenPath) {
+			return errortypes.TokenFileNotFoundMainError
+		}
+
+		fileContent, err := ioutil.ReadFile(config.TokenPath)
+		if err != nil {
+			return err
+		}
+
+		if err := kptypes.LoadClientToken(string(fileContent)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func setDefaultConfig(v *viper.Viper) {
+	v.SetDefault("play.start_point", 1)
+	v.SetDefault("play.play_model", "list")
+	v.SetDefault("play.encode_model", "rtmp")
+	v.SetDefault("play.cache_on", false)
+	v.SetDefault("play.cache_uncheck", false)
+	v.SetDefault("play.skip_invalid_resource", false)
+	v.SetDefault("play.delay_queue_size", 50)
+	v.SetDefault("play.fill_strategy", "tile")
+	v.SetDefault("play.persist", false)
+	v.SetDefault("play.resume", false)
+
+	v.SetDefault("play.rpc.on", true)
+	v.SetDefault("play.rpc.http_port", kptypes.DefaultHttpPort)
+	v.SetDefault("play.rpc.grpc_port", kptypes.DefaultRPCPort)
+	v.SetDefault("play.rpc.address", kptypes.DefaultRPCAddress)
+	v.SetDefault("play.rpc.timeout", kptypes.DefaultRPCTimeout)
+
+	v.SetDefault("play.watchdog.on", false)
+	v.SetDefault("play.watchdog.max_restarts", kptypes.DefaultWatchdogMaxRestarts)
+	v.SetDefault("play.watchdog.stall_timeout", kptypes.DefaultWatchdogStallTimeout)
+	v.SetDefault("play.watchdog.max_backoff", kptypes.DefaultWatchdogMaxBackoff)
+
+	v.SetDefault("play.encode.video_width", 854)
+	v.SetDefault("play.encode.video_height", 480)
+	v.SetDefault("play.encode.video_fps", 25)
+	v.SetDefault("play.encode.audio_channel_layout", 3)
+	v.SetDefault("play.encode.audio_channels", 2)
+	v.SetDefault("play.encode.audio_sample_rate", 44100)
+	v.SetDefault("play.encode.bit_rate", 0)
+	v.SetDefault("play.encode.avg_quality", 0)
+
+	v.SetDefault("resource.hot_folder.scan_interval", kptypes.DefaultHotFolderScanInterval)
+	v.SetDefault("resource.hot_folder.stable_time", kptypes.DefaultHotFolderStableTime)
+	v.SetDefault("resource.live.max_retries", kptypes.DefaultLiveMaxRetries)
+	v.SetDefault("resource.live.retry_interval", kptypes.DefaultLiveRetryInterval)
+	v.SetDefault("resource.live.max_backoff", kptypes.DefaultLiveMaxBackoff)
+
+	// auth
+	v.SetDefault("auth.auth_on", false)
+}
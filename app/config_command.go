@@ -0,0 +1,188 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/bytelang/kplayer/module"
+	playprovider "github.com/bytelang/kplayer/module/play/provider"
+	kptypes "github.com/bytelang/kplayer/types"
+	"github.com/bytelang/kplayer/types/client"
+	kpserver "github.com/bytelang/kplayer/types/server"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// AnnotationSkipLoadConfig the commands work on config file itself, the config and modules are not initialized
+const AnnotationSkipLoadConfig = "skip_load_config"
+
+var skipLoadConfigAnnotations = map[string]string{AnnotationSkipLoadConfig: "true"}
+
+const (
+	flagConfigOutputJson  = "json"
+	flagConfigDryRun      = "dry-run"
+	flagConfigFormat      = "format"
+	flagConfigShowSecrets = "show-secrets"
+)
+
+func addConfigValidateCommands() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:          "validate",
+		Annotations:  skipLoadConfigAnnotations,
+		Short:        "validate config file and report all the problems",
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			configFileName, err := kptypes.GetConfigFileName(cmd)
+			if err != nil {
+				return err
+			}
+
+			problems := CheckConfig(configFileName)
+			outputJson, _ := cmd.Flags().GetBool(flagConfigOutputJson)
+			if outputJson {
+				if problems == nil {
+					problems = []ConfigProblem{}
+				}
+				d, err := json.MarshalIndent(problems, "", "    ")
+				if err != nil {
+					return err
+				}
+				fmt.Println(string(d))
+			} else {
+				for _, item := range problems {
+					fmt.Println(item.String())
+				}
+			}
+
+			if len(problems) != 0 {
+				return fmt.Errorf("config invalid. %d problems found", len(problems))
+			}
+			if !outputJson {
+				fmt.Println("config valid")
+			}
+			return nil
+		},
+	}
+	cmd.Flags().Bool(flagConfigOutputJson, false, "print the problems as json")
+
+	return cmd
+}
+
+func addConfigSchemaCommands() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:         "schema",
+		Annotations: skipLoadConfigAnnotations,
+		Short:       "export JSON Schema of config file",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			schema, err := ConfigSchema()
+			if err != nil {
+				return err
+			}
+
+			fmt.Println(string(schema))
+			return nil
+		},
+	}
+
+	return cmd
+}
+
+func addConfigMigrateCommands() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:          "migrate",
+		Annotations:  skipLoadConfigAnnotations,
+		Short:        "migrate config file to the current version",
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			configFileName, err := kptypes.GetConfigFileName(cmd)
+			if err != nil {
+				return err
+			}
+
+			dryRun, _ := cmd.Flags().GetBool(flagConfigDryRun)
+			backupPath, changes, err := MigrateConfigFile(configFileName, dryRun)
+			if err != nil {
+				return err
+			}
+			if len(changes) == 0 {
+				fmt.Printf("config is the current version %s. nothing to migrate\n", ConfigVersion)
+				return nil
+			}
+
+			for _, item := range changes {
+				fmt.Println(item)
+			}
+			if !dryRun {
+				fmt.Printf("config migrated to version %s. backup: %s\n", ConfigVersion, backupPath)
+			}
+			return nil
+		},
+	}
+	cmd.Flags().Bool(flagConfigDryRun, false, "print the changes without rewriting config file")
+
+	return cmd
+}
+
+// addConfigDumpCommands the config and modules are initialized before dump as play start
+func addConfigDumpCommands(mm module.ModuleManager) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "dump",
+		Short: "print the effective config resolved from config files, defaults and modules",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			clientCtx := kptypes.GetClientContextFromCommand(cmd)
+
+			format, _ := cmd.Flags().GetString(flagConfigFormat)
+			showSecrets, _ := cmd.Flags().GetBool(flagConfigShowSecrets)
+			d, err := DumpConfig(clientCtx, mm, format, showSecrets)
+			if err != nil {
+				return err
+			}
+
+			fmt.Println(strings.TrimSuffix(string(d), "\n"))
+			return nil
+		},
+	}
+	cmd.Flags().String(flagConfigFormat, "json", "output format. json or yaml")
+	cmd.Flags().Bool(flagConfigShowSecrets, false, "print the secrets without redaction")
+	cmd.Flags().BoolP(playprovider.FlagGenerateCache, "g", false, "dump the config of generate cache mode")
+
+	return cmd
+}
+
+func addConfigSaveCommands() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "save",
+		Short: "write the resources, outputs and plugins of running kplayer back to config file",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// get client ctx
+			clientCtx := kptypes.GetClientContextFromCommand(cmd)
+
+			// request
+			conn, err := client.GrpcClientRequest(clientCtx.Config.Play.Rpc)
+			if err != nil {
+				return err
+			}
+
+			playClient := kpserver.NewPlayGreeterClient(conn)
+			reply, err := playClient.PlaySaveConfig(context.Background(), &kpserver.PlaySaveConfigArgs{})
+			if err != nil {
+				log.Error(err)
+				return nil
+			}
+
+			if len(reply.Files) == 0 {
+				fmt.Println("config not changed")
+				return nil
+			}
+			for _, item := range reply.Files {
+				fmt.Printf("config saved. path: %s\n", item)
+			}
+
+			return nil
+		},
+	}
+
+	return cmd
+}
@@ -91,6 +91,13 @@ func getDefaultConfig() *config.KPConfig {
 				Address:  types.DefaultRPCAddress,
 				GrpcPort: types.DefaultRPCPort,
 				HttpPort: types.DefaultHttpPort,
+				Timeout:  types.DefaultRPCTimeout,
+			},
+			Watchdog: &config.Watchdog{
+				On:           false,
+				MaxRestarts:  types.DefaultWatchdogMaxRestarts,
+				StallTimeout: types.DefaultWatchdogStallTimeout,
+				MaxBackoff:   types.DefaultWatchdogMaxBackoff,
 			},
 			Encode: &config.Encode{
 				VideoWidth:         780,
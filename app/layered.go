@@ -0,0 +1,237 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/bytelang/kplayer/types/config"
+	"github.com/ghodss/yaml"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+const (
+	// ConfigIncludeKey the list of config files merged after the file declared it
+	ConfigIncludeKey = "include"
+	// ConfigDirectoryName the directory beside the config file, the files in it are merged by name order
+	ConfigDirectoryName = "conf.d"
+	// ConfigEnvPrefix the prefix of environment overrides. KPLAYER_PLAY_RPC_HTTP_PORT overrides play.rpc.http_port
+	ConfigEnvPrefix = "KPLAYER"
+)
+
+// ${NAME} or ${NAME:-default}
+var configEnvPattern = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)(:-([^}]*))?\}`)
+
+// readLayeredConfig read the config file with the included files and the conf.d directory.
+// the maps are merged deeply, the later file replace the other values. return the merged settings and the read files
+func readLayeredConfig(configFilePath string) (map[string]interface{}, []string, error) {
+	settings := map[string]interface{}{}
+	var files []string
+	if err := mergeConfigFile(settings, configFilePath, map[string]bool{}, &files); err != nil {
+		return nil, nil, err
+	}
+
+	confDir := filepath.Join(filepath.Dir(configFilePath), ConfigDirectoryName)
+	if stat, err := os.Stat(confDir); err == nil && stat.IsDir() {
+		entries, err := ioutil.ReadDir(confDir)
+		if err != nil {
+			return nil, nil, err
+		}
+		var names []string
+		for _, item := range entries {
+			if !item.IsDir() && isConfigFileExtension(item.Name()) {
+				names = append(names, item.Name())
+			}
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if err := mergeConfigFile(settings, filepath.Join(confDir, name), map[string]bool{}, &files); err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+
+	interpolateConfigEnv(settings)
+	return settings, files, nil
+}
+
+func mergeConfigFile(settings map[string]interface{}, path string, visited map[string]bool, files *[]string) error {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+	if visited[absPath] {
+		return fmt.Errorf("config file included circularly. path: %s", path)
+	}
+	visited[absPath] = true
+	defer delete(visited, absPath)
+
+	fileSettings, _, err := decodeConfigFile(path)
+	if err != nil {
+		return fmt.Errorf("read config file failed. path: %s, error: %s", path, err)
+	}
+	*files = append(*files, absPath)
+
+	includes := fileSettings[ConfigIncludeKey]
+	delete(fileSettings, ConfigIncludeKey)
+	mergeSettings(settings, fileSettings)
+
+	var includePaths []string
+	switch item := includes.(type) {
+	case nil:
+	case string:
+		includePaths = []string{item}
+	case []interface{}:
+		for _, includePath := range item {
+			str, ok := includePath.(string)
+			if !ok {
+				return fmt.Errorf("include item must be path string. path: %s, item: %v", path, includePath)
+			}
+			includePaths = append(includePaths, str)
+		}
+	default:
+		return fmt.Errorf("include must be path list. path: %s", path)
+	}
+
+	// the included paths are relative to the file declared them and can be glob patterns
+	for _, includePath := range includePaths {
+		if !filepath.IsAbs(includePath) {
+			includePath = filepath.Join(filepath.Dir(path), includePath)
+		}
+		matches, err := filepath.Glob(includePath)
+		if err != nil {
+			return err
+		}
+		if len(matches) == 0 {
+			return fmt.Errorf("included config file not found. path: %s", includePath)
+		}
+		for _, match := range matches {
+			if err := mergeConfigFile(settings, match, visited, files); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// decodeConfigFile return the decoded json or yaml config file and whether it is yaml
+func decodeConfigFile(path string) (map[string]interface{}, bool, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, false, err
+	}
+
+	isYaml := false
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		isYaml = true
+	case ".json":
+	default:
+		isYaml = !json.Valid(content)
+	}
+	if isYaml {
+		if content, err = yaml.YAMLToJSON(content); err != nil {
+			return nil, false, err
+		}
+	}
+
+	settings := map[string]interface{}{}
+	if err := json.Unmarshal(content, &settings); err != nil {
+		return nil, false, err
+	}
+
+	return settings, isYaml, nil
+}
+
+func isConfigFileExtension(name string) bool {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".json", ".yaml", ".yml":
+		return true
+	}
+	return false
+}
+
+// mergeSettings merge the maps of src into dst deeply, the other values of src replace dst
+func mergeSettings(dst map[string]interface{}, src map[string]interface{}) {
+	for key, value := range src {
+		key = strings.ToLower(key)
+		srcMap, srcOk := value.(map[string]interface{})
+		dstMap, dstOk := dst[key].(map[string]interface{})
+		if srcOk && dstOk {
+			mergeSettings(dstMap, srcMap)
+			continue
+		}
+		dst[key] = value
+	}
+}
+
+// interpolateConfigEnv replace ${NAME} and ${NAME:-default} in the string values with environment variables
+func interpolateConfigEnv(value interface{}) interface{} {
+	switch item := value.(type) {
+	case string:
+		return configEnvPattern.ReplaceAllStringFunc(item, func(match string) string {
+			groups := configEnvPattern.FindStringSubmatch(match)
+			if env, ok := os.LookupEnv(groups[1]); ok {
+				return env
+			}
+			if groups[2] == "" {
+				log.WithField("name", groups[1]).Warn("config environment variable not set. replace with empty string")
+			}
+			return groups[3]
+		})
+	case map[string]interface{}:
+		for key, child := range item {
+			item[key] = interpolateConfigEnv(child)
+		}
+	case []interface{}:
+		for key, child := range item {
+			item[key] = interpolateConfigEnv(child)
+		}
+	}
+
+	return value
+}
+
+// bindConfigEnv bind the KPLAYER_* environment variables of config fields on viper.
+// the list fields can not be overridden
+func bindConfigEnv(v *viper.Viper) {
+	v.SetEnvPrefix(ConfigEnvPrefix)
+	v.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
+
+	var bind func(t reflect.Type, prefix string)
+	bind = func(t reflect.Type, prefix string) {
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			name := strings.SplitN(field.Tag.Get("json"), ",", 2)[0]
+			if field.PkgPath != "" || name == "" || name == "-" {
+				continue
+			}
+
+			key := name
+			if prefix != "" {
+				key = prefix + "." + name
+			}
+
+			fieldType := field.Type
+			if fieldType.Kind() == reflect.Ptr {
+				fieldType = fieldType.Elem()
+			}
+			switch fieldType.Kind() {
+			case reflect.Struct:
+				bind(fieldType, key)
+			case reflect.Slice, reflect.Map:
+			default:
+				_ = v.BindEnv(key)
+			}
+		}
+	}
+	bind(reflect.TypeOf(config.KPConfig{}), "")
+}
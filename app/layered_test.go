@@ -0,0 +1,86 @@
+package app
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestReadConfigLayered(t *testing.T) {
+	dir := t.TempDir()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+
+	files := map[string]string{
+		"config.json": `{
+    "version": "2.0.0",
+    "include": ["outputs.yaml"],
+    "resource": {"lists": ["a.mp4"]},
+    "play": {"play_model": "loop", "encode": {"video_width": 1280}}
+}`,
+		"outputs.yaml":      "output:\n  lists:\n    - path: rtmp://127.0.0.1/live/${KPLAYER_TEST_STREAM_KEY}\n      unique: ${KPLAYER_TEST_UNIQUE:-main}\n",
+		"conf.d/10-a.yaml":  "play:\n  encode:\n    video_height: 720\n",
+		"conf.d/20-b.json":  `{"play": {"play_model": "random"}}`,
+		"conf.d/ignore.txt": "play_model: list",
+	}
+	for name, content := range files {
+		if err := os.MkdirAll(filepath.Dir(filepath.Join(dir, name)), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	os.Setenv("KPLAYER_TEST_STREAM_KEY", "secret")
+	os.Setenv("KPLAYER_PLAY_RPC_HTTP_PORT", "8080")
+	defer os.Unsetenv("KPLAYER_TEST_STREAM_KEY")
+	defer os.Unsetenv("KPLAYER_PLAY_RPC_HTTP_PORT")
+
+	loadedConfig, err := readConfig("config", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := loadedConfig.Config
+
+	if len(loadedConfig.Files) != 4 {
+		t.Fatalf("unexpected read files: %v", loadedConfig.Files)
+	}
+	play := loadedConfig.RawSettings["play"].(map[string]interface{})
+	if play["play_model"] != "random" {
+		t.Errorf("unexpected play model: %v", play["play_model"])
+	}
+	encode := play["encode"].(map[string]interface{})
+	if encode["video_width"] != float64(1280) || encode["video_height"] != float64(720) || encode["video_fps"] != 25 {
+		t.Errorf("unexpected encode: %v", encode)
+	}
+	if len(cfg.Output.Lists) != 1 || cfg.Output.Lists[0].Path != "rtmp://127.0.0.1/live/secret" || cfg.Output.Lists[0].Unique != "main" {
+		t.Errorf("unexpected output: %+v", cfg.Output.Lists)
+	}
+	if httpPort := play["rpc"].(map[string]interface{})["http_port"]; httpPort != "8080" {
+		t.Errorf("unexpected http port: %v", httpPort)
+	}
+	if len(cfg.Resource.Lists) != 1 {
+		t.Errorf("unexpected resource: %v", cfg.Resource.Lists)
+	}
+}
+
+func TestReadConfigIncludeCircularly(t *testing.T) {
+	dir := t.TempDir()
+	if err := ioutil.WriteFile(filepath.Join(dir, "a.yaml"), []byte("include: b.yaml\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "b.yaml"), []byte("include: [a.yaml]\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, _, err := readLayeredConfig(filepath.Join(dir, "a.yaml")); err == nil {
+		t.Fatal("expected error of circular include")
+	}
+}
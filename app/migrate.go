@@ -0,0 +1,177 @@
+package app
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// legacyConfigVersion the version of config file without version field
+const legacyConfigVersion = "1.0.0"
+
+// MigrateFunc rewrite the decoded config file to the next version layout in place.
+// return the summary of changes
+type MigrateFunc func(settings map[string]interface{}) ([]string, error)
+
+// Migration converter of config file from version to the next version.
+// From matches the exact version or the versions under it, "1" matches "1.5.2"
+type Migration struct {
+	From    string
+	To      string
+	Migrate MigrateFunc
+}
+
+var (
+	migrations     []Migration
+	migrationsLock sync.Mutex
+)
+
+func init() {
+	RegisterMigration(Migration{From: "1", To: ConfigVersion, Migrate: migrateV1ToV2})
+}
+
+// RegisterMigration register the converter of config file. a schema bump should register
+// the converter from the previous version
+func RegisterMigration(m Migration) {
+	migrationsLock.Lock()
+	defer migrationsLock.Unlock()
+
+	migrations = append(migrations, m)
+}
+
+func getMigration(version string) (Migration, bool) {
+	migrationsLock.Lock()
+	defer migrationsLock.Unlock()
+
+	for _, item := range migrations {
+		if version == item.From || strings.HasPrefix(version, item.From+".") {
+			return item, true
+		}
+	}
+
+	return Migration{}, false
+}
+
+// MigrateConfig rewrite the decoded config file to the current version by the registered migrations
+func MigrateConfig(settings map[string]interface{}) ([]string, error) {
+	version, _ := settings["version"].(string)
+	var changes []string
+	if version == "" {
+		version = legacyConfigVersion
+		changes = append(changes, fmt.Sprintf("version not found. regard as %s", legacyConfigVersion))
+	}
+
+	// each migration step forward a version, the steps can not more than the registered migrations
+	for step := 0; version != ConfigVersion; step++ {
+		m, ok := getMigration(version)
+		if !ok || step > len(migrations) {
+			return nil, fmt.Errorf("no migration from config version %s to %s", version, ConfigVersion)
+		}
+
+		stepChanges, err := m.Migrate(settings)
+		if err != nil {
+			return nil, fmt.Errorf("migrate config version %s to %s failed. error: %s", version, m.To, err)
+		}
+		changes = append(changes, stepChanges...)
+		changes = append(changes, fmt.Sprintf("version: %s -> %s", version, m.To))
+
+		version = m.To
+		settings["version"] = version
+	}
+
+	return changes, nil
+}
+
+// MigrateConfigFile migrate the config file in place and keep the backup of origin file.
+// return the backup file path and the summary of changes. the file is untouched on dry run or
+// when the config file is the current version
+func MigrateConfigFile(configFileName string, dryRun bool) (string, []string, error) {
+	v := NewConfigViper(configFileName)
+	if err := v.ReadInConfig(); err != nil {
+		return "", nil, err
+	}
+	configFilePath := v.ConfigFileUsed()
+
+	file, err := readPersistedFile(configFilePath)
+	if err != nil {
+		return "", nil, err
+	}
+
+	changes, err := MigrateConfig(file.settings)
+	if err != nil {
+		return "", nil, err
+	}
+	if dryRun || len(changes) == 0 {
+		return "", changes, nil
+	}
+
+	// backup the origin file, the earlier backup is kept. the origin file is replaced after backup written
+	backupPath := configFilePath + ".bak"
+	if _, err := os.Stat(backupPath); err == nil {
+		backupPath = fmt.Sprintf("%s.%d.bak", configFilePath, time.Now().Unix())
+	}
+	if err := backupConfigFile(configFilePath, backupPath); err != nil {
+		return "", nil, err
+	}
+	if err := file.write(); err != nil {
+		return "", nil, err
+	}
+
+	return backupPath, changes, nil
+}
+
+// backupConfigFile copy the config file to backup path with the file mode
+func backupConfigFile(configFilePath string, backupPath string) error {
+	stat, err := os.Stat(configFilePath)
+	if err != nil {
+		return err
+	}
+	content, err := ioutil.ReadFile(configFilePath)
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(backupPath, content, stat.Mode())
+}
+
+// migrateV1ToV2 migrate the 1.x layout.
+// the rpc serve only one http port and the output, plugin items can be the path string
+func migrateV1ToV2(settings map[string]interface{}) ([]string, error) {
+	var changes []string
+
+	if play, ok := settings["play"].(map[string]interface{}); ok {
+		if rpc, ok := play["rpc"].(map[string]interface{}); ok {
+			if port, ok := rpc["port"]; ok {
+				if _, ok := rpc["http_port"]; !ok {
+					rpc["http_port"] = port
+					changes = append(changes, "play.rpc.port -> play.rpc.http_port")
+				} else {
+					changes = append(changes, "play.rpc.port removed. play.rpc.http_port has been set")
+				}
+				delete(rpc, "port")
+			}
+		}
+	}
+
+	for _, section := range []string{"output", "plugin"} {
+		sectionSettings, ok := settings[section].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		lists, ok := sectionSettings["lists"].([]interface{})
+		if !ok {
+			continue
+		}
+		for key, item := range lists {
+			if path, ok := item.(string); ok {
+				lists[key] = map[string]interface{}{"path": path}
+				changes = append(changes, fmt.Sprintf("%s.lists[%d]: path string -> object", section, key))
+			}
+		}
+	}
+
+	return changes, nil
+}
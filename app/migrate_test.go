@@ -0,0 +1,93 @@
+package app
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMigrateConfig(t *testing.T) {
+	settings := map[string]interface{}{}
+	if err := json.Unmarshal([]byte(`{
+    "version": "1.5.2",
+    "play": {"rpc": {"on": true, "port": 4156}},
+    "output": {"lists": ["rtmp://127.0.0.1/live", {"path": "rtmp://127.0.0.1/backup"}]}
+}`), &settings); err != nil {
+		t.Fatal(err)
+	}
+
+	changes, err := MigrateConfig(settings)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 3 {
+		t.Fatalf("unexpected changes: %v", changes)
+	}
+	if settings["version"] != ConfigVersion {
+		t.Fatalf("unexpected version: %v", settings["version"])
+	}
+	rpc := settings["play"].(map[string]interface{})["rpc"].(map[string]interface{})
+	if _, ok := rpc["port"]; ok || rpc["http_port"] != float64(4156) {
+		t.Fatalf("unexpected rpc: %v", rpc)
+	}
+	output := settings["output"].(map[string]interface{})["lists"].([]interface{})
+	if item, ok := output[0].(map[string]interface{}); !ok || item["path"] != "rtmp://127.0.0.1/live" {
+		t.Fatalf("unexpected output: %v", output)
+	}
+
+	// the current version is not changed
+	changes, err = MigrateConfig(settings)
+	if err != nil || len(changes) != 0 {
+		t.Fatalf("unexpected migrate result. changes: %v, error: %v", changes, err)
+	}
+
+	// unknown version
+	if _, err := MigrateConfig(map[string]interface{}{"version": "0.9.0"}); err == nil {
+		t.Fatal("expected error of unknown version")
+	}
+}
+
+func TestMigrateConfigFile(t *testing.T) {
+	dir := t.TempDir()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+
+	origin := "version: 1.0.0\nplay:\n  rpc:\n    port: 4156\n"
+	if err := ioutil.WriteFile(filepath.Join(dir, "config.yaml"), []byte(origin), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	backupPath, changes, err := MigrateConfigFile("config", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) == 0 {
+		t.Fatal("expected changes")
+	}
+	backup, err := ioutil.ReadFile(backupPath)
+	if err != nil || string(backup) != origin {
+		t.Fatalf("unexpected backup. content: %s, error: %v", backup, err)
+	}
+
+	// the migrated file keeps the mode of origin file
+	stat, err := os.Stat(filepath.Join(dir, "config.yaml"))
+	if err != nil || stat.Mode().Perm() != 0600 {
+		t.Fatalf("unexpected migrated file mode. stat: %v, error: %v", stat, err)
+	}
+
+	v := NewConfigViper("config")
+	if err := v.ReadInConfig(); err != nil {
+		t.Fatal(err)
+	}
+	if v.GetString("version") != ConfigVersion || v.GetInt("play.rpc.http_port") != 4156 {
+		t.Fatalf("unexpected migrated config: %v", v.AllSettings())
+	}
+}
@@ -0,0 +1,225 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/bytelang/kplayer/module"
+	kptypes "github.com/bytelang/kplayer/types"
+	"github.com/fsnotify/fsnotify"
+	log "github.com/sirupsen/logrus"
+)
+
+// editors write the config file by several events, reload after the file is quiet
+const reloadDebounce = time.Millisecond * 500
+
+// configReloader reload the config file on changed and on SIGHUP.
+// the reloadable modules apply the difference of their section through the provider methods
+type configReloader struct {
+	mm       module.ModuleManager
+	previous *LoadedConfig
+	lock     sync.Mutex
+}
+
+var _ kptypes.ConfigReloader = &configReloader{}
+
+func NewConfigReloader(mm module.ModuleManager) *configReloader {
+	return &configReloader{
+		mm: mm,
+	}
+}
+
+// WatchConfig watch the config file and SIGHUP until the context done
+func (r *configReloader) WatchConfig(ctx context.Context, configFileName string) {
+	loadedConfig, err := LoadConfig(configFileName, false)
+	if err != nil {
+		log.WithField("error", err).Error("load config failed. config reload disabled")
+		return
+	}
+	r.previous = loadedConfig
+
+	// watch the directories, editors replace the config file by rename
+	configFilePath, err := filepath.Abs(loadedConfig.Viper.ConfigFileUsed())
+	if err != nil {
+		log.WithField("error", err).Error("get config file path failed. config reload disabled")
+		return
+	}
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		log.WithField("error", err).Error("create config watcher failed. config reload disabled")
+		return
+	}
+	defer watcher.Close()
+	confDir := filepath.Join(filepath.Dir(configFilePath), ConfigDirectoryName)
+	watchFiles := map[string]bool{configFilePath: true}
+	watchDirs := map[string]bool{filepath.Dir(configFilePath): true}
+	for _, item := range loadedConfig.Files {
+		watchFiles[item] = true
+		watchDirs[filepath.Dir(item)] = true
+	}
+	if stat, err := os.Stat(confDir); err == nil && stat.IsDir() {
+		watchDirs[confDir] = true
+	}
+	for dir := range watchDirs {
+		if err := watcher.Add(dir); err != nil {
+			log.WithFields(log.Fields{"error": err, "path": dir}).Error("watch config directory failed. config reload disabled")
+			return
+		}
+	}
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGHUP)
+	defer signal.Stop(signals)
+
+	log.WithField("path", configFilePath).Info("watching config file")
+
+	var debounce <-chan time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-signals:
+			log.Info("receive SIGHUP. reload config")
+			r.Reload(ctx, configFileName)
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return
+			}
+			eventPath, _ := filepath.Abs(event.Name)
+			if event.Op&(fsnotify.Write|fsnotify.Create|fsnotify.Rename|fsnotify.Remove) == 0 {
+				continue
+			}
+			if !watchFiles[eventPath] && !(filepath.Dir(eventPath) == confDir && isConfigFileExtension(eventPath)) {
+				continue
+			}
+			debounce = time.After(reloadDebounce)
+		case <-debounce:
+			debounce = nil
+			log.WithField("path", configFilePath).Info("config file changed. reload config")
+			r.Reload(ctx, configFileName)
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return
+			}
+			log.WithField("error", err).Warn("watch config file failed")
+		}
+	}
+}
+
+// Reload load the config file and apply the difference to modules.
+// an invalid config is not applied, the running config is kept
+func (r *configReloader) Reload(ctx context.Context, configFileName string) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	loadedConfig, err := LoadConfig(configFileName, false)
+	if err != nil {
+		log.WithField("error", err).Error("reload config failed. keep the running config")
+		return
+	}
+
+	// global config
+	moduleNames := map[string]bool{}
+	for _, m := range r.mm.GetOrderedModules() {
+		moduleNames[m.GetModuleName()] = true
+	}
+	previousGlobal, currentGlobal := map[string]interface{}{}, map[string]interface{}{}
+	if err := decodeConfig(r.previous.Config, &previousGlobal); err != nil {
+		log.WithField("error", err).Error("reload config failed")
+		return
+	}
+	if err := decodeConfig(loadedConfig.Config, &currentGlobal); err != nil {
+		log.WithField("error", err).Error("reload config failed")
+		return
+	}
+	for name := range moduleNames {
+		delete(previousGlobal, name)
+		delete(currentGlobal, name)
+	}
+	for _, field := range diffConfig("", previousGlobal, currentGlobal) {
+		log.WithField("field", field).Warn("config changed. cannot be applied on running, restart required")
+	}
+
+	// modules
+	for _, m := range r.mm.GetOrderedModules() {
+		logFields := log.WithField("module", m.GetModuleName())
+
+		section, err := loadedConfig.GetModuleSection(m.GetModuleName())
+		if err != nil {
+			logFields.WithField("error", err).Error("reload module config failed")
+			continue
+		}
+
+		if reloadModule, ok := m.(module.ReloadAppModule); ok {
+			if err := reloadModule.ReloadConfig(ctx, section); err != nil {
+				logFields.WithField("error", err).Error("reload module config failed")
+				continue
+			}
+			logFields.Info("reload module config success")
+			continue
+		}
+
+		previousSection, err := r.previous.GetModuleSection(m.GetModuleName())
+		if err != nil {
+			logFields.WithField("error", err).Error("reload module config failed")
+			continue
+		}
+		var previous, current interface{}
+		_ = json.Unmarshal(previousSection, &previous)
+		_ = json.Unmarshal(section, &current)
+		for _, field := range diffConfig(m.GetModuleName(), previous, current) {
+			logFields.WithField("field", field).Warn("config changed. cannot be applied on running, restart required")
+		}
+	}
+
+	r.previous = loadedConfig
+}
+
+func decodeConfig(cfg interface{}, result *map[string]interface{}) error {
+	data, err := json.Marshal(cfg)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(data, result)
+}
+
+// diffConfig return the sorted paths of changed fields between the decoded json values
+func diffConfig(prefix string, previous interface{}, current interface{}) []string {
+	previousMap, previousOk := previous.(map[string]interface{})
+	currentMap, currentOk := current.(map[string]interface{})
+	if !previousOk || !currentOk {
+		if reflect.DeepEqual(previous, current) {
+			return nil
+		}
+		return []string{prefix}
+	}
+
+	keys := map[string]bool{}
+	for key := range previousMap {
+		keys[key] = true
+	}
+	for key := range currentMap {
+		keys[key] = true
+	}
+
+	var fields []string
+	for key := range keys {
+		path := key
+		if prefix != "" {
+			path = prefix + "." + key
+		}
+		fields = append(fields, diffConfig(path, previousMap[key], currentMap[key])...)
+	}
+	sort.Strings(fields)
+
+	return fields
+}
@@ -0,0 +1,25 @@
+package app
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestDiffConfig(t *testing.T) {
+	var previous, current interface{}
+	if err := json.Unmarshal([]byte(`{"encode":{"video_width":854,"video_height":480},"play_model":"list","rpc":{"on":true}}`), &previous); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal([]byte(`{"encode":{"video_width":1280,"video_height":480},"play_model":"loop","cache_on":true,"rpc":{"on":true}}`), &current); err != nil {
+		t.Fatal(err)
+	}
+
+	fields := diffConfig("play", previous, current)
+	if result := strings.Join(fields, ","); result != "play.cache_on,play.encode.video_width,play.play_model" {
+		t.Fatalf("unexpected changed fields: %s", result)
+	}
+	if fields := diffConfig("play", previous, previous); len(fields) != 0 {
+		t.Fatalf("unexpected changed fields: %v", fields)
+	}
+}
@@ -0,0 +1,410 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/bytelang/kplayer/module"
+	resourceprovider "github.com/bytelang/kplayer/module/resource/provider"
+	kptypes "github.com/bytelang/kplayer/types"
+	"github.com/bytelang/kplayer/types/config"
+	"github.com/ghodss/yaml"
+)
+
+// persistedSections the module sections whose lists are written back to the config file
+var persistedSections = []string{"resource", "output", "plugin"}
+
+// jsonIndentPattern the indent of the first nested line in json file
+var jsonIndentPattern = regexp.MustCompile(`\n([ \t]+)\S`)
+
+type persistedFile struct {
+	path     string
+	settings map[string]interface{}
+	isYaml   bool
+	indent   string
+	changed  bool
+}
+
+// SaveConfig write the resources, outputs and plugins of running modules back to the config files.
+// each list is written to the last file defines it, the main config file when no file defines it.
+// the unchanged items are kept as written, the other keys of files are untouched. return the written files
+func SaveConfig(ctx *kptypes.ClientContext, mm module.ModuleManager) ([]string, error) {
+	if ctx.Viper == nil || ctx.Viper.ConfigFileUsed() == "" {
+		return nil, fmt.Errorf("config file not loaded")
+	}
+	configFilePath, err := filepath.Abs(ctx.Viper.ConfigFileUsed())
+	if err != nil {
+		return nil, err
+	}
+
+	effective, err := EffectiveConfig(ctx, mm)
+	if err != nil {
+		return nil, err
+	}
+
+	_, paths, err := readLayeredConfig(configFilePath)
+	if err != nil {
+		return nil, err
+	}
+	var files []*persistedFile
+	for _, path := range paths {
+		file, err := readPersistedFile(path)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, file)
+	}
+
+	for _, section := range persistedSections {
+		sectionSettings, _ := effective[section].(map[string]interface{})
+		current, _ := sectionSettings["lists"].([]interface{})
+
+		// the last file defines the list wins on merging
+		target := files[0]
+		for i := len(files) - 1; i >= 0; i-- {
+			if fileSection, ok := files[i].settings[section].(map[string]interface{}); ok {
+				if _, ok := fileSection["lists"]; ok {
+					target = files[i]
+					break
+				}
+			}
+		}
+
+		targetSection, ok := target.settings[section].(map[string]interface{})
+		if !ok {
+			if len(current) == 0 {
+				continue
+			}
+			targetSection = map[string]interface{}{}
+			target.settings[section] = targetSection
+		}
+		raw, _ := targetSection["lists"].([]interface{})
+
+		var extensions []string
+		if items, ok := sectionSettings["extensions"].([]interface{}); ok {
+			for _, item := range items {
+				extensions = append(extensions, fmt.Sprint(item))
+			}
+		}
+		lists := mergePersistedList(section, raw, current, extensions)
+		if reflect.DeepEqual(lists, raw) || (len(lists) == 0 && len(raw) == 0) {
+			continue
+		}
+		targetSection["lists"] = lists
+		target.changed = true
+	}
+
+	var written []string
+	for _, file := range files {
+		if !file.changed {
+			continue
+		}
+		if err := file.write(); err != nil {
+			return written, err
+		}
+		written = append(written, file.path)
+	}
+
+	return written, nil
+}
+
+func readPersistedFile(path string) (*persistedFile, error) {
+	settings, isYaml, err := decodeConfigFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	file := &persistedFile{
+		path:     path,
+		settings: settings,
+		isYaml:   isYaml,
+		indent:   "  ",
+	}
+	if !isYaml {
+		content, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		if match := jsonIndentPattern.FindSubmatch(content); match != nil {
+			file.indent = string(match[1])
+		}
+	}
+
+	return file, nil
+}
+
+// write replace the file atomically by renaming the written temporary file
+func (f *persistedFile) write() error {
+	content, err := json.MarshalIndent(f.settings, "", f.indent)
+	if err != nil {
+		return err
+	}
+	if f.isYaml {
+		if content, err = yaml.JSONToYAML(content); err != nil {
+			return err
+		}
+	} else {
+		content = append(content, '\n')
+	}
+
+	stat, err := os.Stat(f.path)
+	if err != nil {
+		return err
+	}
+	tmpFile, err := ioutil.TempFile(filepath.Dir(f.path), "."+filepath.Base(f.path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmpFile.Name())
+
+	if _, err := tmpFile.Write(content); err != nil {
+		tmpFile.Close()
+		return err
+	}
+	if err := tmpFile.Sync(); err != nil {
+		tmpFile.Close()
+		return err
+	}
+	if err := tmpFile.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmpFile.Name(), stat.Mode()); err != nil {
+		return err
+	}
+
+	return os.Rename(tmpFile.Name(), f.path)
+}
+
+// mergePersistedList return the list of current module state in config format. the config items equal to
+// the current items are kept as written, the directory is kept when all of its files are in playlist
+func mergePersistedList(section string, raw []interface{}, current []interface{}, extensions []string) []interface{} {
+	lists := []interface{}{}
+	used := make([]bool, len(raw))
+
+	// the expanded files of directory
+	covered := map[int]bool{}
+	directoryAt := map[int]int{}
+	if section == "resource" {
+		for rawKey, rawItem := range raw {
+			indexes := matchDirectoryResources(rawItem, current, extensions, covered)
+			if len(indexes) == 0 {
+				continue
+			}
+			for _, index := range indexes {
+				covered[index] = true
+			}
+			directoryAt[indexes[0]] = rawKey
+			used[rawKey] = true
+		}
+	}
+
+	for key, item := range current {
+		if rawKey, ok := directoryAt[key]; ok {
+			lists = append(lists, raw[rawKey])
+			continue
+		}
+		if covered[key] {
+			continue
+		}
+
+		matched := false
+		for rawKey, rawItem := range raw {
+			if used[rawKey] || !persistedItemEqual(section, rawItem, item) {
+				continue
+			}
+			lists = append(lists, rawItem)
+			used[rawKey] = true
+			matched = true
+			break
+		}
+		if !matched {
+			lists = append(lists, item)
+		}
+	}
+
+	return lists
+}
+
+// matchDirectoryResources return the indexes of current resources expanded from the directory or playlist item
+func matchDirectoryResources(rawItem interface{}, current []interface{}, extensions []string, covered map[int]bool) []int {
+	item := canonicalPersistedItem("resource", rawItem)
+	path, _ := item["path"].(string)
+	directory, _ := item["directory"].(string)
+	if directory != "" {
+		// the live directory is kept as config item in current list
+		if item["live"] == true {
+			return nil
+		}
+	} else if path == "" || item["seek"] != float64(0) || item["end"] != float64(0) {
+		return nil
+	}
+
+	// the expanded resources are matched by path, seek and end
+	expanded := map[string]int{}
+	total := 0
+	expandedKey := func(path string, seek, end float64) string {
+		return fmt.Sprintf("%s#%v#%v", path, seek, end)
+	}
+	if directory != "" {
+		files, err := resourceprovider.ScanDirectoryResource(&config.DirectoryResource{
+			Directory:      directory,
+			Recursive:      item["recursive"] == true,
+			Include:        item["include"].([]string),
+			Exclude:        item["exclude"].([]string),
+			Sort:           item["sort"].(string),
+			Hidden:         item["hidden"] == true,
+			FollowSymlinks: item["follow_symlinks"] == true,
+		}, extensions)
+		if err != nil {
+			return nil
+		}
+		for _, f := range files {
+			expanded[expandedKey(f, 0, -1)]++
+			total++
+		}
+	} else if resourceprovider.IsPlaylistFile(path) {
+		resources, err := resourceprovider.ParsePlaylistFile(path, "")
+		if err != nil {
+			return nil
+		}
+		for _, res := range resources {
+			expanded[expandedKey(res.Path, float64(res.Seek), float64(res.End))]++
+			total++
+		}
+	} else {
+		files, err := kptypes.GetDirectorFiles(path)
+		if err != nil {
+			return nil
+		}
+		for _, f := range files {
+			ext := strings.TrimPrefix(filepath.Ext(f), ".")
+			if extensions == nil || kptypes.ArrayInString(extensions, ext) {
+				expanded[expandedKey(f, 0, -1)]++
+				total++
+			}
+		}
+	}
+	if total == 0 {
+		return nil
+	}
+
+	var indexes []int
+	for key, currentItem := range current {
+		res := canonicalPersistedItem("resource", currentItem)
+		itemKey := expandedKey(res["path"].(string), res["seek"].(float64), res["end"].(float64))
+		if !covered[key] && expanded[itemKey] > 0 {
+			expanded[itemKey]--
+			indexes = append(indexes, key)
+		}
+	}
+	if len(indexes) != total {
+		return nil
+	}
+	sort.Ints(indexes)
+
+	return indexes
+}
+
+// persistedItemEqual return whether the config item equal to the current item. the unique is ignored when
+// the config item does not configure it
+func persistedItemEqual(section string, rawItem interface{}, currentItem interface{}) bool {
+	raw := canonicalPersistedItem(section, rawItem)
+	current := canonicalPersistedItem(section, currentItem)
+	if raw["unique"] == "" {
+		delete(raw, "unique")
+		delete(current, "unique")
+	}
+
+	return reflect.DeepEqual(raw, current)
+}
+
+// canonicalPersistedItem return the comparable fields of list item. the environment variables are interpolated
+func canonicalPersistedItem(section string, item interface{}) map[string]interface{} {
+	var decoded interface{}
+	if d, err := json.Marshal(item); err == nil {
+		_ = json.Unmarshal(d, &decoded)
+	}
+	decoded = interpolateConfigEnv(decoded)
+
+	fields, ok := decoded.(map[string]interface{})
+	if !ok {
+		path, _ := decoded.(string)
+		fields = map[string]interface{}{"path": path}
+	}
+	getString := func(key string) string {
+		value, _ := fields[key].(string)
+		return value
+	}
+	getNumber := func(key string) float64 {
+		value, _ := fields[key].(float64)
+		return value
+	}
+	getBool := func(key string) bool {
+		value, _ := fields[key].(bool)
+		return value
+	}
+	getStrings := func(key string) []string {
+		var values []string
+		items, _ := fields[key].([]interface{})
+		for _, item := range items {
+			if value, ok := item.(string); ok {
+				values = append(values, value)
+			}
+		}
+		return values
+	}
+
+	result := map[string]interface{}{
+		"path":   getString("path"),
+		"unique": getString("unique"),
+	}
+	switch section {
+	case "plugin":
+		params, _ := fields["params"].(map[string]interface{})
+		if len(params) == 0 {
+			params = nil
+		}
+		result["params"] = params
+	case "resource":
+		result["seek"] = getNumber("seek")
+		result["end"] = getNumber("end")
+		result["directory"] = getString("directory")
+		result["live"] = getBool("live")
+		result["recursive"] = getBool("recursive")
+		result["include"] = getStrings("include")
+		result["exclude"] = getStrings("exclude")
+		result["sort"] = getString("sort")
+		result["hidden"] = getBool("hidden")
+		result["follow_symlinks"] = getBool("follow_symlinks")
+
+		var groups []interface{}
+		items, _ := fields["groups"].([]interface{})
+		for _, groupItem := range items {
+			group, _ := groupItem.(map[string]interface{})
+			path, _ := group["path"].(string)
+			persistentLoop, _ := group["persistent_loop"].(bool)
+			mediaType, ok := group["media_type"].(float64)
+			if name, isName := group["media_type"].(string); isName {
+				mediaType, ok = float64(config.ResourceMediaType_value[name]), true
+			}
+			if !ok {
+				mediaType = 0
+			}
+			groups = append(groups, map[string]interface{}{
+				"path":            path,
+				"media_type":      mediaType,
+				"persistent_loop": persistentLoop,
+			})
+		}
+		result["groups"] = groups
+	}
+
+	return result
+}
@@ -0,0 +1,224 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bytelang/kplayer/core"
+	"github.com/bytelang/kplayer/module"
+	outputm "github.com/bytelang/kplayer/module/output"
+	resourcem "github.com/bytelang/kplayer/module/resource"
+	kptypes "github.com/bytelang/kplayer/types"
+	svrproto "github.com/bytelang/kplayer/types/server"
+)
+
+func TestMergePersistedList(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"a.mp4", "b.mp4", "c.txt"} {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	os.Setenv("KPLAYER_TEST_VIDEO", "/video/env.mp4")
+	defer os.Unsetenv("KPLAYER_TEST_VIDEO")
+
+	var raw, current []interface{}
+	if err := json.Unmarshal([]byte(`[
+    "${KPLAYER_TEST_VIDEO}",
+    "`+dir+`",
+    {"path": "/video/removed.mp4"},
+    {"unique": "mix", "groups": [{"path": "/video/v.mp4", "media_type": "video"}, {"path": "/video/a.mp3", "media_type": "audio"}]}
+]`), &raw); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal([]byte(`[
+    {"path": "/video/env.mp4", "unique": "generated"},
+    {"path": "`+filepath.Join(dir, "a.mp4")+`", "unique": "a", "end": -1},
+    {"path": "`+filepath.Join(dir, "b.mp4")+`", "unique": "b", "end": -1},
+    {"unique": "mix", "groups": [{"path": "/video/v.mp4", "media_type": 1}, {"path": "/video/a.mp3", "media_type": 2}]},
+    {"path": "/video/added.mp4", "unique": "added", "seek": 10}
+]`), &current); err != nil {
+		t.Fatal(err)
+	}
+
+	lists := mergePersistedList("resource", raw, current, []string{"mp4"})
+	expect := []interface{}{raw[0], raw[1], raw[3], current[4]}
+	if !reflect.DeepEqual(lists, expect) {
+		t.Fatalf("unexpected persisted list: %v", lists)
+	}
+}
+
+func TestSaveConfig(t *testing.T) {
+	dir := t.TempDir()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+	os.Setenv("KPLAYER_TEST_STREAM_KEY", "secret")
+	defer os.Unsetenv("KPLAYER_TEST_STREAM_KEY")
+
+	mainConfig := "version: 2.0.0\ninclude: outputs.json\nresource:\n  lists:\n  - /video/a.mp4\n"
+	if err := ioutil.WriteFile("config.yaml", []byte(mainConfig), 0644); err != nil {
+		t.Fatal(err)
+	}
+	outputConfig := "{\n    \"output\": {\n        \"reconnect_internal\": 5,\n        \"lists\": [{\"path\": \"rtmp://127.0.0.1/live/${KPLAYER_TEST_STREAM_KEY}\"}]\n    }\n}\n"
+	if err := ioutil.WriteFile("outputs.json", []byte(outputConfig), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	loadedConfig, err := readConfig("config", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	clientCtx := &kptypes.ClientContext{Viper: loadedConfig.Viper, Config: loadedConfig.Config}
+
+	// an output is added on running
+	outputModule := outputm.NewAppModule(core.NewFakeEngine())
+	if _, err := outputModule.InitConfig(clientCtx, json.RawMessage(`{"lists": [{"path": "rtmp://127.0.0.1/live/secret"}, {"path": "rtmp://127.0.0.1/live/added", "unique": "added"}]}`)); err != nil {
+		t.Fatal(err)
+	}
+	mm, err := module.NewModuleManager(outputModule)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := SaveConfig(clientCtx, mm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 || filepath.Base(files[0]) != "outputs.json" {
+		t.Fatalf("unexpected written files: %v", files)
+	}
+
+	content, err := ioutil.ReadFile("outputs.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(content), "\n    \"output\"") || !strings.Contains(string(content), "${KPLAYER_TEST_STREAM_KEY}") || !strings.Contains(string(content), "rtmp://127.0.0.1/live/added") {
+		t.Fatalf("unexpected saved config: %s", content)
+	}
+	if stat, err := os.Stat("outputs.json"); err != nil || stat.Mode().Perm() != 0600 {
+		t.Fatalf("unexpected saved config mode. stat: %v, error: %v", stat, err)
+	}
+	if content, _ := ioutil.ReadFile("config.yaml"); string(content) != mainConfig {
+		t.Fatalf("unexpected main config changed: %s", content)
+	}
+
+	// nothing changed
+	if files, err := SaveConfig(clientCtx, mm); err != nil || len(files) != 0 {
+		t.Fatalf("unexpected save result. files: %v, error: %v", files, err)
+	}
+}
+
+func TestSaveConfigOnInterrupt(t *testing.T) {
+	dir := t.TempDir()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+
+	mainConfig := "version: 2.0.0\nresource:\n  lists:\n  - a.mp4\n"
+	files := map[string]string{"config.yaml": mainConfig, "a.mp4": "", "alert.mp4": ""}
+	for name, content := range files {
+		if err := ioutil.WriteFile(name, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	loadedConfig, err := readConfig("config", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	clientCtx := &kptypes.ClientContext{Viper: loadedConfig.Viper, Config: loadedConfig.Config}
+
+	fe := core.NewFakeEngine()
+	mm := NewModuleManager(fe)
+	for _, m := range mm.GetOrderedModules() {
+		section, err := loadedConfig.GetModuleSection(m.GetModuleName())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := m.InitConfig(clientCtx, section); err != nil {
+			t.Fatal(err)
+		}
+	}
+	fe.SetCallBackMessage(func(message *core.Message) {
+		for _, item := range mm.GetOrderedModules() {
+			copyMsg := *message.KPMessage
+			item.ParseMessage(&copyMsg)
+
+			copyMsg = *message.KPMessage
+			item.TriggerMessage(&core.Message{KPMessage: &copyMsg, CorrelationId: message.CorrelationId})
+		}
+	})
+	fe.SetCallBackProgress(func(percent float64, bitRate int) {
+		for _, item := range mm.GetOrderedModules() {
+			if m, ok := item.(module.ProgressAppModule); ok {
+				m.ParseProgress(percent, bitRate)
+			}
+		}
+	})
+
+	resultChan := make(chan int)
+	go func() {
+		resultChan <- fe.Run()
+	}()
+	defer func() {
+		fe.Terminate(0)
+		<-resultChan
+	}()
+
+	resourceModule := mm.GetModule("resource").(resourcem.AppModule)
+	waitPlaying := func(unique string) {
+		t.Helper()
+		for i := 0; i < 200; i++ {
+			reply, err := resourceModule.ResourceCurrent(context.Background(), &svrproto.ResourceCurrentArgs{})
+			if err == nil && strings.HasPrefix(reply.Resource.Unique, unique) && reply.Resource.StartTime != 0 {
+				return
+			}
+			time.Sleep(time.Millisecond * 10)
+		}
+		t.Fatalf("wait playing resource %s timeout", unique)
+	}
+	waitPlaying("")
+	fe.Advance(time.Second * 30)
+
+	// the playlist resource is cut at 30 seconds, it is resumed from the position after the interrupt
+	if _, err := resourceModule.ResourceInterrupt(context.Background(), &svrproto.ResourceInterruptArgs{
+		Paths:  []string{"alert.mp4"},
+		Unique: "alert",
+	}); err != nil {
+		t.Fatal(err)
+	}
+	waitPlaying("alert")
+
+	if files, err := SaveConfig(clientCtx, mm); err != nil || len(files) != 0 {
+		t.Fatalf("unexpected save result. files: %v, error: %v", files, err)
+	}
+	if content, _ := ioutil.ReadFile("config.yaml"); string(content) != mainConfig {
+		t.Fatalf("unexpected main config changed: %s", content)
+	}
+
+	reply, err := resourceModule.ResourceExport(context.Background(), &svrproto.ResourceExportArgs{Format: "json"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(reply.Content, "\"seek\"") {
+		t.Fatalf("unexpected exported playlist: %s", reply.Content)
+	}
+}
@@ -0,0 +1,177 @@
+package app
+
+import (
+	"encoding/json"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/bytelang/kplayer/types/config"
+	"github.com/spf13/viper"
+	"google.golang.org/protobuf/types/known/anypb"
+)
+
+const jsonSchemaDraft = "http://json-schema.org/draft-07/schema#"
+
+var (
+	anyType               = reflect.TypeOf(anypb.Any{})
+	resourceMediaTypeType = reflect.TypeOf(config.ResourceMediaType(0))
+)
+
+// ConfigSchema return the JSON Schema of the config file.
+// the fields, rules and default values are reflected from the config protos
+func ConfigSchema() ([]byte, error) {
+	v := viper.New()
+	setDefaultConfig(v)
+
+	schema := structSchema(reflect.TypeOf(config.KPConfig{}), "", v)
+	schema["$schema"] = jsonSchemaDraft
+	schema["title"] = AppName + " config"
+
+	return json.MarshalIndent(schema, "", "    ")
+}
+
+func structSchema(t reflect.Type, prefix string, v *viper.Viper) map[string]interface{} {
+	properties := map[string]interface{}{}
+	var required []string
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name := strings.SplitN(field.Tag.Get("json"), ",", 2)[0]
+		if field.PkgPath != "" || name == "" || name == "-" {
+			continue
+		}
+
+		path := name
+		if prefix != "" {
+			path = prefix + "." + name
+		}
+
+		// fields with default value are not required in the config file
+		property := typeSchema(field.Type, path, v)
+		isRequired := applyValidateRules(property, field.Tag.Get("validate"))
+		if value := v.Get(path); value != nil {
+			isRequired = false
+			if _, ok := value.(map[string]interface{}); !ok {
+				property["default"] = value
+			}
+		}
+		if isRequired {
+			required = append(required, name)
+		}
+		properties[name] = property
+	}
+
+	schema := map[string]interface{}{
+		"type":       "object",
+		"properties": properties,
+	}
+	if len(required) != 0 {
+		schema["required"] = required
+	}
+
+	return schema
+}
+
+func typeSchema(t reflect.Type, path string, v *viper.Viper) map[string]interface{} {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch {
+	case t == anyType:
+		// resource list item is a path string, a single, mix or directory resource.
+		// the unknown fields are rejected as the item is recognized by its fields
+		oneOf := []interface{}{map[string]interface{}{"type": "string"}}
+		for _, item := range []interface{}{config.SingleResource{}, config.MixResource{}, config.DirectoryResource{}} {
+			itemSchema := structSchema(reflect.TypeOf(item), path, v)
+			itemSchema["additionalProperties"] = false
+			oneOf = append(oneOf, itemSchema)
+		}
+		return map[string]interface{}{"oneOf": oneOf}
+	case t == resourceMediaTypeType:
+		// enum accepts both of the name and the number
+		var enum []interface{}
+		for key := int32(0); key < int32(len(config.ResourceMediaType_name)); key++ {
+			enum = append(enum, config.ResourceMediaType_name[key], key)
+		}
+		return map[string]interface{}{"enum": enum}
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		return structSchema(t, path, v)
+	case reflect.Slice:
+		return map[string]interface{}{"type": "array", "items": typeSchema(t.Elem(), path, v)}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": typeSchema(t.Elem(), path, v)}
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer", "minimum": 0}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	}
+
+	return map[string]interface{}{}
+}
+
+// applyValidateRules translate the validate tag to schema keywords. return true on the required rule
+func applyValidateRules(property map[string]interface{}, tag string) bool {
+	required := false
+	for _, rule := range strings.Split(tag, ",") {
+		kv := strings.SplitN(rule, "=", 2)
+		if len(kv) == 1 {
+			if kv[0] == "required" {
+				required = true
+			}
+			continue
+		}
+
+		name, param := kv[0], kv[1]
+		switch name {
+		case "oneof":
+			var enum []interface{}
+			for _, item := range strings.Fields(param) {
+				if property["type"] == "integer" {
+					if number, err := strconv.ParseInt(item, 10, 64); err == nil {
+						enum = append(enum, number)
+						continue
+					}
+				}
+				enum = append(enum, item)
+			}
+			property["enum"] = enum
+		case "gt", "gte", "lt", "lte", "min", "max":
+			number, err := strconv.ParseInt(param, 10, 64)
+			if err != nil {
+				continue
+			}
+			if property["type"] == "string" {
+				if name == "min" {
+					property["minLength"] = number
+				} else if name == "max" {
+					property["maxLength"] = number
+				}
+				continue
+			}
+			switch name {
+			case "gt":
+				property["exclusiveMinimum"] = number
+				delete(property, "minimum")
+			case "gte", "min":
+				property["minimum"] = number
+			case "lt":
+				property["exclusiveMaximum"] = number
+			case "lte", "max":
+				property["maximum"] = number
+			}
+		}
+	}
+
+	return required
+}
@@ -0,0 +1,283 @@
+package app
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+
+	"github.com/bytelang/kplayer/channel"
+	pluginprovider "github.com/bytelang/kplayer/module/plugin/provider"
+	resourceprovider "github.com/bytelang/kplayer/module/resource/provider"
+	kptypes "github.com/bytelang/kplayer/types"
+	"github.com/bytelang/kplayer/types/config"
+	errortypes "github.com/bytelang/kplayer/types/error"
+	"github.com/go-playground/validator/v10"
+)
+
+// ConfigProblem problem of the config file located by the json path of field
+type ConfigProblem struct {
+	Path    string `json:"path"`
+	Message string `json:"message"`
+}
+
+func (p ConfigProblem) String() string {
+	return fmt.Sprintf("%s: %s", p.Path, p.Message)
+}
+
+// CheckConfig read the config file and return all the problems found in it
+func CheckConfig(configFileName string) []ConfigProblem {
+	loadedConfig, err := readConfig(configFileName, false)
+	if err != nil {
+		return []ConfigProblem{{Path: "$", Message: err.Error()}}
+	}
+	cfg := loadedConfig.Config
+
+	var problems []ConfigProblem
+	addProblem := func(path string, format string, args ...interface{}) {
+		problems = append(problems, ConfigProblem{Path: path, Message: fmt.Sprintf(format, args...)})
+	}
+
+	// global
+	if cfg.Version != ConfigVersion {
+		if _, ok := getMigration(cfg.Version); ok || cfg.Version == "" {
+			addProblem("$.version", "%s. expected: %s, current: %s. run `%s config migrate` to upgrade", errortypes.VersionInvalidMainError, ConfigVersion, cfg.Version, AppName)
+		} else {
+			addProblem("$.version", "%s. expected: %s, current: %s", errortypes.VersionInvalidMainError, ConfigVersion, cfg.Version)
+		}
+	}
+	if cfg.TokenPath != "" && !kptypes.FileExists(cfg.TokenPath) {
+		addProblem("$.token_path", "%s. path: %s", errortypes.TokenFileNotFoundMainError, cfg.TokenPath)
+	}
+	if cfg.Auth != nil && cfg.Auth.AuthOn {
+		problems = append(problems, checkStruct("$.auth", cfg.Auth)...)
+	}
+	if _, err := channel.NewManager(cfg.Channels); err != nil {
+		addProblem("$.channels", "%s", err)
+	}
+
+	// play
+	problems = append(problems, checkStruct("$.play", &cfg.Play)...)
+
+	// resource
+	var items []interface{}
+	if resourceSettings, ok := loadedConfig.RawSettings["resource"].(map[string]interface{}); ok {
+		items, _ = resourceSettings["lists"].([]interface{})
+	}
+	resourceCount := 0
+	resourceUniques := map[string]string{}
+	for key, item := range items {
+		path := fmt.Sprintf("$.resource.lists[%d]", key)
+		res, err := unpackResourceItem(item)
+		if err != nil {
+			addProblem(path, "%s", err)
+			continue
+		}
+
+		var unique string
+		switch assertRes := res.(type) {
+		case *config.SingleResource:
+			unique = assertRes.Unique
+			problems = append(problems, checkStruct(path, assertRes)...)
+			if assertRes.Path == "" {
+				addProblem(path+".path", "%s", "resource path can not be empty")
+				break
+			}
+			if files, err := kptypes.GetDirectorFiles(assertRes.Path); err == nil {
+				count := 0
+				for _, f := range files {
+					ext := strings.TrimPrefix(filepath.Ext(f), ".")
+					if cfg.Resource.Extensions == nil || kptypes.ArrayInString(cfg.Resource.Extensions, ext) {
+						count = count + 1
+					}
+				}
+				if count == 0 {
+					addProblem(path+".path", "directory has no resource of allowed extensions. path: %s, extensions: %v", assertRes.Path, cfg.Resource.Extensions)
+				}
+				resourceCount = resourceCount + count
+				break
+			}
+			if !resourcePathExists(assertRes.Path) {
+				addProblem(path+".path", "file not exists. path: %s", assertRes.Path)
+			}
+			if assertRes.End > 0 && assertRes.End < assertRes.Seek {
+				addProblem(path+".end", "end timestamp can not be less than start timestamp")
+			}
+			resourceCount = resourceCount + 1
+		case *config.DirectoryResource:
+			unique = assertRes.Unique
+			problems = append(problems, checkStruct(path, assertRes)...)
+			if assertRes.Directory == "" {
+				break
+			}
+			files, err := resourceprovider.ScanDirectoryResource(assertRes, cfg.Resource.Extensions)
+			if err != nil {
+				addProblem(path+".directory", "directory invalid. directory: %s, error: %s", assertRes.Directory, err)
+				break
+			}
+			if len(files) == 0 && !assertRes.Live {
+				addProblem(path+".directory", "directory has no resource of allowed extensions. directory: %s, extensions: %v", assertRes.Directory, cfg.Resource.Extensions)
+			}
+			resourceCount = resourceCount + len(files)
+			if assertRes.Live && unique == "" {
+				unique = filepath.Clean(assertRes.Directory)
+			}
+		case *config.MixResource:
+			unique = assertRes.Unique
+			problems = append(problems, checkStruct(path, assertRes)...)
+			mediaTypes := map[config.ResourceMediaType]bool{}
+			for groupKey, group := range assertRes.Groups {
+				groupPath := fmt.Sprintf("%s.groups[%d]", path, groupKey)
+				problems = append(problems, checkStruct(groupPath, group)...)
+				if !resourcePathExists(group.Path) {
+					addProblem(groupPath+".path", "file not exists. path: %s", group.Path)
+				}
+				mediaTypes[group.MediaType] = true
+			}
+			if !mediaTypes[config.ResourceMediaType_video] || !mediaTypes[config.ResourceMediaType_audio] {
+				addProblem(path+".groups", "mix resource requires both video and audio group")
+			}
+			if assertRes.End > 0 && assertRes.End < assertRes.Seek {
+				addProblem(path+".end", "end timestamp can not be less than start timestamp")
+			}
+			resourceCount = resourceCount + 1
+		}
+
+		if unique == "" {
+			continue
+		}
+		if exist, ok := resourceUniques[unique]; ok {
+			addProblem(path+".unique", "resource unique name has existed. unique: %s, existed: %s", unique, exist)
+			continue
+		}
+		resourceUniques[unique] = path
+	}
+	hotFolder := cfg.Resource.HotFolder != nil && cfg.Resource.HotFolder.Path != ""
+	if hotFolder {
+		if stat, err := os.Stat(cfg.Resource.HotFolder.Path); err != nil || !stat.IsDir() {
+			addProblem("$.resource.hot_folder.path", "hot folder not exists. path: %s", cfg.Resource.HotFolder.Path)
+		}
+	}
+	schedule := cfg.Resource.Schedule != nil && len(cfg.Resource.Schedule.Slots) != 0
+	if schedule {
+		slotUniques := map[string]string{}
+		for key, item := range cfg.Resource.Schedule.Slots {
+			path := fmt.Sprintf("$.resource.schedule.slots[%d]", key)
+			if slotProblems := checkStruct(path, item); len(slotProblems) != 0 {
+				problems = append(problems, slotProblems...)
+				continue
+			}
+			if err := resourceprovider.ValidateScheduleSlot(item, cfg.Resource.Schedule.Timezone); err != nil {
+				addProblem(path, "%s", err)
+			}
+			if stat, err := os.Stat(item.Path); err != nil {
+				addProblem(path+".path", "schedule slot path not exists. path: %s", item.Path)
+			} else if !stat.IsDir() && !resourceprovider.IsPlaylistFile(item.Path) {
+				addProblem(path+".path", "schedule slot path is not playlist file or directory. path: %s", item.Path)
+			}
+			if item.Unique == "" {
+				continue
+			}
+			if exist, ok := slotUniques[item.Unique]; ok {
+				addProblem(path+".unique", "%s. unique: %s, existed: %s", resourceprovider.ScheduleSlotHasExisted, item.Unique, exist)
+				continue
+			}
+			slotUniques[item.Unique] = path
+		}
+	}
+	if resourceCount == 0 {
+		// the playlist waits for the files of hot folder or the schedule slot
+		if !hotFolder && !schedule {
+			addProblem("$.resource.lists", "resource list can not be empty")
+		}
+	} else if cfg.Play.PlayModel != "random" && int(cfg.Play.StartPoint) > resourceCount {
+		addProblem("$.play.start_point", "start point invalid. cannot great than total resource %d", resourceCount)
+	}
+
+	// output
+	outputUniques := map[string]string{}
+	for key, item := range cfg.Output.Lists {
+		path := fmt.Sprintf("$.output.lists[%d]", key)
+		if item.Path == "" {
+			addProblem(path+".path", "output path cannot be empty")
+		}
+		if item.Unique == "" {
+			continue
+		}
+		if exist, ok := outputUniques[item.Unique]; ok {
+			addProblem(path+".unique", "output unique has existed. unique: %s, existed: %s", item.Unique, exist)
+			continue
+		}
+		outputUniques[item.Unique] = path
+	}
+
+	// plugin
+	pluginUniques := map[string]string{}
+	for key, item := range cfg.Plugin.Lists {
+		path := fmt.Sprintf("$.plugin.lists[%d]", key)
+		if item.Path == "" {
+			addProblem(path+".path", "plugin path cannot be empty")
+		} else if !kptypes.FileExists(pluginprovider.GetPluginPath(item.Path)) {
+			addProblem(path+".path", "%s. path: %s", pluginprovider.PluginFileNotFound, pluginprovider.GetPluginPath(item.Path))
+		}
+		if item.Unique == "" {
+			continue
+		}
+		if exist, ok := pluginUniques[item.Unique]; ok {
+			addProblem(path+".unique", "%s. unique: %s, existed: %s", pluginprovider.PluginUniqueHasExist, item.Unique, exist)
+			continue
+		}
+		pluginUniques[item.Unique] = path
+	}
+
+	return problems
+}
+
+// checkStruct validate the struct by the validate tags of config protos
+func checkStruct(path string, s interface{}) []ConfigProblem {
+	validate := validator.New()
+	validate.RegisterTagNameFunc(func(field reflect.StructField) string {
+		return strings.SplitN(field.Tag.Get("json"), ",", 2)[0]
+	})
+
+	err := validate.Struct(s)
+	if err == nil {
+		return nil
+	}
+	validationErrors, ok := err.(validator.ValidationErrors)
+	if !ok {
+		return []ConfigProblem{{Path: path, Message: err.Error()}}
+	}
+
+	var problems []ConfigProblem
+	for _, item := range validationErrors {
+		// the namespace begins with the struct name
+		namespace := item.Namespace()
+		if index := strings.Index(namespace, "."); index >= 0 {
+			namespace = namespace[index+1:]
+		}
+
+		rule := item.Tag()
+		if item.Param() != "" {
+			rule = rule + "=" + item.Param()
+		}
+		problems = append(problems, ConfigProblem{
+			Path:    path + "." + namespace,
+			Message: fmt.Sprintf("failed on the '%s' rule. value: %v", rule, item.Value()),
+		})
+	}
+
+	return problems
+}
+
+// resourcePathExists return false when the local file not exists. the remote resources are not checked
+func resourcePathExists(path string) bool {
+	if parseUrl, err := url.Parse(path); err == nil && parseUrl.Scheme != "" {
+		return true
+	}
+
+	_, err := os.Stat(path)
+	return err == nil
+}
@@ -0,0 +1,101 @@
+package app
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCheckConfig(t *testing.T) {
+	dir := t.TempDir()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+
+	if err := ioutil.WriteFile(filepath.Join(dir, "a.mp4"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	cfg := `{
+    "version": "1.0.0",
+    "resource": {
+        "lists": [
+            "a.mp4",
+            {"path": "missing.mp4", "unique": "a"},
+            {"path": "a.mp4", "unique": "a", "seek": 20, "end": 10},
+            {"unique": "b", "groups": [{"path": "a.mp4", "media_type": 1}]},
+            123,
+            {"directory": "missing", "live": true},
+            {"directory": ".", "live": true, "include": ["*.mp4"]}
+        ],
+        "schedule": {
+            "timezone": "Asia/Shanghai",
+            "slots": [
+                {"start": "08:00", "path": ".", "days": ["weekdays"]},
+                {"start": "25:00", "path": "missing", "days": ["someday"]}
+            ]
+        }
+    },
+    "output": {"lists": [{"path": "rtmp://127.0.0.1/live", "unique": "o"}, {"path": "rtmp://127.0.0.1/live", "unique": "o"}]},
+    "plugin": {"lists": [{"path": "missing", "unique": "p"}]}
+}`
+	if err := ioutil.WriteFile(filepath.Join(dir, "config.json"), []byte(cfg), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	paths := map[string]bool{}
+	for _, item := range CheckConfig("config") {
+		paths[item.Path] = true
+	}
+	for _, expect := range []string{
+		"$.version",
+		"$.resource.lists[1].path",
+		"$.resource.lists[2].unique",
+		"$.resource.lists[2].end",
+		"$.resource.lists[3].groups",
+		"$.resource.lists[4]",
+		"$.resource.lists[5].directory",
+		"$.resource.schedule.slots[1]",
+		"$.resource.schedule.slots[1].path",
+		"$.output.lists[1].unique",
+		"$.plugin.lists[0].path",
+	} {
+		if !paths[expect] {
+			t.Errorf("expected problem of %s. problems: %v", expect, paths)
+		}
+	}
+	if paths["$.resource.lists[0].path"] || paths["$.resource.lists[6].directory"] ||
+		paths["$.resource.schedule.slots[0]"] || paths["$.resource.schedule.slots[0].path"] {
+		t.Errorf("unexpected problem of existed resource")
+	}
+}
+
+func TestConfigSchema(t *testing.T) {
+	d, err := ConfigSchema()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var schema struct {
+		Properties map[string]struct {
+			Properties map[string]json.RawMessage `json:"properties"`
+		} `json:"properties"`
+	}
+	if err := json.Unmarshal(d, &schema); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"version", "resource", "play", "output", "plugin"} {
+		if _, ok := schema.Properties[name]; !ok {
+			t.Errorf("schema property %s not found", name)
+		}
+	}
+	if _, ok := schema.Properties["play"].Properties["start_point"]; !ok {
+		t.Errorf("schema property play.start_point not found")
+	}
+}
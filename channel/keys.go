@@ -0,0 +1,11 @@
+package channel
+
+const (
+	ChannelNotFound ChannelError = "channel not found"
+)
+
+type ChannelError string
+
+func (e ChannelError) Error() string {
+	return string(e)
+}
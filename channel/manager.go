@@ -0,0 +1,288 @@
+package channel
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"sort"
+	"sync"
+	"syscall"
+	"time"
+
+	kptypes "github.com/bytelang/kplayer/types"
+	"github.com/bytelang/kplayer/types/config"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+const (
+	DefaultConfigFileName = "config"
+
+	// channel log file in the home of the manager process
+	logFilePathFormat = "log/channel.%s.log"
+)
+
+const (
+	restartMinBackoff = time.Second
+	restartMaxBackoff = time.Minute
+
+	// a child running longer than the stable period refresh the restart backoff
+	restartStablePeriod = time.Minute * 10
+
+	// the children not exited in the timeout after terminated are killed
+	stopTimeout = time.Second * 10
+)
+
+var channelNameRegexp = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
+
+// Channel named channel played by a child kplayer process.
+// libkplayer hosts a single instance per process, every channel runs in its own process
+type Channel struct {
+	Name   string
+	Home   string
+	Config string
+
+	grpcEndpoint string
+	httpEndpoint string
+
+	cmd *exec.Cmd
+}
+
+// Manager supervise the child processes of channels
+type Manager struct {
+	channels map[string]*Channel
+	logLevel string
+
+	stopping bool
+	lock     sync.Mutex
+	wait     sync.WaitGroup
+}
+
+// NewManager return manager of the channels config. the rpc endpoints are resolved from the config of channels
+func NewManager(cfg map[string]*config.Channel) (*Manager, error) {
+	m := &Manager{
+		channels: make(map[string]*Channel),
+	}
+
+	endpoints := map[string]string{}
+	for name, item := range cfg {
+		if !channelNameRegexp.MatchString(name) {
+			return nil, fmt.Errorf("channel name invalid: %s", name)
+		}
+		if item == nil || item.Home == "" {
+			return nil, fmt.Errorf("channel home cannot be empty: %s", name)
+		}
+
+		home, err := filepath.Abs(item.Home)
+		if err != nil {
+			return nil, err
+		}
+		configName := item.Config
+		if configName == "" {
+			configName = DefaultConfigFileName
+		}
+
+		ch := &Channel{
+			Name:   name,
+			Home:   home,
+			Config: configName,
+		}
+		if err := ch.loadEndpoint(); err != nil {
+			return nil, fmt.Errorf("load channel config failed. channel: %s, error: %s", name, err)
+		}
+
+		for _, endpoint := range []string{ch.grpcEndpoint, ch.httpEndpoint} {
+			if exist, ok := endpoints[endpoint]; ok {
+				return nil, fmt.Errorf("channel rpc endpoint conflict. channel: %s, %s, endpoint: %s", exist, name, endpoint)
+			}
+			endpoints[endpoint] = name
+		}
+
+		m.channels[name] = ch
+	}
+
+	return m, nil
+}
+
+// loadEndpoint read the rpc endpoints from the channel config
+func (ch *Channel) loadEndpoint() error {
+	v := viper.New()
+	v.AddConfigPath(ch.Home)
+	v.SetConfigType("json")
+	if !kptypes.FileExists(filepath.Join(ch.Home, ch.Config)) && !kptypes.FileExists(filepath.Join(ch.Home, ch.Config+".json")) {
+		v.SetConfigType("yaml")
+	}
+	v.SetConfigName(ch.Config)
+	if err := v.ReadInConfig(); err != nil {
+		return err
+	}
+
+	v.SetDefault("play.rpc.on", true)
+	v.SetDefault("play.rpc.http_port", kptypes.DefaultHttpPort)
+	v.SetDefault("play.rpc.grpc_port", kptypes.DefaultRPCPort)
+	v.SetDefault("play.rpc.address", kptypes.DefaultRPCAddress)
+	if !v.GetBool("play.rpc.on") {
+		return fmt.Errorf("channel rpc server must be on")
+	}
+	// the child would start the channels recursively
+	if v.IsSet("channels") {
+		return fmt.Errorf("channel config cannot contain channels")
+	}
+
+	address := v.GetString("play.rpc.address")
+	ch.grpcEndpoint = fmt.Sprintf("%s:%d", address, v.GetUint32("play.rpc.grpc_port"))
+	ch.httpEndpoint = fmt.Sprintf("%s:%d", address, v.GetUint32("play.rpc.http_port"))
+
+	return nil
+}
+
+// Start start the child processes of channels
+func (m *Manager) Start(logLevel string) {
+	m.logLevel = logLevel
+
+	for _, name := range m.GetChannelNames() {
+		m.wait.Add(1)
+		go m.supervise(m.channels[name])
+	}
+}
+
+// Stop terminate the child processes and wait for the supervisors exited. the children not exited in the stop
+// timeout are killed
+func (m *Manager) Stop() {
+	m.lock.Lock()
+	m.stopping = true
+	m.signalChannels(syscall.SIGTERM)
+	m.lock.Unlock()
+
+	done := make(chan bool)
+	go func() {
+		m.wait.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return
+	case <-time.After(stopTimeout):
+	}
+
+	log.WithField("timeout", stopTimeout.String()).Warn("channels not exited on terminated. kill them")
+	m.lock.Lock()
+	m.signalChannels(syscall.SIGKILL)
+	m.lock.Unlock()
+
+	<-done
+}
+
+// signalChannels send the signal to the process groups of children running. the manager lock must be held
+func (m *Manager) signalChannels(sig syscall.Signal) {
+	for _, ch := range m.channels {
+		if ch.cmd != nil && ch.cmd.Process != nil {
+			_ = syscall.Kill(-ch.cmd.Process.Pid, sig)
+		}
+	}
+}
+
+// GetChannelNames return the sorted channel names
+func (m *Manager) GetChannelNames() []string {
+	var names []string
+	for name := range m.channels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// GetChannelEndpoint return the grpc and http endpoint of channel
+func (m *Manager) GetChannelEndpoint(name string) (string, string, error) {
+	ch, ok := m.channels[name]
+	if !ok {
+		return "", "", ChannelNotFound
+	}
+
+	return ch.grpcEndpoint, ch.httpEndpoint, nil
+}
+
+// supervise run the child process of channel, restart it until the manager stopping
+func (m *Manager) supervise(ch *Channel) {
+	defer m.wait.Done()
+
+	logFields := log.WithFields(log.Fields{"channel": ch.Name, "home": ch.Home})
+	delay := restartMinBackoff
+	for {
+		begin := time.Now()
+		err := m.run(ch)
+
+		m.lock.Lock()
+		stopping := m.stopping
+		m.lock.Unlock()
+		if stopping {
+			logFields.Info("channel stopped")
+			return
+		}
+
+		if time.Since(begin) >= restartStablePeriod {
+			delay = restartMinBackoff
+		}
+		logFields.WithFields(log.Fields{"error": err, "delay": delay.String()}).Warn("channel exited. will be restarted")
+		time.Sleep(delay)
+
+		delay = delay * 2
+		if delay > restartMaxBackoff {
+			delay = restartMaxBackoff
+		}
+	}
+}
+
+// run start the child process and wait for it exited
+func (m *Manager) run(ch *Channel) error {
+	executable, err := os.Executable()
+	if err != nil {
+		return err
+	}
+
+	logFile, err := os.OpenFile(fmt.Sprintf(logFilePathFormat, ch.Name), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	defer logFile.Close()
+
+	cmd := exec.Command(executable, "play", "start",
+		"--"+kptypes.FlagHome, ch.Home,
+		"--"+kptypes.FlagConfigFileName, ch.Config,
+		"--"+kptypes.FlagLogLevel, m.logLevel,
+	)
+	cmd.Stdout = logFile
+	cmd.Stderr = logFile
+	cmd.SysProcAttr = childProcAttr()
+
+	// the parent death signal is sent when the thread started the child exited, keep the thread until the child exited
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	m.lock.Lock()
+	if m.stopping {
+		m.lock.Unlock()
+		return nil
+	}
+	if err := cmd.Start(); err != nil {
+		m.lock.Unlock()
+		return err
+	}
+	ch.cmd = cmd
+	m.lock.Unlock()
+
+	log.WithFields(log.Fields{"channel": ch.Name, "pid": cmd.Process.Pid, "grpc": ch.grpcEndpoint, "http": ch.httpEndpoint}).Info("channel started")
+	err = cmd.Wait()
+
+	// the pid of child exited may be reused
+	m.lock.Lock()
+	ch.cmd = nil
+	m.lock.Unlock()
+
+	return err
+}
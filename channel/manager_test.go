@@ -0,0 +1,81 @@
+package channel
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bytelang/kplayer/types/config"
+)
+
+func writeChannelConfig(t *testing.T, content string) string {
+	home := t.TempDir()
+	if err := ioutil.WriteFile(filepath.Join(home, DefaultConfigFileName+".yaml"), []byte(content), os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+
+	return home
+}
+
+func TestNewManager(t *testing.T) {
+	news := writeChannelConfig(t, `
+version: 2.0.0
+play:
+  rpc:
+    http_port: 4256
+    grpc_port: 4255
+`)
+	sports := writeChannelConfig(t, `
+version: 2.0.0
+play:
+  rpc:
+    address: 127.0.0.2
+`)
+
+	m, err := NewManager(map[string]*config.Channel{
+		"news":   {Home: news},
+		"sports": {Home: sports, Config: DefaultConfigFileName},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	grpcEndpoint, httpEndpoint, err := m.GetChannelEndpoint("news")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if grpcEndpoint != "127.0.0.1:4255" || httpEndpoint != "127.0.0.1:4256" {
+		t.Fatalf("unexpected endpoint: %s %s", grpcEndpoint, httpEndpoint)
+	}
+
+	grpcEndpoint, httpEndpoint, err = m.GetChannelEndpoint("sports")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if grpcEndpoint != "127.0.0.2:4155" || httpEndpoint != "127.0.0.2:4156" {
+		t.Fatalf("unexpected endpoint: %s %s", grpcEndpoint, httpEndpoint)
+	}
+
+	if _, _, err := m.GetChannelEndpoint("movies"); err != ChannelNotFound {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestNewManagerInvalid(t *testing.T) {
+	home := writeChannelConfig(t, "version: 2.0.0\n")
+	nested := writeChannelConfig(t, "version: 2.0.0\nchannels:\n  news:\n    home: /tmp\n")
+
+	cases := map[string]map[string]*config.Channel{
+		"invalid name":      {"news/live": {Home: home}},
+		"empty home":        {"news": {}},
+		"config not found":  {"news": {Home: t.TempDir()}},
+		"endpoint conflict": {"news": {Home: home}, "sports": {Home: home}},
+		"nested channels":   {"news": {Home: nested}},
+	}
+	for name, item := range cases {
+		if _, err := NewManager(item); err == nil {
+			t.Fatalf("%s should be failed", name)
+		}
+	}
+}
@@ -0,0 +1,14 @@
+//go:build linux
+// +build linux
+
+package channel
+
+import "syscall"
+
+// childProcAttr run the child in its own process group, the child is terminated once the manager process died
+func childProcAttr() *syscall.SysProcAttr {
+	return &syscall.SysProcAttr{
+		Setpgid:   true,
+		Pdeathsig: syscall.SIGTERM,
+	}
+}
@@ -0,0 +1,13 @@
+//go:build !linux
+// +build !linux
+
+package channel
+
+import "syscall"
+
+// childProcAttr run the child in its own process group
+func childProcAttr() *syscall.SysProcAttr {
+	return &syscall.SysProcAttr{
+		Setpgid: true,
+	}
+}
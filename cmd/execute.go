@@ -0,0 +1,128 @@
+package cmd
+
+import (
+	"context"
+	"os"
+
+	"github.com/bytelang/kplayer/app"
+	"github.com/bytelang/kplayer/core"
+	"github.com/bytelang/kplayer/module"
+	"github.com/bytelang/kplayer/module/play/provider"
+	"github.com/bytelang/kplayer/server"
+	kptypes "github.com/bytelang/kplayer/types"
+	"github.com/go-playground/validator/v10"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// Execute run kplayer on the libkplayer core. the modules are created on call,
+// downstream binaries register their modules by module.Register before calling it
+func Execute(defaultHome string, defaultFile string) error {
+	engine := core.GetLibKplayerInstance()
+	mm := app.NewModuleManager(engine)
+
+	return ExecuteRootCmd(NewRootCmd(engine, mm), mm, defaultHome, defaultFile)
+}
+
+// ExecuteRootCmd execute from flags and commands
+func ExecuteRootCmd(rootCmd *cobra.Command, mm module.ModuleManager, defaultHome string, defaultFile string) error {
+	rootCmd.PersistentFlags().String(kptypes.FlagLogLevel, log.InfoLevel.String(), "The logging level (trace|debug|info|warn|error|fatal|panic)")
+	rootCmd.PersistentFlags().String(kptypes.FlagLogFormat, "plain", "The logging format (json|plain)")
+	rootCmd.PersistentFlags().StringP(kptypes.FlagHome, "", defaultHome, "directory for config and data")
+	rootCmd.PersistentFlags().StringP(kptypes.FlagConfigFileName, "c", defaultFile, "config file name")
+	rootCmd.PersistentPreRunE = func(cmd *cobra.Command, args []string) error {
+		// set home path
+		homePath, err := cmd.Flags().GetString(kptypes.FlagHome)
+		if err != nil {
+			return err
+		}
+		if homePath != "" {
+			if err := os.Chdir(homePath); err != nil {
+				log.WithField("error", err).Fatal("chdir failed")
+			}
+		}
+
+		// init context
+		InitGlobalContextConfig(cmd)
+		return nil
+	}
+
+	ctx := context.Background()
+	ctx = context.WithValue(ctx, kptypes.ClientContextKey, kptypes.DefaultClientContext())
+	ctx = context.WithValue(ctx, kptypes.ModuleManagerContextKey, mm)
+	ctx = context.WithValue(ctx, kptypes.ServerCreatorContextKey, server.NewHttpServer())
+	ctx = context.WithValue(ctx, kptypes.ConfigReloaderContextKey, app.NewConfigReloader(mm))
+
+	return kptypes.SetCommandContextAndExecute(rootCmd, ctx)
+}
+
+func InitGlobalContextConfig(cmd *cobra.Command) {
+	mm := cmd.Context().Value(kptypes.ModuleManagerContextKey).(module.ModuleManager)
+	clientCtx := cmd.Context().Value(kptypes.ClientContextKey).(*kptypes.ClientContext)
+
+	configFileName, err := kptypes.GetConfigFileName(cmd)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// set log level
+	logLevel, err := kptypes.GetLogLevel(cmd)
+	if err != nil {
+		log.Fatal(err)
+	}
+	log.SetLevel(logLevel)
+	if logLevel == log.InfoLevel {
+		log.SetReportCaller(false)
+	}
+
+	// skip on init stage and the tools of config file
+	if cmd.Parent().Use == "init" || cmd.Annotations[app.AnnotationSkipLoadConfig] == "true" {
+		return
+	}
+
+	// load config context in file
+	generateCache := false
+	if cmd.Flag(provider.FlagGenerateCache) != nil {
+		generateCache = cmd.Flag(provider.FlagGenerateCache).Value.String() == provider.FlagYesValue
+	}
+	loadedConfig, err := app.LoadConfig(configFileName, generateCache)
+	if err != nil {
+		log.Fatal(err)
+	}
+	v := loadedConfig.Viper
+	clientCtx.Viper = v
+	clientCtx.Config = loadedConfig.Config
+
+	// init module
+	for _, m := range mm.GetOrderedModules() {
+
+		// init config and set default value
+		section, err := loadedConfig.GetModuleSection(m.GetModuleName())
+		if err != nil {
+			log.Fatal(err)
+		}
+		modifyData, err := m.InitConfig(clientCtx, section)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		// validator
+		validate := validator.New()
+		if err := validate.Struct(modifyData); err != nil {
+			log.Fatal(err)
+		}
+
+		// set modify data
+		v.Set(m.GetModuleName(), modifyData)
+
+		// validate config
+		if err := m.ValidateConfig(); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	// set context before module modify
+	if err := v.Unmarshal(clientCtx.Config); err != nil {
+		log.Fatal(err)
+	}
+}
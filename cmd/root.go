@@ -2,10 +2,11 @@ package cmd
 
 import (
 	"fmt"
-	"sync"
 
 	"github.com/bytelang/kplayer/app"
 	"github.com/bytelang/kplayer/core"
+	"github.com/bytelang/kplayer/eventbus"
+	"github.com/bytelang/kplayer/module"
 	"github.com/bytelang/kplayer/types"
 	kpproto "github.com/bytelang/kplayer/types/core/proto"
 	log "github.com/sirupsen/logrus"
@@ -15,17 +16,11 @@ import (
 
 const terminalCharsetMaxCount uint = 115
 
-var subscribeCollector map[string]chan kpproto.KPMessage
-var subscribeMutex sync.Mutex
-
-func init() {
-	subscribeCollector = make(map[string]chan kpproto.KPMessage)
-}
-
-func NewRootCmd() *cobra.Command {
+// NewRootCmd return the root command of the modules running on the engine
+func NewRootCmd(coreKplayer core.Engine, mm module.ModuleManager) *cobra.Command {
 	// init core
-	coreKplayer := core.GetLibKplayerInstance()
-	coreKplayer.SetCallBackMessage(messageConsumer)
+	coreKplayer.SetCallBackMessage(newMessageConsumer(mm))
+	coreKplayer.SetCallBackProgress(newProgressConsumer(mm))
 
 	// get core information
 	info := coreKplayer.GetInformation()
@@ -72,60 +67,48 @@ func NewRootCmd() *cobra.Command {
 		},
 	}
 
-	initRootCmd(rootCmd)
+	initRootCmd(rootCmd, mm)
 	return rootCmd
 }
 
-func initRootCmd(rootCmd *cobra.Command) {
+func initRootCmd(rootCmd *cobra.Command, mm module.ModuleManager) {
 	// add init command
 	rootCmd.AddCommand(app.AddInitCommands())
 
+	// add config command
+	rootCmd.AddCommand(app.AddConfigCommands(mm))
+
 	// add module command
-	app.AddModuleCommands(rootCmd)
+	app.AddModuleCommands(rootCmd, mm)
 }
 
-func messageConsumer(action int, body string) {
-	log.WithFields(log.Fields{"action": kpproto.EventMessageAction_name[int32(action)]}).Debug("receive broadcast message")
+func newMessageConsumer(mm module.ModuleManager) func(message *core.Message) {
+	return func(message *core.Message) {
+		log.WithFields(log.Fields{
+			"action":         kpproto.EventMessageAction_name[int32(message.Action)],
+			"correlation_id": message.CorrelationId,
+		}).Debug("receive broadcast message")
 
-	var copyMsg kpproto.KPMessage
-	message := &kpproto.KPMessage{
-		Action: kpproto.EventMessageAction(action),
-		Body:   body,
-	}
-
-	for _, item := range app.ModuleManager.Modules {
-		copyMsg = *message
-		item.ParseMessage(&copyMsg)
-
-		copyMsg = *message
-		item.TriggerMessage(&copyMsg)
-	}
+		var copyMsg kpproto.KPMessage
+		for _, item := range mm.GetOrderedModules() {
+			copyMsg = *message.KPMessage
+			item.ParseMessage(&copyMsg)
 
-	go func() {
-		subscribeMutex.Lock()
-		defer subscribeMutex.Unlock()
-
-		for _, item := range subscribeCollector {
-			item <- *message
+			copyMsg = *message.KPMessage
+			item.TriggerMessage(&core.Message{KPMessage: &copyMsg, CorrelationId: message.CorrelationId})
 		}
-	}()
-}
 
-func SubscribeMessage(name string) (chan kpproto.KPMessage, error) {
-	subscribeMutex.Lock()
-	defer subscribeMutex.Unlock()
-
-	if _, ok := subscribeCollector[name]; ok {
-		return nil, fmt.Errorf("subscribe name has been registed")
+		// modules consume the messages in order without loss, the other subscribers are served by the event bus
+		eventbus.PublishMessage(message)
 	}
-
-	subscribeCollector[name] = make(chan kpproto.KPMessage, 500)
-	return subscribeCollector[name], nil
 }
 
-func CancelSubscribeMessage(name string) {
-	subscribeMutex.Lock()
-	defer subscribeMutex.Unlock()
-
-	delete(subscribeCollector, name)
+func newProgressConsumer(mm module.ModuleManager) func(percent float64, bitRate int) {
+	return func(percent float64, bitRate int) {
+		for _, item := range mm.GetOrderedModules() {
+			if m, ok := item.(module.ProgressAppModule); ok {
+				m.ParseProgress(percent, bitRate)
+			}
+		}
+	}
 }
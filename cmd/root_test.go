@@ -0,0 +1,115 @@
+package cmd
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/bytelang/kplayer/app"
+	"github.com/bytelang/kplayer/core"
+	"github.com/bytelang/kplayer/module"
+	kptypes "github.com/bytelang/kplayer/types"
+	kpproto "github.com/bytelang/kplayer/types/core/proto"
+	"github.com/spf13/cobra"
+)
+
+type externalConfig struct {
+	Greeting string `json:"greeting" validate:"required"`
+}
+
+// externalModule module of downstream binary, registered outside of app
+type externalModule struct {
+	*module.ModuleKeeper
+	config externalConfig
+	ran    bool
+}
+
+func (m *externalModule) GetModuleName() string                      { return "external" }
+func (m *externalModule) ParseMessage(message *kpproto.KPMessage)    {}
+func (m *externalModule) TriggerMessage(message *core.Message)       {}
+func (m *externalModule) ValidateConfig() error                      { return nil }
+func (m *externalModule) DependsOn() []string                        { return []string{"play"} }
+func (m *externalModule) BeginRunning(option ...module.ModuleOption) {}
+func (m *externalModule) EndRunning(option ...module.ModuleOption)   {}
+func (m *externalModule) InitConfig(ctx *kptypes.ClientContext, cfg json.RawMessage) (interface{}, error) {
+	if err := json.Unmarshal(cfg, &m.config); err != nil {
+		return nil, err
+	}
+	return m.config, nil
+}
+func (m *externalModule) GetCommand() *cobra.Command {
+	return &cobra.Command{
+		Use: "external",
+		Run: func(cmd *cobra.Command, args []string) {
+			m.ran = true
+		},
+	}
+}
+
+var (
+	registerExternal   sync.Once
+	registeredExternal *externalModule
+)
+
+func TestExecuteRegisteredModule(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+
+	home, err := ioutil.TempDir("", "kplayer-cmd-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(home)
+	files := map[string]string{
+		"config.yaml": "version: 2.0.0\nresource:\n  lists:\n  - a.flv\nexternal:\n  greeting: hello\n",
+		"a.flv":       "",
+		// the default font is downloaded when it is missing
+		"resource/font.ttf": "",
+	}
+	for name, content := range files {
+		if err := os.MkdirAll(filepath.Dir(filepath.Join(home, name)), os.ModePerm); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(filepath.Join(home, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// registered after app package initialized, as a downstream main does. the registry is kept over the test runs
+	external := &externalModule{ModuleKeeper: &module.ModuleKeeper{}}
+	registerExternal.Do(func() {
+		module.Register(func(engine core.Engine) module.AppModule {
+			return registeredExternal
+		})
+	})
+	registeredExternal = external
+
+	engine := core.NewFakeEngine()
+	mm := app.NewModuleManager(engine)
+	if mm.GetModule("external") == nil {
+		t.Fatal("registered module is not created")
+	}
+	if order := strings.Join(mm.Order, ","); !strings.HasPrefix(order, "play,") || !strings.HasSuffix(order, ",external") {
+		t.Fatalf("unexpected order: %s", order)
+	}
+
+	rootCmd := NewRootCmd(engine, mm)
+	rootCmd.SetArgs([]string{"--home", home, "external"})
+	if err := ExecuteRootCmd(rootCmd, mm, "./", app.DefaultConfigFileName); err != nil {
+		t.Fatal(err)
+	}
+
+	if !external.ran {
+		t.Fatal("command of registered module is not executed")
+	}
+	if external.config.Greeting != "hello" {
+		t.Fatalf("unexpected config of registered module: %+v", external.config)
+	}
+}
@@ -0,0 +1,154 @@
+package core
+
+import (
+	"sync"
+	"time"
+
+	kpproto "github.com/bytelang/kplayer/types/core/proto"
+	"github.com/google/uuid"
+)
+
+// promptReplyAction the message action which the core answers a prompt with
+var promptReplyAction = map[kpproto.EventPromptAction]kpproto.EventMessageAction{
+	kpproto.EventPromptAction_EVENT_PROMPT_ACTION_PLAYER_STOP:          kpproto.EventMessageAction_EVENT_MESSAGE_ACTION_PLAYER_STOP,
+	kpproto.EventPromptAction_EVENT_PROMPT_ACTION_PLAYER_PAUSE:         kpproto.EventMessageAction_EVENT_MESSAGE_ACTION_PLAYER_PAUSE,
+	kpproto.EventPromptAction_EVENT_PROMPT_ACTION_PLAYER_CONTINUE:      kpproto.EventMessageAction_EVENT_MESSAGE_ACTION_PLAYER_CONTINUE,
+	kpproto.EventPromptAction_EVENT_PROMPT_ACTION_PLAYER_SKIP:          kpproto.EventMessageAction_EVENT_MESSAGE_ACTION_PLAYER_SKIP,
+	kpproto.EventPromptAction_EVENT_PROMPT_ACTION_PLAYER_OUTPUT_OPTION: kpproto.EventMessageAction_EVENT_MESSAGE_ACTION_PLAYER_OUTPUT_OPTION,
+	kpproto.EventPromptAction_EVENT_PROMPT_ACTION_PLAYER_SET_QUALITY:   kpproto.EventMessageAction_EVENT_MESSAGE_ACTION_PLAYER_SET_QUALITY,
+	kpproto.EventPromptAction_EVENT_PROMPT_ACTION_RESOURCE_ADD:         kpproto.EventMessageAction_EVENT_MESSAGE_ACTION_RESOURCE_ADD,
+	kpproto.EventPromptAction_EVENT_PROMPT_ACTION_RESOURCE_LIST:        kpproto.EventMessageAction_EVENT_MESSAGE_ACTION_RESOURCE_LIST,
+	kpproto.EventPromptAction_EVENT_PROMPT_ACTION_RESOURCE_CURRENT:     kpproto.EventMessageAction_EVENT_MESSAGE_ACTION_RESOURCE_CURRENT,
+	kpproto.EventPromptAction_EVENT_PROMPT_ACTION_RESOURCE_SEEK:        kpproto.EventMessageAction_EVENT_MESSAGE_ACTION_RESOURCE_SEEK,
+	kpproto.EventPromptAction_EVENT_PROMPT_ACTION_OUTPUT_ADD:           kpproto.EventMessageAction_EVENT_MESSAGE_ACTION_OUTPUT_ADD,
+	kpproto.EventPromptAction_EVENT_PROMPT_ACTION_OUTPUT_REMOVE:        kpproto.EventMessageAction_EVENT_MESSAGE_ACTION_OUTPUT_REMOVE,
+	kpproto.EventPromptAction_EVENT_PROMPT_ACTION_PLUGIN_ADD:           kpproto.EventMessageAction_EVENT_MESSAGE_ACTION_PLUGIN_ADD,
+	kpproto.EventPromptAction_EVENT_PROMPT_ACTION_PLUGIN_REMOVE:        kpproto.EventMessageAction_EVENT_MESSAGE_ACTION_PLUGIN_REMOVE,
+	kpproto.EventPromptAction_EVENT_PROMPT_ACTION_PLUGIN_LIST:          kpproto.EventMessageAction_EVENT_MESSAGE_ACTION_PLUGIN_LIST,
+	kpproto.EventPromptAction_EVENT_PROMPT_ACTION_PLUGIN_UPDATE:        kpproto.EventMessageAction_EVENT_MESSAGE_ACTION_PLUGIN_UPDATE,
+}
+
+// Message core message with the correlation id of the prompt which caused it.
+// unsolicited messages (resource finish, output disconnect...) carry an empty correlation id
+type Message struct {
+	*kpproto.KPMessage
+	CorrelationId string
+}
+
+// NewCorrelationId return a new prompt correlation id
+func NewCorrelationId() string {
+	return uuid.New().String()
+}
+
+// correlationExpireGrace the time a released prompt waits for its late reply before it is evicted
+const correlationExpireGrace = time.Second * 10
+
+// correlationStaleAge the time an unreleased prompt waits for its reply before it is evicted. it is longer than
+// any waiter of modules waits, the waiter leaked or the reply is lost
+var correlationStaleAge = time.Minute * 5
+
+// pendingPrompt the prompt waiting for reply. expired is set when the waiter gave up or there is no waiter
+type pendingPrompt struct {
+	correlationId string
+	pushed        time.Time
+	expired       time.Time
+}
+
+// stale whether the reply of prompt is regarded as lost
+func (pp pendingPrompt) stale(now time.Time) bool {
+	if !pp.expired.IsZero() {
+		return now.Sub(pp.expired) > correlationExpireGrace
+	}
+	return now.Sub(pp.pushed) > correlationStaleAge
+}
+
+// correlator infer the prompt which a reply answers from order. the core does not echo any identity of prompts,
+// but answers the prompts of the same action in order. pending correlation ids are queued by reply action and
+// popped when the reply arrives. the prompt without waiter or released by its waiter keeps its place, so its
+// late reply is not taken as the reply of the next one. the head prompts are evicted when the next prompt of the
+// action is pushed after correlationExpireGrace since released, or after correlationStaleAge since pushed
+type correlator struct {
+	pending map[kpproto.EventMessageAction][]pendingPrompt
+	lock    sync.Mutex
+}
+
+func newCorrelator() *correlator {
+	return &correlator{
+		pending: make(map[kpproto.EventMessageAction][]pendingPrompt),
+	}
+}
+
+// push queue the correlation id of prompt. the empty correlation id means no waiter, the prompt is queued released.
+// the prompt must be sent before the next push of the same action, the push and the send are guarded by one lock
+// of engine
+func (c *correlator) push(action kpproto.EventPromptAction, correlationId string) {
+	replyAction, ok := promptReplyAction[action]
+	if !ok {
+		return
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	// evict the prompts whose reply never arrived
+	now := time.Now()
+	prompts := c.pending[replyAction]
+	for len(prompts) != 0 && prompts[0].stale(now) {
+		prompts = prompts[1:]
+	}
+
+	pending := pendingPrompt{correlationId: correlationId, pushed: now}
+	if correlationId == "" {
+		pending.expired = now
+	}
+	c.pending[replyAction] = append(prompts, pending)
+}
+
+// release mark the prompt released by its waiter
+func (c *correlator) release(correlationId string) {
+	if correlationId == "" {
+		return
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	for _, prompts := range c.pending {
+		for key := range prompts {
+			if prompts[key].correlationId == correlationId && prompts[key].expired.IsZero() {
+				prompts[key].expired = time.Now()
+				return
+			}
+		}
+	}
+}
+
+func (c *correlator) pop(action kpproto.EventMessageAction) string {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	prompts := c.pending[action]
+	if len(prompts) == 0 {
+		return ""
+	}
+	c.pending[action] = prompts[1:]
+
+	return prompts[0].correlationId
+}
+
+func (c *correlator) reset() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.pending = make(map[kpproto.EventMessageAction][]pendingPrompt)
+}
+
+func (c *correlator) message(action int, body string) *Message {
+	return &Message{
+		KPMessage: &kpproto.KPMessage{
+			Action: kpproto.EventMessageAction(action),
+			Body:   body,
+		},
+		CorrelationId: c.pop(kpproto.EventMessageAction(action)),
+	}
+}
@@ -0,0 +1,45 @@
+package core
+
+import (
+	kpproto "github.com/bytelang/kplayer/types/core/proto"
+	kpprompt "github.com/bytelang/kplayer/types/core/proto/prompt"
+	"github.com/golang/protobuf/proto"
+)
+
+type CoreKplayerOption string
+
+var (
+	ProtocolOption     CoreKplayerOption = "protocol"
+	VideoWidthOption   CoreKplayerOption = "video_width"
+	VideoHeightOption  CoreKplayerOption = "video_height"
+	VideoBitrateOption CoreKplayerOption = "video_bitrate"
+	VideoQualityOption CoreKplayerOption = "video_quality"
+	VideoFpsOption     CoreKplayerOption = "video_fps"
+	AudioSampleRate    CoreKplayerOption = "audio_sample_rate"
+	AudioChannelLayout CoreKplayerOption = "audio_channel_layout"
+	AudioChannels      CoreKplayerOption = "audio_channels"
+	VideoFillStrategy  CoreKplayerOption = "video_fill_strategy"
+)
+
+// Engine the prompt/message contract between modules and the kplayer core.
+// prompts are sent by SendPrompt, the core answers asynchronously through the message callback.
+// the prompt sent by SendCorrelatedPrompt carries a correlation id, which is set on the message answering it.
+// the core does not echo the id, the engine infers it from the order of prompts and replies of the same action.
+// the waiter giving up the reply releases the correlation id, the reply arrived later still carries it
+type Engine interface {
+	SetOptions(options map[CoreKplayerOption]interface{}) error
+	SetCallBackMessage(fn func(message *Message))
+	SetCallBackProgress(fn func(percent float64, bitRate int))
+	GetInformation() *kpproto.Information
+	SendPrompt(action kpproto.EventPromptAction, body proto.Message) error
+	SendCorrelatedPrompt(correlationId string, action kpproto.EventPromptAction, body proto.Message) error
+	ReleaseCorrelation(correlationId string)
+	Run() int
+	SetCacheOn(c bool)
+	SetCacheUncheckSource()
+	SetSkipInvalidResource(s bool)
+	SetLogLevel(path string, level int)
+	Initialization()
+	AddOutput(body *kpprompt.EventPromptOutputAdd) error
+	AddPlugin(body *kpprompt.EventPromptPluginAdd) error
+}
@@ -0,0 +1,608 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	kpproto "github.com/bytelang/kplayer/types/core/proto"
+	kpprompt "github.com/bytelang/kplayer/types/core/proto/prompt"
+	"github.com/golang/protobuf/proto"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	FakeDefaultResourceDuration = time.Second * 60
+	FakeMajorVersion            = "fake"
+	FakePluginVersion           = "1.0.0"
+)
+
+type fakeMessage struct {
+	action int
+	body   string
+}
+
+type fakeResource struct {
+	resource *kpproto.PromptResource
+	position time.Duration
+	end      time.Duration
+}
+
+// FakeEngine pure go engine following the libkplayer prompt/message protocol.
+// resources play on a simulated clock which only moves on Advance,
+// messages are delivered in order from a dispatcher goroutine while Run is blocking.
+type FakeEngine struct {
+	mu   sync.Mutex
+	cond *sync.Cond
+
+	// options
+	options             map[CoreKplayerOption]interface{}
+	cacheOn             bool
+	cacheUncheckSource  bool
+	skipInvalidResource bool
+	quality             int64
+
+	// simulated media
+	defaultDuration time.Duration
+	durations       map[string]time.Duration
+	invalidPaths    map[string]string
+	invalidOutputs  map[string]string
+	promptErrors    map[kpproto.EventPromptAction]string
+
+	// player state
+	running  bool
+	paused   bool
+	clock    time.Duration
+	current  *fakeResource
+	queue    []*kpproto.PromptResource
+	outputs  []*kpprompt.PromptOutput
+	plugins  []*kpprompt.PromptPlugin
+	stopChan chan int
+
+	// message dispatcher
+	pending []fakeMessage
+	closed  bool
+
+	// event message receiver
+	callbackMessageFn  func(message *Message)
+	callbackProgressFn func(percent float64, bitRate int)
+
+	// pending prompt correlation ids. the fake answers the prompts in order as the core does
+	correlator *correlator
+}
+
+var _ Engine = &FakeEngine{}
+
+// NewFakeEngine return fake engine. every resource lasts FakeDefaultResourceDuration unless SetResourceDuration
+func NewFakeEngine() *FakeEngine {
+	fe := &FakeEngine{
+		options:            make(map[CoreKplayerOption]interface{}),
+		defaultDuration:    FakeDefaultResourceDuration,
+		durations:          make(map[string]time.Duration),
+		invalidPaths:       make(map[string]string),
+		invalidOutputs:     make(map[string]string),
+		promptErrors:       make(map[kpproto.EventPromptAction]string),
+		stopChan:           make(chan int, 1),
+		correlator:         newCorrelator(),
+		callbackMessageFn:  func(message *Message) {},
+		callbackProgressFn: func(percent float64, bitRate int) {},
+	}
+	fe.cond = sync.NewCond(&fe.mu)
+
+	return fe
+}
+
+// SetResourceDuration set the simulated media duration of the resource path
+func (fe *FakeEngine) SetResourceDuration(path string, duration time.Duration) {
+	fe.mu.Lock()
+	defer fe.mu.Unlock()
+
+	fe.durations[path] = duration
+}
+
+// SetResourceError the resource path will be finished with error once it starts playing
+func (fe *FakeEngine) SetResourceError(path string, err string) {
+	fe.mu.Lock()
+	defer fe.mu.Unlock()
+
+	fe.invalidPaths[path] = err
+}
+
+// SetOutputError the output path will be answered with error once it is added
+func (fe *FakeEngine) SetOutputError(path string, err string) {
+	fe.mu.Lock()
+	defer fe.mu.Unlock()
+
+	fe.invalidOutputs[path] = err
+}
+
+// SetPromptError the prompt of action will be answered with error until it is cleared by the empty error
+func (fe *FakeEngine) SetPromptError(action kpproto.EventPromptAction, err string) {
+	fe.mu.Lock()
+	defer fe.mu.Unlock()
+
+	if err == "" {
+		delete(fe.promptErrors, action)
+		return
+	}
+	fe.promptErrors[action] = err
+}
+
+// Advance move the simulated clock forward. playing resources finish when they reach the end
+func (fe *FakeEngine) Advance(d time.Duration) {
+	fe.mu.Lock()
+	fe.clock = fe.clock + d
+	for d > 0 && fe.current != nil && !fe.paused {
+		remain := fe.current.end - fe.current.position
+		if d < remain {
+			fe.current.position = fe.current.position + d
+			break
+		}
+
+		d = d - remain
+		fe.current.position = fe.current.end
+		fe.finishCurrent("")
+	}
+
+	percent, progressFn := fe.progress()
+	fe.mu.Unlock()
+
+	progressFn(percent, 0)
+}
+
+// Terminate make Run return with the result code, as the core does on shutdown or failure
+func (fe *FakeEngine) Terminate(code int) {
+	fe.mu.Lock()
+	defer fe.mu.Unlock()
+
+	fe.terminate(code)
+}
+
+// Clock return the elapsed simulated time
+func (fe *FakeEngine) Clock() time.Duration {
+	fe.mu.Lock()
+	defer fe.mu.Unlock()
+
+	return fe.clock
+}
+
+func (fe *FakeEngine) SetOptions(options map[CoreKplayerOption]interface{}) error {
+	fe.mu.Lock()
+	defer fe.mu.Unlock()
+
+	for option, value := range options {
+		fe.options[option] = value
+	}
+	return nil
+}
+
+func (fe *FakeEngine) SetCallBackMessage(fn func(message *Message)) {
+	fe.mu.Lock()
+	defer fe.mu.Unlock()
+
+	fe.callbackMessageFn = fn
+}
+
+func (fe *FakeEngine) SetCallBackProgress(fn func(percent float64, bitRate int)) {
+	fe.mu.Lock()
+	defer fe.mu.Unlock()
+
+	fe.callbackProgressFn = fn
+}
+
+func (fe *FakeEngine) GetInformation() *kpproto.Information {
+	return &kpproto.Information{
+		MajorVersion:  FakeMajorVersion,
+		PluginVersion: FakePluginVersion,
+		BuildType:     "fake",
+	}
+}
+
+func (fe *FakeEngine) SetCacheOn(c bool) {
+	fe.cacheOn = c
+}
+
+func (fe *FakeEngine) SetCacheUncheckSource() {
+	fe.cacheUncheckSource = true
+}
+
+func (fe *FakeEngine) SetSkipInvalidResource(s bool) {
+	fe.skipInvalidResource = s
+}
+
+func (fe *FakeEngine) SetLogLevel(path string, level int) {
+}
+
+func (fe *FakeEngine) Initialization() {
+	fe.mu.Lock()
+	defer fe.mu.Unlock()
+
+	fe.paused = false
+	fe.current = nil
+	fe.queue = nil
+	fe.outputs = nil
+	fe.plugins = nil
+	fe.pending = nil
+	fe.correlator.reset()
+}
+
+func (fe *FakeEngine) Run() int {
+	fe.mu.Lock()
+	if fe.running {
+		fe.mu.Unlock()
+		return -1
+	}
+	fe.running = true
+	fe.closed = false
+
+	fe.emit(kpproto.EventMessageAction_EVENT_MESSAGE_ACTION_PLAYER_STARTED, nil)
+	if len(fe.queue) == 0 {
+		fe.emit(kpproto.EventMessageAction_EVENT_MESSAGE_ACTION_RESOURCE_EMPTY, nil)
+	} else {
+		fe.startNext()
+	}
+	fe.mu.Unlock()
+
+	dispatchDone := make(chan bool)
+	go func() {
+		defer close(dispatchDone)
+		fe.dispatch()
+	}()
+
+	resultCode := <-fe.stopChan
+
+	// drain delivered messages before return
+	fe.mu.Lock()
+	fe.running = false
+	fe.closed = true
+	fe.cond.Broadcast()
+	fe.mu.Unlock()
+	<-dispatchDone
+
+	return resultCode
+}
+
+func (fe *FakeEngine) SendPrompt(action kpproto.EventPromptAction, body proto.Message) error {
+	// nobody waits for the reply, the message carries no correlation id
+	return fe.SendCorrelatedPrompt("", action, body)
+}
+
+func (fe *FakeEngine) SendCorrelatedPrompt(correlationId string, action kpproto.EventPromptAction, body proto.Message) error {
+	fe.mu.Lock()
+	defer fe.mu.Unlock()
+
+	fe.correlator.push(action, correlationId)
+
+	if err, ok := fe.promptErrors[action]; ok {
+		fe.emitError(promptReplyAction[action], err)
+		return nil
+	}
+
+	switch action {
+	case kpproto.EventPromptAction_EVENT_PROMPT_ACTION_PLAYER_STOP:
+		fe.emit(kpproto.EventMessageAction_EVENT_MESSAGE_ACTION_PLAYER_STOP, nil)
+		fe.terminate(0)
+	case kpproto.EventPromptAction_EVENT_PROMPT_ACTION_PLAYER_PAUSE:
+		if fe.paused {
+			fe.emitError(kpproto.EventMessageAction_EVENT_MESSAGE_ACTION_PLAYER_PAUSE, "player has been paused")
+			break
+		}
+		fe.paused = true
+		fe.emit(kpproto.EventMessageAction_EVENT_MESSAGE_ACTION_PLAYER_PAUSE, nil)
+	case kpproto.EventPromptAction_EVENT_PROMPT_ACTION_PLAYER_CONTINUE:
+		if !fe.paused {
+			fe.emitError(kpproto.EventMessageAction_EVENT_MESSAGE_ACTION_PLAYER_CONTINUE, "player is not paused")
+			break
+		}
+		fe.paused = false
+		fe.emit(kpproto.EventMessageAction_EVENT_MESSAGE_ACTION_PLAYER_CONTINUE, nil)
+	case kpproto.EventPromptAction_EVENT_PROMPT_ACTION_PLAYER_SKIP:
+		if fe.current == nil {
+			fe.emitError(kpproto.EventMessageAction_EVENT_MESSAGE_ACTION_PLAYER_SKIP, "no resource is playing")
+			break
+		}
+		fe.emit(kpproto.EventMessageAction_EVENT_MESSAGE_ACTION_PLAYER_SKIP, nil)
+		fe.finishCurrent("")
+	case kpproto.EventPromptAction_EVENT_PROMPT_ACTION_PLAYER_OUTPUT_OPTION:
+		fe.emit(kpproto.EventMessageAction_EVENT_MESSAGE_ACTION_PLAYER_OUTPUT_OPTION, map[string]interface{}{
+			"video_scale_width":    fe.options[VideoWidthOption],
+			"video_scale_height":   fe.options[VideoHeightOption],
+			"video_fps":            fe.options[VideoFpsOption],
+			"audio_channel_layout": fe.options[AudioChannelLayout],
+			"audio_sample_rate":    fe.options[AudioSampleRate],
+			"video_bitrate":        fe.options[VideoBitrateOption],
+			"video_quality":        fe.quality,
+		})
+	case kpproto.EventPromptAction_EVENT_PROMPT_ACTION_PLAYER_SET_QUALITY:
+		fe.quality = body.(*kpprompt.EventPromptPlayerSetQuality).Quality
+		fe.emit(kpproto.EventMessageAction_EVENT_MESSAGE_ACTION_PLAYER_SET_QUALITY, nil)
+	case kpproto.EventPromptAction_EVENT_PROMPT_ACTION_RESOURCE_ADD:
+		res := body.(*kpprompt.EventPromptResourceAdd).Resource
+		fe.queue = append(fe.queue, res)
+		fe.emit(kpproto.EventMessageAction_EVENT_MESSAGE_ACTION_RESOURCE_ADD, map[string]interface{}{"resource": fe.rawResource(res)})
+		if fe.running && fe.current == nil {
+			fe.startNext()
+		}
+	case kpproto.EventPromptAction_EVENT_PROMPT_ACTION_RESOURCE_LIST:
+		var resources []map[string]interface{}
+		for _, item := range fe.queue {
+			resources = append(resources, fe.rawResource(item))
+		}
+		fe.emit(kpproto.EventMessageAction_EVENT_MESSAGE_ACTION_RESOURCE_LIST, map[string]interface{}{"resources": resources})
+	case kpproto.EventPromptAction_EVENT_PROMPT_ACTION_RESOURCE_CURRENT:
+		if fe.current == nil {
+			fe.emitError(kpproto.EventMessageAction_EVENT_MESSAGE_ACTION_RESOURCE_CURRENT, "no resource is playing")
+			break
+		}
+		fe.emit(kpproto.EventMessageAction_EVENT_MESSAGE_ACTION_RESOURCE_CURRENT, map[string]interface{}{
+			"resource":  fe.rawResource(fe.current.resource),
+			"duration":  uint64(fe.current.end.Seconds()),
+			"seek":      int64(fe.current.position.Seconds()),
+			"hit_cache": false,
+		})
+	case kpproto.EventPromptAction_EVENT_PROMPT_ACTION_RESOURCE_SEEK:
+		res := body.(*kpprompt.EventPromptResourceSeek).Resource
+		fe.emit(kpproto.EventMessageAction_EVENT_MESSAGE_ACTION_RESOURCE_SEEK, map[string]interface{}{"resource": fe.rawResource(res)})
+		if fe.current != nil && fe.current.resource.Unique == res.Unique {
+			fe.current.position = time.Duration(res.Seek) * time.Second
+			break
+		}
+		fe.start(res)
+	case kpproto.EventPromptAction_EVENT_PROMPT_ACTION_OUTPUT_ADD:
+		fe.addOutput(body.(*kpprompt.EventPromptOutputAdd).Output)
+	case kpproto.EventPromptAction_EVENT_PROMPT_ACTION_OUTPUT_REMOVE:
+		unique := body.(*kpprompt.EventPromptOutputRemove).Unique
+		for key, item := range fe.outputs {
+			if item.Unique == unique {
+				fe.outputs = append(fe.outputs[:key], fe.outputs[key+1:]...)
+				fe.emit(kpproto.EventMessageAction_EVENT_MESSAGE_ACTION_OUTPUT_REMOVE, map[string]interface{}{"output": fe.rawOutput(item)})
+				return nil
+			}
+		}
+		fe.emit(kpproto.EventMessageAction_EVENT_MESSAGE_ACTION_OUTPUT_REMOVE, map[string]interface{}{
+			"output": map[string]string{"unique": unique},
+			"error":  "output not found",
+		})
+	case kpproto.EventPromptAction_EVENT_PROMPT_ACTION_PLUGIN_ADD:
+		fe.addPlugin(body.(*kpprompt.EventPromptPluginAdd).Plugin)
+	case kpproto.EventPromptAction_EVENT_PROMPT_ACTION_PLUGIN_REMOVE:
+		unique := body.(*kpprompt.EventPromptPluginRemove).Unique
+		for key, item := range fe.plugins {
+			if item.Unique == unique {
+				fe.plugins = append(fe.plugins[:key], fe.plugins[key+1:]...)
+				fe.emit(kpproto.EventMessageAction_EVENT_MESSAGE_ACTION_PLUGIN_REMOVE, map[string]interface{}{"plugin": fe.rawPlugin(item)})
+				return nil
+			}
+		}
+		fe.emit(kpproto.EventMessageAction_EVENT_MESSAGE_ACTION_PLUGIN_REMOVE, map[string]interface{}{
+			"plugin": map[string]string{"unique": unique},
+			"error":  "plugin not found",
+		})
+	case kpproto.EventPromptAction_EVENT_PROMPT_ACTION_PLUGIN_UPDATE:
+		update := body.(*kpprompt.EventPromptPluginUpdate)
+		for _, item := range fe.plugins {
+			if item.Unique == update.Unique {
+				for k, v := range update.Params {
+					item.Params[k] = v
+				}
+				fe.emit(kpproto.EventMessageAction_EVENT_MESSAGE_ACTION_PLUGIN_UPDATE, map[string]interface{}{"plugin": fe.rawPlugin(item)})
+				return nil
+			}
+		}
+		fe.emit(kpproto.EventMessageAction_EVENT_MESSAGE_ACTION_PLUGIN_UPDATE, map[string]interface{}{
+			"plugin": map[string]string{"unique": update.Unique},
+			"error":  "plugin not found",
+		})
+	case kpproto.EventPromptAction_EVENT_PROMPT_ACTION_PLUGIN_LIST:
+		var plugins []map[string]interface{}
+		for _, item := range fe.plugins {
+			plugins = append(plugins, fe.rawPlugin(item))
+		}
+		fe.emit(kpproto.EventMessageAction_EVENT_MESSAGE_ACTION_PLUGIN_LIST, map[string]interface{}{"plugins": plugins})
+	default:
+		return fmt.Errorf("fake engine not support prompt action: %s", action)
+	}
+
+	return nil
+}
+
+func (fe *FakeEngine) ReleaseCorrelation(correlationId string) {
+	fe.correlator.release(correlationId)
+}
+
+func (fe *FakeEngine) AddOutput(body *kpprompt.EventPromptOutputAdd) error {
+	fe.mu.Lock()
+	defer fe.mu.Unlock()
+
+	// the core answers added output without correlation
+	fe.correlator.push(kpproto.EventPromptAction_EVENT_PROMPT_ACTION_OUTPUT_ADD, "")
+
+	return fe.addOutput(body.Output)
+}
+
+func (fe *FakeEngine) AddPlugin(body *kpprompt.EventPromptPluginAdd) error {
+	fe.mu.Lock()
+	defer fe.mu.Unlock()
+
+	// the core answers added plugin without correlation
+	fe.correlator.push(kpproto.EventPromptAction_EVENT_PROMPT_ACTION_PLUGIN_ADD, "")
+
+	return fe.addPlugin(body.Plugin)
+}
+
+func (fe *FakeEngine) addOutput(output *kpprompt.PromptOutput) error {
+	for _, item := range fe.outputs {
+		if item.Unique == output.Unique {
+			fe.emit(kpproto.EventMessageAction_EVENT_MESSAGE_ACTION_OUTPUT_ADD, map[string]interface{}{
+				"output": fe.rawOutput(output),
+				"error":  "output unique has existed",
+			})
+			return fmt.Errorf("add output failed. result code: %d", -1)
+		}
+	}
+
+	if err, ok := fe.invalidOutputs[output.Path]; ok {
+		fe.emit(kpproto.EventMessageAction_EVENT_MESSAGE_ACTION_OUTPUT_ADD, map[string]interface{}{
+			"output": fe.rawOutput(output),
+			"error":  err,
+		})
+		return nil
+	}
+
+	fe.outputs = append(fe.outputs, output)
+	fe.emit(kpproto.EventMessageAction_EVENT_MESSAGE_ACTION_OUTPUT_ADD, map[string]interface{}{"output": fe.rawOutput(output)})
+	return nil
+}
+
+func (fe *FakeEngine) addPlugin(plugin *kpprompt.PromptPlugin) error {
+	for _, item := range fe.plugins {
+		if item.Unique == plugin.Unique {
+			fe.emit(kpproto.EventMessageAction_EVENT_MESSAGE_ACTION_PLUGIN_ADD, map[string]interface{}{
+				"plugin": fe.rawPlugin(plugin),
+				"error":  "plugin unique has existed",
+			})
+			return fmt.Errorf("add plugin failed. result code: %d", -1)
+		}
+	}
+
+	if plugin.Params == nil {
+		plugin.Params = make(map[string]string)
+	}
+	fe.plugins = append(fe.plugins, plugin)
+	fe.emit(kpproto.EventMessageAction_EVENT_MESSAGE_ACTION_PLUGIN_ADD, map[string]interface{}{"plugin": fe.rawPlugin(plugin)})
+	return nil
+}
+
+// startNext play the first queued resource. must hold lock
+func (fe *FakeEngine) startNext() {
+	if len(fe.queue) == 0 {
+		fe.emit(kpproto.EventMessageAction_EVENT_MESSAGE_ACTION_RESOURCE_EMPTY, nil)
+		return
+	}
+
+	res := fe.queue[0]
+	fe.queue = fe.queue[1:]
+	fe.start(res)
+}
+
+// start replace current resource. must hold lock
+func (fe *FakeEngine) start(res *kpproto.PromptResource) {
+	duration, ok := fe.durations[res.Path]
+	if !ok {
+		duration = fe.defaultDuration
+	}
+	end := duration
+	if res.End > 0 && time.Duration(res.End)*time.Second < end {
+		end = time.Duration(res.End) * time.Second
+	}
+
+	fe.current = &fakeResource{
+		resource: res,
+		position: time.Duration(res.Seek) * time.Second,
+		end:      end,
+	}
+	fe.emit(kpproto.EventMessageAction_EVENT_MESSAGE_ACTION_RESOURCE_START, map[string]interface{}{"resource": fe.rawResource(res)})
+
+	if err, ok := fe.invalidPaths[res.Path]; ok {
+		fe.finishCurrent(err)
+		return
+	}
+	fe.emit(kpproto.EventMessageAction_EVENT_MESSAGE_ACTION_RESOURCE_CHECKED, map[string]interface{}{
+		"resource":        fe.rawResource(res),
+		"input_attribute": map[string]interface{}{"duration": uint64(duration.Seconds())},
+		"hit_cache":       false,
+	})
+}
+
+// finishCurrent must hold lock
+func (fe *FakeEngine) finishCurrent(err string) {
+	body := map[string]interface{}{"resource": fe.rawResource(fe.current.resource)}
+	if len(err) != 0 {
+		body["error"] = err
+	}
+	fe.current = nil
+	fe.emit(kpproto.EventMessageAction_EVENT_MESSAGE_ACTION_RESOURCE_FINISH, body)
+	fe.startNext()
+}
+
+// terminate must hold lock. terminate before Run makes the next Run return immediately
+func (fe *FakeEngine) terminate(code int) {
+	select {
+	case fe.stopChan <- code:
+	default:
+	}
+}
+
+// progress must hold lock
+func (fe *FakeEngine) progress() (float64, func(percent float64, bitRate int)) {
+	if fe.current == nil || fe.current.end == 0 {
+		return 0, fe.callbackProgressFn
+	}
+
+	return float64(fe.current.position) / float64(fe.current.end) * 100, fe.callbackProgressFn
+}
+
+func (fe *FakeEngine) emitError(action kpproto.EventMessageAction, err string) {
+	fe.emit(action, map[string]interface{}{"error": err})
+}
+
+// emit queue message for dispatcher. must hold lock
+func (fe *FakeEngine) emit(action kpproto.EventMessageAction, body map[string]interface{}) {
+	if body == nil {
+		body = map[string]interface{}{}
+	}
+
+	data, err := json.Marshal(body)
+	if err != nil {
+		log.WithFields(log.Fields{"error": err, "action": action}).Fatal("fake engine marshal message failed")
+	}
+
+	fe.pending = append(fe.pending, fakeMessage{action: int(action), body: string(data)})
+	fe.cond.Signal()
+}
+
+func (fe *FakeEngine) dispatch() {
+	for {
+		fe.mu.Lock()
+		for len(fe.pending) == 0 && !fe.closed {
+			fe.cond.Wait()
+		}
+		if len(fe.pending) == 0 {
+			fe.mu.Unlock()
+			return
+		}
+
+		message := fe.pending[0]
+		fe.pending = fe.pending[1:]
+		callbackMessageFn := fe.callbackMessageFn
+		fe.mu.Unlock()
+
+		callbackMessageFn(fe.correlator.message(message.action, message.body))
+	}
+}
+
+func (fe *FakeEngine) rawResource(res *kpproto.PromptResource) map[string]interface{} {
+	return map[string]interface{}{
+		"path":   res.Path,
+		"unique": res.Unique,
+		"seek":   res.Seek,
+		"end":    res.End,
+	}
+}
+
+func (fe *FakeEngine) rawOutput(output *kpprompt.PromptOutput) map[string]interface{} {
+	return map[string]interface{}{
+		"path":   output.Path,
+		"unique": output.Unique,
+	}
+}
+
+// rawPlugin plugin content is not part of the message
+func (fe *FakeEngine) rawPlugin(plugin *kpprompt.PromptPlugin) map[string]interface{} {
+	return map[string]interface{}{
+		"path":   plugin.Path,
+		"unique": plugin.Unique,
+		"params": plugin.Params,
+	}
+}
@@ -0,0 +1,275 @@
+package core
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	kpproto "github.com/bytelang/kplayer/types/core/proto"
+	kpprompt "github.com/bytelang/kplayer/types/core/proto/prompt"
+	"github.com/tidwall/gjson"
+)
+
+func runFakeEngine(t *testing.T, fe *FakeEngine) chan Message {
+	messages := make(chan Message, 100)
+	fe.SetCallBackMessage(func(message *Message) {
+		messages <- *message
+	})
+
+	resultChan := make(chan int)
+	go func() {
+		resultChan <- fe.Run()
+	}()
+	t.Cleanup(func() {
+		_ = fe.SendPrompt(kpproto.EventPromptAction_EVENT_PROMPT_ACTION_PLAYER_STOP, &kpprompt.EventPromptPlayerStop{})
+		<-resultChan
+	})
+
+	return messages
+}
+
+func waitMessage(t *testing.T, messages chan Message, action kpproto.EventMessageAction) Message {
+	timeout := time.After(time.Second * 5)
+	for {
+		select {
+		case message := <-messages:
+			if message.Action == action {
+				return message
+			}
+		case <-timeout:
+			t.Fatalf("wait message timeout. action: %s", action)
+		}
+	}
+}
+
+func TestFakeEngineResourcePlay(t *testing.T) {
+	fe := NewFakeEngine()
+	fe.SetResourceDuration("short.flv", time.Second*10)
+	messages := runFakeEngine(t, fe)
+
+	waitMessage(t, messages, kpproto.EventMessageAction_EVENT_MESSAGE_ACTION_PLAYER_STARTED)
+	waitMessage(t, messages, kpproto.EventMessageAction_EVENT_MESSAGE_ACTION_RESOURCE_EMPTY)
+
+	if err := fe.SendPrompt(kpproto.EventPromptAction_EVENT_PROMPT_ACTION_RESOURCE_ADD, &kpprompt.EventPromptResourceAdd{Resource: &kpproto.PromptResource{
+		Path:   "short.flv",
+		Unique: "test",
+	}}); err != nil {
+		t.Fatal(err)
+	}
+	start := waitMessage(t, messages, kpproto.EventMessageAction_EVENT_MESSAGE_ACTION_RESOURCE_START)
+	if unique := gjson.Get(start.Body, "resource.unique").String(); unique != "test" {
+		t.Fatalf("unexpected resource unique: %s", unique)
+	}
+
+	// the clock has not reached the end of resource
+	fe.Advance(time.Second * 9)
+	if err := fe.SendPrompt(kpproto.EventPromptAction_EVENT_PROMPT_ACTION_RESOURCE_CURRENT, &kpprompt.EventPromptResourceCurrent{}); err != nil {
+		t.Fatal(err)
+	}
+	current := waitMessage(t, messages, kpproto.EventMessageAction_EVENT_MESSAGE_ACTION_RESOURCE_CURRENT)
+	if seek := gjson.Get(current.Body, "seek").Int(); seek != 9 {
+		t.Fatalf("unexpected resource seek: %d", seek)
+	}
+
+	fe.Advance(time.Second)
+	finish := waitMessage(t, messages, kpproto.EventMessageAction_EVENT_MESSAGE_ACTION_RESOURCE_FINISH)
+	if unique := gjson.Get(finish.Body, "resource.unique").String(); unique != "test" {
+		t.Fatalf("unexpected resource unique: %s", unique)
+	}
+	waitMessage(t, messages, kpproto.EventMessageAction_EVENT_MESSAGE_ACTION_RESOURCE_EMPTY)
+}
+
+func TestFakeEngineResourceError(t *testing.T) {
+	fe := NewFakeEngine()
+	fe.SetResourceError("broken.flv", "invalid data found when processing input")
+	messages := runFakeEngine(t, fe)
+
+	if err := fe.SendPrompt(kpproto.EventPromptAction_EVENT_PROMPT_ACTION_RESOURCE_ADD, &kpprompt.EventPromptResourceAdd{Resource: &kpproto.PromptResource{
+		Path:   "broken.flv",
+		Unique: "broken",
+	}}); err != nil {
+		t.Fatal(err)
+	}
+
+	finish := waitMessage(t, messages, kpproto.EventMessageAction_EVENT_MESSAGE_ACTION_RESOURCE_FINISH)
+	if gjson.Get(finish.Body, "error").String() == "" {
+		t.Fatal("resource finish message should carry error")
+	}
+}
+
+func TestFakeEngineOutputAndPause(t *testing.T) {
+	fe := NewFakeEngine()
+	if err := fe.AddOutput(&kpprompt.EventPromptOutputAdd{Output: &kpprompt.PromptOutput{
+		Path:   "test.flv",
+		Unique: "test",
+	}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := fe.AddOutput(&kpprompt.EventPromptOutputAdd{Output: &kpprompt.PromptOutput{
+		Path:   "test.flv",
+		Unique: "test",
+	}}); err == nil {
+		t.Fatal("add duplicate output should be failed")
+	}
+	messages := runFakeEngine(t, fe)
+
+	add := waitMessage(t, messages, kpproto.EventMessageAction_EVENT_MESSAGE_ACTION_OUTPUT_ADD)
+	if gjson.Get(add.Body, "error").String() != "" {
+		t.Fatal(add.Body)
+	}
+
+	if err := fe.SendPrompt(kpproto.EventPromptAction_EVENT_PROMPT_ACTION_PLAYER_PAUSE, &kpprompt.EventPromptPlayerPause{}); err != nil {
+		t.Fatal(err)
+	}
+	waitMessage(t, messages, kpproto.EventMessageAction_EVENT_MESSAGE_ACTION_PLAYER_PAUSE)
+
+	if err := fe.SendPrompt(kpproto.EventPromptAction_EVENT_PROMPT_ACTION_PLAYER_PAUSE, &kpprompt.EventPromptPlayerPause{}); err != nil {
+		t.Fatal(err)
+	}
+	pause := waitMessage(t, messages, kpproto.EventMessageAction_EVENT_MESSAGE_ACTION_PLAYER_PAUSE)
+	if gjson.Get(pause.Body, "error").String() == "" {
+		t.Fatal("pause a paused player should be failed")
+	}
+}
+
+func TestFakeEngineTerminate(t *testing.T) {
+	fe := NewFakeEngine()
+
+	resultChan := make(chan int)
+	go func() {
+		resultChan <- fe.Run()
+	}()
+
+	// wait for running
+	for {
+		fe.mu.Lock()
+		running := fe.running
+		fe.mu.Unlock()
+		if running {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	fe.Terminate(-1)
+	if result := <-resultChan; result != -1 {
+		t.Fatalf("unexpected result code: %d", result)
+	}
+}
+
+func TestFakeEngineCorrelation(t *testing.T) {
+	fe := NewFakeEngine()
+	messages := runFakeEngine(t, fe)
+
+	if err := fe.SendCorrelatedPrompt("pause", kpproto.EventPromptAction_EVENT_PROMPT_ACTION_PLAYER_PAUSE, &kpprompt.EventPromptPlayerPause{}); err != nil {
+		t.Fatal(err)
+	}
+	pause := waitMessage(t, messages, kpproto.EventMessageAction_EVENT_MESSAGE_ACTION_PLAYER_PAUSE)
+	if pause.CorrelationId != "pause" {
+		t.Fatalf("unexpected correlation id: %s", pause.CorrelationId)
+	}
+
+	// unsolicited message carry no correlation id
+	fe.Advance(time.Second)
+	if err := fe.SendPrompt(kpproto.EventPromptAction_EVENT_PROMPT_ACTION_RESOURCE_ADD, &kpprompt.EventPromptResourceAdd{Resource: &kpproto.PromptResource{
+		Path:   "short.flv",
+		Unique: "test",
+	}}); err != nil {
+		t.Fatal(err)
+	}
+	start := waitMessage(t, messages, kpproto.EventMessageAction_EVENT_MESSAGE_ACTION_RESOURCE_START)
+	if start.CorrelationId != "" {
+		t.Fatalf("unexpected correlation id: %s", start.CorrelationId)
+	}
+}
+
+func TestFakeEngineConcurrentCorrelation(t *testing.T) {
+	fe := NewFakeEngine()
+	messages := runFakeEngine(t, fe)
+
+	// the replies of same action sent concurrently are correlated to their own prompts
+	const count = 20
+	var wg sync.WaitGroup
+	for i := 0; i < count; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if err := fe.SendCorrelatedPrompt(fmt.Sprintf("remove-%d", i), kpproto.EventPromptAction_EVENT_PROMPT_ACTION_OUTPUT_REMOVE,
+				&kpprompt.EventPromptOutputRemove{Unique: fmt.Sprintf("out-%d", i)}); err != nil {
+				t.Error(err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	for i := 0; i < count; i++ {
+		remove := waitMessage(t, messages, kpproto.EventMessageAction_EVENT_MESSAGE_ACTION_OUTPUT_REMOVE)
+		unique := gjson.Get(remove.Body, "output.unique").String()
+		if "remove-"+unique[len("out-"):] != remove.CorrelationId {
+			t.Fatalf("reply of %s correlated to %s", unique, remove.CorrelationId)
+		}
+	}
+}
+
+func TestCorrelatorRelease(t *testing.T) {
+	c := newCorrelator()
+	action := kpproto.EventPromptAction_EVENT_PROMPT_ACTION_PLAYER_PAUSE
+	replyAction := kpproto.EventMessageAction_EVENT_MESSAGE_ACTION_PLAYER_PAUSE
+
+	// the late reply of released prompt is not taken as the reply of the next one
+	c.push(action, "first")
+	c.release("first")
+	c.push(action, "second")
+	if id := c.pop(replyAction); id != "first" {
+		t.Fatalf("unexpected correlation id: %s", id)
+	}
+	if id := c.pop(replyAction); id != "second" {
+		t.Fatalf("unexpected correlation id: %s", id)
+	}
+
+	// the released prompt whose reply never arrived is evicted after the grace
+	c.push(action, "lost")
+	c.release("lost")
+	c.pending[replyAction][0].expired = time.Now().Add(-correlationExpireGrace - time.Second)
+	c.push(action, "next")
+	if id := c.pop(replyAction); id != "next" {
+		t.Fatalf("unexpected correlation id: %s", id)
+	}
+	if id := c.pop(replyAction); id != "" {
+		t.Fatalf("unexpected correlation id: %s", id)
+	}
+}
+
+func TestCorrelatorStale(t *testing.T) {
+	c := newCorrelator()
+	action := kpproto.EventPromptAction_EVENT_PROMPT_ACTION_PLAYER_PAUSE
+	replyAction := kpproto.EventMessageAction_EVENT_MESSAGE_ACTION_PLAYER_PAUSE
+
+	// the prompt without waiter keeps its place, and is evicted after the grace
+	c.push(action, "")
+	c.push(action, "waited")
+	if id := c.pop(replyAction); id != "" {
+		t.Fatalf("unexpected correlation id: %s", id)
+	}
+	if id := c.pop(replyAction); id != "waited" {
+		t.Fatalf("unexpected correlation id: %s", id)
+	}
+	c.push(action, "")
+	c.pending[replyAction][0].expired = time.Now().Add(-correlationExpireGrace - time.Second)
+	c.push(action, "next")
+	if id := c.pop(replyAction); id != "next" {
+		t.Fatalf("unexpected correlation id: %s", id)
+	}
+
+	// the unreleased prompt whose reply never arrived is evicted by age
+	c.push(action, "leaked")
+	c.pending[replyAction][0].pushed = time.Now().Add(-correlationStaleAge - time.Second)
+	c.push(action, "next")
+	if id := c.pop(replyAction); id != "next" {
+		t.Fatalf("unexpected correlation id: %s", id)
+	}
+	if id := c.pop(replyAction); id != "" {
+		t.Fatalf("unexpected correlation id: %s", id)
+	}
+}
@@ -1,3 +1,6 @@
+//go:build cgo
+// +build cgo
+
 package core
 
 // #cgo LDFLAGS: -lkplayer -lkpcodec -lkputil -lkpadapter -lkpplugin
@@ -12,6 +15,7 @@ import (
 	kpprompt "github.com/bytelang/kplayer/types/core/proto/prompt"
 	"github.com/golang/protobuf/jsonpb"
 	"strings"
+	"sync"
 	"unsafe"
 
 	kpproto "github.com/bytelang/kplayer/types/core/proto"
@@ -24,7 +28,7 @@ import (
 func goCallBackMessage(action C.int, msgRaw *C.char) {
 	msg := C.GoString(msgRaw)
 	ac := int(action)
-	libKplayerInstance.callbackMessageFn(ac, msg)
+	libKplayerInstance.callbackMessageFn(libKplayerInstance.correlator.message(ac, msg))
 }
 
 //export goCallBackProgress
@@ -33,21 +37,6 @@ func goCallBackProgress(percent C.double, bitRate C.int) {
 	libKplayerInstance.callbackProgressFn(float64(percent), int(bitRate))
 }
 
-type CoreKplayerOption string
-
-var (
-	ProtocolOption     CoreKplayerOption = "protocol"
-	VideoWidthOption   CoreKplayerOption = "video_width"
-	VideoHeightOption  CoreKplayerOption = "video_height"
-	VideoBitrateOption CoreKplayerOption = "video_bitrate"
-	VideoQualityOption CoreKplayerOption = "video_quality"
-	VideoFpsOption     CoreKplayerOption = "video_fps"
-	AudioSampleRate    CoreKplayerOption = "audio_sample_rate"
-	AudioChannelLayout CoreKplayerOption = "audio_channel_layout"
-	AudioChannels      CoreKplayerOption = "audio_channels"
-	VideoFillStrategy  CoreKplayerOption = "video_fill_strategy"
-)
-
 var libKplayerInstance *libKplayer = &libKplayer{
 	protocol:              "file",
 	video_width:           848,
@@ -61,7 +50,8 @@ var libKplayerInstance *libKplayer = &libKplayer{
 	cache_on:              false,
 	skip_invalid_resource: false,
 	video_fill_strategy:   0,
-	callbackMessageFn:     func(action int, message string) {},
+	correlator:            newCorrelator(),
+	callbackMessageFn:     func(message *Message) {},
 	callbackProgressFn:    func(percent float64, bitRate int) {},
 }
 
@@ -84,11 +74,18 @@ type libKplayer struct {
 	cache_uncheck_source  bool
 	skip_invalid_resource bool
 
+	// pending prompt correlation ids. the prompts are pushed and sent under prompt lock, so the core receives them
+	// in the order queued
+	correlator *correlator
+	promptLock sync.Mutex
+
 	// event message receiver
-	callbackMessageFn  func(action int, message string)
+	callbackMessageFn  func(message *Message)
 	callbackProgressFn func(percent float64, bitRate int)
 }
 
+var _ Engine = &libKplayer{}
+
 // GetLibKplayer return singleton LibKplayer instance
 func GetLibKplayerInstance() *libKplayer {
 	return libKplayerInstance
@@ -123,7 +120,7 @@ func (lb *libKplayer) SetOptions(options map[CoreKplayerOption]interface{}) erro
 	return nil
 }
 
-func (lb *libKplayer) SetCallBackMessage(fn func(action int, message string)) {
+func (lb *libKplayer) SetCallBackMessage(fn func(message *Message)) {
 	lb.callbackMessageFn = fn
 }
 
@@ -147,6 +144,11 @@ func (lb *libKplayer) GetInformation() *kpproto.Information {
 }
 
 func (lb *libKplayer) SendPrompt(action kpproto.EventPromptAction, body proto.Message) error {
+	// nobody waits for the reply, the message carries no correlation id
+	return lb.SendCorrelatedPrompt("", action, body)
+}
+
+func (lb *libKplayer) SendCorrelatedPrompt(correlationId string, action kpproto.EventPromptAction, body proto.Message) error {
 	m := jsonpb.Marshaler{}
 	str, err := m.MarshalToString(body)
 	if err != nil {
@@ -156,11 +158,19 @@ func (lb *libKplayer) SendPrompt(action kpproto.EventPromptAction, body proto.Me
 	cs := C.CString(str)
 	defer C.free(unsafe.Pointer(cs))
 
+	// the reply may arrive before PromptMessage returned. the core answers on its own thread
+	lb.promptLock.Lock()
+	lb.correlator.push(action, correlationId)
 	C.PromptMessage(C.int(action), cs)
-	log.WithFields(log.Fields{"action": kpproto.EventPromptAction_name[int32(action)]}).Debug("send prompt message")
+	lb.promptLock.Unlock()
+	log.WithFields(log.Fields{"action": kpproto.EventPromptAction_name[int32(action)], "correlation_id": correlationId}).Debug("send prompt message")
 	return nil
 }
 
+func (lb *libKplayer) ReleaseCorrelation(correlationId string) {
+	lb.correlator.release(correlationId)
+}
+
 func (lb *libKplayer) Run() int {
 	resultCode := 0
 	// start
@@ -204,6 +214,8 @@ func (lb *libKplayer) SetLogLevel(path string, level int) {
 }
 
 func (lb *libKplayer) Initialization() {
+	lb.correlator.reset()
+
 	if lb.cache_on {
 		C.SetCacheOn(C.int(1))
 	}
@@ -239,7 +251,12 @@ func (lb *libKplayer) AddOutput(body *kpprompt.EventPromptOutputAdd) error {
 
 	cs := C.CString(str)
 	defer C.free(unsafe.Pointer(cs))
+
+	// the core answers added output without correlation
+	lb.promptLock.Lock()
+	lb.correlator.push(kpproto.EventPromptAction_EVENT_PROMPT_ACTION_OUTPUT_ADD, "")
 	resultCode := C.AddOutput(cs)
+	lb.promptLock.Unlock()
 	if resultCode != 0 {
 		return fmt.Errorf("add output failed. result code: %d", resultCode)
 	}
@@ -256,7 +273,12 @@ func (lb *libKplayer) AddPlugin(body *kpprompt.EventPromptPluginAdd) error {
 
 	cs := C.CString(str)
 	defer C.free(unsafe.Pointer(cs))
+
+	// the core answers added plugin without correlation
+	lb.promptLock.Lock()
+	lb.correlator.push(kpproto.EventPromptAction_EVENT_PROMPT_ACTION_PLUGIN_ADD, "")
 	resultCode := C.AddPlugin(cs)
+	lb.promptLock.Unlock()
 	if resultCode != 0 {
 		return fmt.Errorf("add plugin failed. result code: %d", resultCode)
 	}
@@ -0,0 +1,28 @@
+//go:build !cgo
+// +build !cgo
+
+package core
+
+import (
+	log "github.com/sirupsen/logrus"
+)
+
+// libkplayer can only be linked through cgo. builds without cgo refuse to run the core,
+// the hermetic test suite runs on the engines created by NewFakeEngine
+var libKplayerInstance = &unlinkedKplayer{FakeEngine: NewFakeEngine()}
+
+// unlinkedKplayer the engine of builds without libkplayer. the commands and the config tools still work on it,
+// but it refuses to play
+type unlinkedKplayer struct {
+	*FakeEngine
+}
+
+// GetLibKplayer return singleton unlinked engine instance
+func GetLibKplayerInstance() *unlinkedKplayer {
+	return libKplayerInstance
+}
+
+func (u *unlinkedKplayer) Run() int {
+	log.Fatal("libkplayer is not linked. kplayer must be built with CGO_ENABLED=1 to play")
+	return -1
+}
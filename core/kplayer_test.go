@@ -1,3 +1,6 @@
+//go:build cgo
+// +build cgo
+
 package core
 
 import (
@@ -10,8 +13,8 @@ func TestFilePlay(t *testing.T) {
 	coreKplayer := GetLibKplayerInstance()
 	coreKplayer.Initialization()
 
-	coreKplayer.SetCallBackMessage(func(action int, message string) {
-		switch kpproto.EventMessageAction(action) {
+	coreKplayer.SetCallBackMessage(func(message *Message) {
+		switch message.Action {
 		case kpproto.EventMessageAction_EVENT_MESSAGE_ACTION_RESOURCE_EMPTY:
 			//add output
 			coreKplayer.SendPrompt(kpproto.EventPromptAction_EVENT_PROMPT_ACTION_OUTPUT_ADD, &kpprompt.EventPromptOutputAdd{Output: &kpprompt.PromptOutput{
@@ -43,8 +46,8 @@ func TestRtmpPlay(t *testing.T) {
 	})
 
 	coreKplayer.Initialization()
-	coreKplayer.SetCallBackMessage(func(action int, message string) {
-		switch kpproto.EventMessageAction(action) {
+	coreKplayer.SetCallBackMessage(func(message *Message) {
+		switch message.Action {
 		case kpproto.EventMessageAction_EVENT_MESSAGE_ACTION_RESOURCE_EMPTY:
 			//add output
 			coreKplayer.SendPrompt(kpproto.EventPromptAction_EVENT_PROMPT_ACTION_OUTPUT_ADD, &kpprompt.EventPromptOutputAdd{Output: &kpprompt.PromptOutput{
@@ -75,8 +78,8 @@ func TestMultiFilePlay(t *testing.T) {
 
 	end := false
 
-	coreKplayer.SetCallBackMessage(func(action int, message string) {
-		switch kpproto.EventMessageAction(action) {
+	coreKplayer.SetCallBackMessage(func(message *Message) {
+		switch message.Action {
 		case kpproto.EventMessageAction_EVENT_MESSAGE_ACTION_RESOURCE_EMPTY:
 			//add output
 			coreKplayer.SendPrompt(kpproto.EventPromptAction_EVENT_PROMPT_ACTION_OUTPUT_ADD, &kpprompt.EventPromptOutputAdd{Output: &kpprompt.PromptOutput{
@@ -117,8 +120,8 @@ func TestLibKplayer_SetSkipInvalidResource(t *testing.T) {
 	coreKplayer.Initialization()
 	end := false
 
-	coreKplayer.SetCallBackMessage(func(action int, message string) {
-		switch kpproto.EventMessageAction(action) {
+	coreKplayer.SetCallBackMessage(func(message *Message) {
+		switch message.Action {
 		case kpproto.EventMessageAction_EVENT_MESSAGE_ACTION_RESOURCE_EMPTY:
 			// add resource
 			coreKplayer.SendPrompt(kpproto.EventPromptAction_EVENT_PROMPT_ACTION_RESOURCE_ADD, &kpprompt.EventPromptResourceAdd{Resource: &kpproto.PromptResource{
@@ -148,8 +151,8 @@ func TestLibKplayer_GenerateCache(t *testing.T) {
 	coreKplayer.Initialization()
 	end := false
 
-	coreKplayer.SetCallBackMessage(func(action int, message string) {
-		switch kpproto.EventMessageAction(action) {
+	coreKplayer.SetCallBackMessage(func(message *Message) {
+		switch message.Action {
 		case kpproto.EventMessageAction_EVENT_MESSAGE_ACTION_RESOURCE_EMPTY:
 			// add resource
 			coreKplayer.SendPrompt(kpproto.EventPromptAction_EVENT_PROMPT_ACTION_RESOURCE_ADD, &kpprompt.EventPromptResourceAdd{Resource: &kpproto.PromptResource{
@@ -179,8 +182,8 @@ func TestLibKplayer_SetCacheUncheckSource(t *testing.T) {
 	coreKplayer.Initialization()
 	end := false
 
-	coreKplayer.SetCallBackMessage(func(action int, message string) {
-		switch kpproto.EventMessageAction(action) {
+	coreKplayer.SetCallBackMessage(func(message *Message) {
+		switch message.Action {
 		case kpproto.EventMessageAction_EVENT_MESSAGE_ACTION_RESOURCE_EMPTY:
 			// add resource
 			coreKplayer.SendPrompt(kpproto.EventPromptAction_EVENT_PROMPT_ACTION_RESOURCE_ADD, &kpprompt.EventPromptResourceAdd{Resource: &kpproto.PromptResource{
@@ -210,8 +213,8 @@ func TestLibKplayer_InvalidResource(t *testing.T) {
 	coreKplayer.SetCacheOn(true)
 	coreKplayer.Initialization()
 
-	coreKplayer.SetCallBackMessage(func(action int, message string) {
-		switch kpproto.EventMessageAction(action) {
+	coreKplayer.SetCallBackMessage(func(message *Message) {
+		switch message.Action {
 		case kpproto.EventMessageAction_EVENT_MESSAGE_ACTION_RESOURCE_EMPTY:
 			// add resource
 			coreKplayer.SendPrompt(kpproto.EventPromptAction_EVENT_PROMPT_ACTION_RESOURCE_ADD, &kpprompt.EventPromptResourceAdd{Resource: &kpproto.PromptResource{
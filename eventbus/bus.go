@@ -0,0 +1,188 @@
+package eventbus
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/bytelang/kplayer/core"
+	kpproto "github.com/bytelang/kplayer/types/core/proto"
+	log "github.com/sirupsen/logrus"
+)
+
+const DefaultCapacity = 500
+
+// Kind type of the published envelope
+type Kind int
+
+const (
+	// KindMessage message broadcast by core
+	KindMessage Kind = iota
+	// KindEvent event published by modules
+	KindEvent
+)
+
+// Event module event which is not answered by core, such as the core restarted by watchdog
+type Event struct {
+	Module string                 `json:"module"`
+	Name   string                 `json:"name"`
+	Body   map[string]interface{} `json:"body"`
+}
+
+// Envelope published item. Message is set on KindMessage, Event is set on KindEvent
+type Envelope struct {
+	Sequence uint64
+	Kind     Kind
+	Message  *core.Message
+	Event    *Event
+}
+
+// Bus deliver the published envelopes to subscribers in publish order.
+// publishing never blocks, a subscriber falling behind is handled by its policy
+type Bus struct {
+	subscribers map[string]*Subscription
+	sequence    uint64
+
+	// metrics of the removed subscribers
+	closedDropped uint64
+
+	lock sync.Mutex
+}
+
+var defaultBus = NewBus()
+
+func NewBus() *Bus {
+	return &Bus{
+		subscribers: make(map[string]*Subscription),
+	}
+}
+
+// Default return the bus shared by core messages, modules and rpc server
+func Default() *Bus {
+	return defaultBus
+}
+
+// PublishMessage publish core message on the default bus
+func PublishMessage(message *core.Message) {
+	defaultBus.PublishMessage(message)
+}
+
+// PublishEvent publish module event on the default bus
+func PublishEvent(event *Event) {
+	defaultBus.PublishEvent(event)
+}
+
+// Subscribe subscribe the default bus
+func Subscribe(name string, opts ...SubscribeOption) (*Subscription, error) {
+	return defaultBus.Subscribe(name, opts...)
+}
+
+// GetMetrics return the metrics of default bus
+func GetMetrics() Metrics {
+	return defaultBus.GetMetrics()
+}
+
+func (b *Bus) PublishMessage(message *core.Message) {
+	b.publish(Envelope{Kind: KindMessage, Message: message})
+}
+
+func (b *Bus) PublishEvent(event *Event) {
+	b.publish(Envelope{Kind: KindEvent, Event: event})
+}
+
+func (b *Bus) publish(envelope Envelope) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	b.sequence = b.sequence + 1
+	envelope.Sequence = b.sequence
+
+	for name, sub := range b.subscribers {
+		if !sub.match(&envelope) {
+			continue
+		}
+		if sub.deliver(envelope) {
+			continue
+		}
+
+		// disconnect policy
+		log.WithFields(log.Fields{"subscriber": name, "capacity": cap(sub.ch)}).Warn("subscriber falling behind. disconnected")
+		b.remove(sub)
+	}
+}
+
+// Subscribe add a subscriber. the name must be unique on the bus
+func (b *Bus) Subscribe(name string, opts ...SubscribeOption) (*Subscription, error) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	if _, ok := b.subscribers[name]; ok {
+		return nil, SubscriberExists
+	}
+
+	option := subscribeOption{
+		policy:   PolicyDropOldest,
+		capacity: DefaultCapacity,
+	}
+	for _, item := range opts {
+		item(&option)
+	}
+	if option.capacity <= 0 {
+		option.capacity = DefaultCapacity
+	}
+
+	sub := &Subscription{
+		name:    name,
+		bus:     b,
+		option:  option,
+		ch:      make(chan Envelope, option.capacity),
+		actions: map[kpproto.EventMessageAction]bool{},
+		kinds:   map[Kind]bool{},
+	}
+	for _, item := range option.actions {
+		sub.actions[item] = true
+	}
+	for _, item := range option.kinds {
+		sub.kinds[item] = true
+	}
+	b.subscribers[name] = sub
+
+	return sub, nil
+}
+
+// remove close the subscriber channel. the bus lock must be held
+func (b *Bus) remove(sub *Subscription) {
+	if b.subscribers[sub.name] != sub {
+		return
+	}
+
+	delete(b.subscribers, sub.name)
+	b.closedDropped = b.closedDropped + sub.dropped
+	close(sub.ch)
+}
+
+// GetMetrics return the delivery metrics of bus and subscribers
+func (b *Bus) GetMetrics() Metrics {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	metrics := Metrics{
+		Published: b.sequence,
+		Dropped:   b.closedDropped,
+	}
+	for _, sub := range b.subscribers {
+		metrics.Dropped = metrics.Dropped + sub.dropped
+		metrics.Subscribers = append(metrics.Subscribers, SubscriberMetrics{
+			Name:      sub.name,
+			Policy:    sub.option.policy.String(),
+			Capacity:  cap(sub.ch),
+			Pending:   len(sub.ch),
+			Delivered: sub.delivered,
+			Dropped:   sub.dropped,
+		})
+	}
+	sort.Slice(metrics.Subscribers, func(i, j int) bool {
+		return metrics.Subscribers[i].Name < metrics.Subscribers[j].Name
+	})
+
+	return metrics
+}
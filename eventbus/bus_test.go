@@ -0,0 +1,104 @@
+package eventbus
+
+import (
+	"testing"
+
+	"github.com/bytelang/kplayer/core"
+	kpproto "github.com/bytelang/kplayer/types/core/proto"
+)
+
+func publishActions(b *Bus, actions ...kpproto.EventMessageAction) {
+	for _, item := range actions {
+		b.PublishMessage(&core.Message{KPMessage: &kpproto.KPMessage{Action: item}})
+	}
+}
+
+func TestBusFilter(t *testing.T) {
+	b := NewBus()
+	messages, err := b.Subscribe("messages", WithActions(kpproto.EventMessageAction_EVENT_MESSAGE_ACTION_RESOURCE_START))
+	if err != nil {
+		t.Fatal(err)
+	}
+	events, err := b.Subscribe("events", WithKinds(KindEvent))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := b.Subscribe("events"); err != SubscriberExists {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	publishActions(b,
+		kpproto.EventMessageAction_EVENT_MESSAGE_ACTION_PLAYER_PAUSE,
+		kpproto.EventMessageAction_EVENT_MESSAGE_ACTION_RESOURCE_START,
+	)
+	b.PublishEvent(&Event{Module: "play", Name: "core_restart"})
+
+	if envelope := <-messages.C(); envelope.Kind != KindMessage || envelope.Sequence != 2 {
+		t.Fatalf("unexpected envelope: %+v", envelope)
+	}
+	if envelope := <-messages.C(); envelope.Kind != KindEvent || envelope.Event.Name != "core_restart" {
+		t.Fatalf("unexpected envelope: %+v", envelope)
+	}
+	if envelope := <-events.C(); envelope.Kind != KindEvent || envelope.Sequence != 3 {
+		t.Fatalf("unexpected envelope: %+v", envelope)
+	}
+	if len(events.C()) != 0 {
+		t.Fatal("events subscriber should not receive messages")
+	}
+}
+
+func TestBusPolicy(t *testing.T) {
+	b := NewBus()
+	oldest, _ := b.Subscribe("oldest", WithCapacity(2), WithPolicy(PolicyDropOldest))
+	newest, _ := b.Subscribe("newest", WithCapacity(2), WithPolicy(PolicyDropNewest))
+	disconnect, _ := b.Subscribe("disconnect", WithCapacity(2), WithPolicy(PolicyDisconnect))
+
+	publishActions(b,
+		kpproto.EventMessageAction_EVENT_MESSAGE_ACTION_RESOURCE_START,
+		kpproto.EventMessageAction_EVENT_MESSAGE_ACTION_RESOURCE_FINISH,
+		kpproto.EventMessageAction_EVENT_MESSAGE_ACTION_PLAYER_PAUSE,
+	)
+
+	expected := map[*Subscription][]uint64{
+		oldest:     {2, 3},
+		newest:     {1, 2},
+		disconnect: {1, 2},
+	}
+	for sub, sequences := range expected {
+		for _, item := range sequences {
+			if envelope := <-sub.C(); envelope.Sequence != item {
+				t.Fatalf("unexpected sequence of %s: %d, expected: %d", sub.GetName(), envelope.Sequence, item)
+			}
+		}
+	}
+	if _, ok := <-disconnect.C(); ok {
+		t.Fatal("disconnected subscriber channel should be closed")
+	}
+
+	metrics := b.GetMetrics()
+	if metrics.Published != 3 || metrics.Dropped != 3 || len(metrics.Subscribers) != 2 {
+		t.Fatalf("unexpected metrics: %+v", metrics)
+	}
+	delivered := map[string]uint64{"oldest": 3, "newest": 2}
+	for _, item := range metrics.Subscribers {
+		if item.Dropped != 1 || item.Delivered != delivered[item.Name] {
+			t.Fatalf("unexpected subscriber metrics: %+v", item)
+		}
+	}
+
+	// closed subscriber can be subscribed again
+	oldest.Close()
+	oldest.Close()
+	if _, err := b.Subscribe("oldest"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestParsePolicy(t *testing.T) {
+	if policy, err := ParsePolicy("DROP_NEWEST"); err != nil || policy != PolicyDropNewest {
+		t.Fatalf("unexpected policy: %s %v", policy, err)
+	}
+	if _, err := ParsePolicy("block"); err != PolicyInvalid {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
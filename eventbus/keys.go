@@ -0,0 +1,12 @@
+package eventbus
+
+const (
+	SubscriberExists EventBusError = "subscriber name has been registered"
+	PolicyInvalid    EventBusError = "subscribe policy invalid"
+)
+
+type EventBusError string
+
+func (e EventBusError) Error() string {
+	return string(e)
+}
@@ -0,0 +1,18 @@
+package eventbus
+
+// Metrics delivery metrics of bus. Dropped include the subscribers removed
+type Metrics struct {
+	Published   uint64              `json:"published"`
+	Dropped     uint64              `json:"dropped"`
+	Subscribers []SubscriberMetrics `json:"subscribers"`
+}
+
+// SubscriberMetrics delivery metrics of subscriber. Delivered is the number of envelopes pushed into its buffer
+type SubscriberMetrics struct {
+	Name      string `json:"name"`
+	Policy    string `json:"policy"`
+	Capacity  int    `json:"capacity"`
+	Pending   int    `json:"pending"`
+	Delivered uint64 `json:"delivered"`
+	Dropped   uint64 `json:"dropped"`
+}
@@ -0,0 +1,152 @@
+package eventbus
+
+import (
+	"strings"
+
+	kpproto "github.com/bytelang/kplayer/types/core/proto"
+)
+
+// Policy handling of the envelopes published while the subscriber buffer is full
+type Policy int
+
+const (
+	// PolicyDropOldest discard the oldest buffered envelope to make room for the new one
+	PolicyDropOldest Policy = iota
+	// PolicyDropNewest discard the new envelope
+	PolicyDropNewest
+	// PolicyDisconnect remove the subscriber and close its channel
+	PolicyDisconnect
+)
+
+var policyName = map[Policy]string{
+	PolicyDropOldest: "drop_oldest",
+	PolicyDropNewest: "drop_newest",
+	PolicyDisconnect: "disconnect",
+}
+
+func (p Policy) String() string {
+	return policyName[p]
+}
+
+// ParsePolicy return the policy of name
+func ParsePolicy(name string) (Policy, error) {
+	for key, item := range policyName {
+		if item == strings.ToLower(name) {
+			return key, nil
+		}
+	}
+
+	return 0, PolicyInvalid
+}
+
+type subscribeOption struct {
+	policy   Policy
+	capacity int
+	actions  []kpproto.EventMessageAction
+	kinds    []Kind
+}
+
+type SubscribeOption func(option *subscribeOption)
+
+// WithPolicy set the backpressure policy. default is PolicyDropOldest
+func WithPolicy(policy Policy) SubscribeOption {
+	return func(option *subscribeOption) {
+		option.policy = policy
+	}
+}
+
+// WithCapacity set the buffer size of subscriber. default is DefaultCapacity
+func WithCapacity(capacity int) SubscribeOption {
+	return func(option *subscribeOption) {
+		option.capacity = capacity
+	}
+}
+
+// WithActions only receive the core messages of actions. module events are not affected
+func WithActions(actions ...kpproto.EventMessageAction) SubscribeOption {
+	return func(option *subscribeOption) {
+		option.actions = append(option.actions, actions...)
+	}
+}
+
+// WithKinds only receive the envelopes of kinds
+func WithKinds(kinds ...Kind) SubscribeOption {
+	return func(option *subscribeOption) {
+		option.kinds = append(option.kinds, kinds...)
+	}
+}
+
+// Subscription subscriber of bus
+type Subscription struct {
+	name    string
+	bus     *Bus
+	option  subscribeOption
+	ch      chan Envelope
+	actions map[kpproto.EventMessageAction]bool
+	kinds   map[Kind]bool
+
+	// guarded by the bus lock
+	delivered uint64
+	dropped   uint64
+}
+
+// C return the channel of envelopes. the channel is closed when the subscription closed or disconnected
+func (s *Subscription) C() <-chan Envelope {
+	return s.ch
+}
+
+func (s *Subscription) GetName() string {
+	return s.name
+}
+
+// Close remove the subscriber from bus
+func (s *Subscription) Close() {
+	s.bus.lock.Lock()
+	defer s.bus.lock.Unlock()
+
+	s.bus.remove(s)
+}
+
+func (s *Subscription) match(envelope *Envelope) bool {
+	if len(s.kinds) != 0 && !s.kinds[envelope.Kind] {
+		return false
+	}
+	if envelope.Kind == KindMessage && len(s.actions) != 0 && !s.actions[envelope.Message.Action] {
+		return false
+	}
+
+	return true
+}
+
+// deliver push the envelope without blocking. return false when the subscriber should be disconnected.
+// publishing is serialized by the bus lock, the receiver is the only one taking from channel concurrently
+func (s *Subscription) deliver(envelope Envelope) bool {
+	select {
+	case s.ch <- envelope:
+		s.delivered = s.delivered + 1
+		return true
+	default:
+	}
+
+	switch s.option.policy {
+	case PolicyDropNewest:
+		s.dropped = s.dropped + 1
+	case PolicyDropOldest:
+		select {
+		case <-s.ch:
+			s.dropped = s.dropped + 1
+		default:
+		}
+		select {
+		case s.ch <- envelope:
+			s.delivered = s.delivered + 1
+		default:
+			s.dropped = s.dropped + 1
+		}
+	case PolicyDisconnect:
+		s.dropped = s.dropped + 1
+		return false
+	}
+
+	return true
+}
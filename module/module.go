@@ -1,83 +1,172 @@
 package module
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+	"github.com/bytelang/kplayer/core"
 	"github.com/bytelang/kplayer/types"
 	kpproto "github.com/bytelang/kplayer/types/core/proto"
+	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"strings"
 	"sync"
+	"time"
 )
 
 type ModuleOption int
 
 const (
 	ModuleOptionGenerateCache ModuleOption = iota
+	// restore the state journaled by the last running
+	ModuleOptionResume
 )
 
+// HasModuleOption whether the option is in the running options
+func HasModuleOption(options []ModuleOption, option ModuleOption) bool {
+	for _, item := range options {
+		if item == option {
+			return true
+		}
+	}
+
+	return false
+}
+
+// keeperTimeout the longest time waiting for core message, when the caller context has no deadline
+var keeperTimeout = time.Duration(types.DefaultRPCTimeout) * time.Second
+
+// SetKeeperTimeout set the default deadline of KeeperContext waits. zero means waiting without deadline
+func SetKeeperTimeout(timeout time.Duration) {
+	keeperTimeout = timeout
+}
+
 type KeeperContext struct {
 	id        string
 	action    kpproto.EventMessageAction
 	ch        chan string
 	validator func(msg string) bool
 	dirty     bool
+	keeper    *ModuleKeeper
 }
 
-func NewKeeperContext(id string, action kpproto.EventMessageAction, validator func(msg string) bool) KeeperContext {
-	return KeeperContext{
+func NewKeeperContext(id string, action kpproto.EventMessageAction, validator func(msg string) bool) *KeeperContext {
+	return &KeeperContext{
 		id:        id,
 		action:    action,
-		ch:        make(chan string),
+		ch:        make(chan string, 1),
 		validator: validator,
 		dirty:     false,
 	}
 }
 
+// Close unregister the keeper context. the abandoned waiter will never be triggered. the prompt not answered yet
+// is released from the engine, its late reply is dropped
 func (kc *KeeperContext) Close() {
-	close(kc.ch)
+	if kc.keeper == nil {
+		return
+	}
+
+	if answered := kc.keeper.unregisterKeeperChannel(kc.id); !answered && kc.keeper.engine != nil {
+		kc.keeper.engine.ReleaseCorrelation(kc.id)
+	}
 }
 
-func (kc KeeperContext) Wait() {
-	_ = <-kc.ch
+// Wait block until the expected message arrived, the context done or the default deadline exceeded
+func (kc *KeeperContext) Wait(ctx context.Context) error {
+	if _, ok := ctx.Deadline(); !ok && keeperTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, keeperTimeout)
+		defer cancel()
+	}
+
+	select {
+	case <-kc.ch:
+		return nil
+	case <-ctx.Done():
+		if ctx.Err() == context.DeadlineExceeded {
+			return status.Errorf(codes.DeadlineExceeded, "wait for core message timeout. action: %s", kc.action)
+		}
+		return status.Errorf(codes.Canceled, "wait for core message canceled. action: %s", kc.action)
+	}
 }
 
-func (kc KeeperContext) GetId() string {
+// trigger wake up the waiter. channel is buffered and triggered once, never block on abandoned waiter
+func (kc *KeeperContext) trigger(body string) {
+	select {
+	case kc.ch <- body:
+	default:
+	}
+	kc.dirty = true
+}
+
+func (kc *KeeperContext) GetId() string {
 	return kc.id
 }
 
 type ModuleKeeper struct {
-	keeper       []KeeperContext
+	keeper       []*KeeperContext
 	triggerMutex sync.Mutex
+
+	// the engine releasing the prompts of closed keepers
+	engine core.Engine
+}
+
+// SetKeeperEngine set the engine which the prompts of keepers are sent to
+func (m *ModuleKeeper) SetKeeperEngine(engine core.Engine) {
+	m.engine = engine
 }
 
 func (m *ModuleKeeper) GetKeeperContext(id string) *KeeperContext {
 	for _, item := range m.keeper {
 		if item.id == id {
-			return &item
+			return item
 		}
 	}
 
 	return nil
 }
 
-func (m *ModuleKeeper) RegisterKeeperChannel(ctx KeeperContext) error {
+func (m *ModuleKeeper) RegisterKeeperChannel(ctx *KeeperContext) error {
 	m.triggerMutex.Lock()
 	defer m.triggerMutex.Unlock()
 
 	if m.GetKeeperContext(ctx.id) != nil {
 		return fmt.Errorf("id has existed: %s", ctx.id)
 	}
+	ctx.keeper = m
 	m.keeper = append(m.keeper, ctx)
 
 	return nil
 }
 
-func (m *ModuleKeeper) Trigger(message *kpproto.KPMessage) {
+// unregisterKeeperChannel remove the keeper context. return false when it has not been triggered
+func (m *ModuleKeeper) unregisterKeeperChannel(id string) bool {
+	m.triggerMutex.Lock()
+	defer m.triggerMutex.Unlock()
+
+	for key, item := range m.keeper {
+		if item.id == id {
+			m.keeper = append(m.keeper[:key], m.keeper[key+1:]...)
+			return item.dirty
+		}
+	}
+
+	// the triggered keeper has been washed
+	return true
+}
+
+// Trigger deliver the core message to the keeper waiting for it. a correlated message is delivered to
+// the keeper which sent the prompt only, and dropped when the keeper has gone. the uncorrelated message is
+// delivered to the keepers matched by action and validator
+func (m *ModuleKeeper) Trigger(message *core.Message) {
 	m.triggerMutex.Lock()
 	defer m.triggerMutex.Unlock()
 
 	// delete dirty object
-	washingKeeper := []KeeperContext{}
+	washingKeeper := []*KeeperContext{}
 	for _, item := range m.keeper {
 		if item.dirty == false {
 			washingKeeper = append(washingKeeper, item)
@@ -85,21 +174,63 @@ func (m *ModuleKeeper) Trigger(message *kpproto.KPMessage) {
 	}
 	m.keeper = washingKeeper
 
-	for key, item := range m.keeper {
+	if message.CorrelationId != "" {
+		for _, item := range m.keeper {
+			if item.id == message.CorrelationId && item.action == message.Action {
+				// validator unmarshal the message body for the waiter. the reply not matched is not taken
+				if !item.validator(message.Body) {
+					log.WithFields(log.Fields{"action": message.Action, "correlation_id": message.CorrelationId}).
+						Warn("correlated message rejected by keeper")
+					return
+				}
+				item.trigger(message.Body)
+				return
+			}
+		}
+		return
+	}
+
+	for _, item := range m.keeper {
 		if item.action == message.Action {
 			if item.validator(message.Body) {
-				item.ch <- message.Body
-				m.keeper[key].dirty = true
+				item.trigger(message.Body)
 			}
 		}
 	}
 }
 
 type BasicAppModule interface {
-	RegisterKeeperChannel(ctx KeeperContext) error
+	RegisterKeeperChannel(ctx *KeeperContext) error
 	GetKeeperContext(id string) *KeeperContext
 	ParseMessage(message *kpproto.KPMessage)
-	TriggerMessage(message *kpproto.KPMessage)
+	TriggerMessage(message *core.Message)
+}
+
+// ProgressAppModule module receiving the playback progress reported by core
+type ProgressAppModule interface {
+	ParseProgress(percent float64, bitRate int)
+}
+
+// DependentAppModule module depending on other modules by name.
+// the dependencies are initialized and begin running before the module, end running after it
+type DependentAppModule interface {
+	DependsOn() []string
+}
+
+// ResumeAppModule module restoring its state to the core restarted by watchdog
+type ResumeAppModule interface {
+	ResumeRunning()
+}
+
+// ReloadAppModule module applying its changed config section on running.
+// the changes cannot be applied live are logged by module
+type ReloadAppModule interface {
+	ReloadConfig(ctx context.Context, cfg json.RawMessage) error
+}
+
+// ConfigAppModule module reporting its effective config section, including the changes made on running
+type ConfigAppModule interface {
+	GetEffectiveConfig() interface{}
 }
 
 type AppModule interface {
@@ -113,20 +244,85 @@ type AppModule interface {
 }
 
 type ModuleManager struct {
-	Modules         map[string]AppModule
-	OrderInitConfig []string
+	Modules map[string]AppModule
+	Order   []string
 }
 
-func NewModuleManager(modules ...AppModule) ModuleManager {
+// NewModuleManager return module manager. modules are ordered by their dependencies,
+// modules without dependency between keep the registration order
+func NewModuleManager(modules ...AppModule) (ModuleManager, error) {
 	moduleMap := ModuleManager{
 		Modules: make(map[string]AppModule, 0),
 	}
 
 	for _, module := range modules {
+		if _, ok := moduleMap.Modules[module.GetModuleName()]; ok {
+			return moduleMap, fmt.Errorf("module has existed: %s", module.GetModuleName())
+		}
 		moduleMap.Modules[module.GetModuleName()] = module
 	}
 
-	return moduleMap
+	order, err := sortModules(modules)
+	if err != nil {
+		return moduleMap, err
+	}
+	moduleMap.Order = order
+
+	return moduleMap, nil
+}
+
+// sortModules return the module names in topological order of dependencies
+func sortModules(modules []AppModule) ([]string, error) {
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+
+	moduleMap := make(map[string]AppModule)
+	for _, module := range modules {
+		moduleMap[module.GetModuleName()] = module
+	}
+
+	var order []string
+	var path []string
+	state := make(map[string]int)
+
+	var visit func(name string) error
+	visit = func(name string) error {
+		switch state[name] {
+		case visited:
+			return nil
+		case visiting:
+			return fmt.Errorf("module dependency cycle: %s -> %s", strings.Join(path, " -> "), name)
+		}
+
+		state[name] = visiting
+		path = append(path, name)
+		if dependent, ok := moduleMap[name].(DependentAppModule); ok {
+			for _, dep := range dependent.DependsOn() {
+				if _, ok := moduleMap[dep]; !ok {
+					return fmt.Errorf("module %s depends on unknown module: %s", name, dep)
+				}
+				if err := visit(dep); err != nil {
+					return err
+				}
+			}
+		}
+		path = path[:len(path)-1]
+		state[name] = visited
+
+		order = append(order, name)
+		return nil
+	}
+
+	for _, module := range modules {
+		if err := visit(module.GetModuleName()); err != nil {
+			return nil, err
+		}
+	}
+
+	return order, nil
 }
 
 func (mm *ModuleManager) GetModule(name string) AppModule {
@@ -134,6 +330,27 @@ func (mm *ModuleManager) GetModule(name string) AppModule {
 	return m
 }
 
-func (mm *ModuleManager) SetOrderInitConfig(order ...string) {
-	mm.OrderInitConfig = order
+// GetOrderedModules return modules in dependency order, dependencies first
+func (mm *ModuleManager) GetOrderedModules() []AppModule {
+	var modules []AppModule
+	for _, name := range mm.Order {
+		modules = append(modules, mm.Modules[name])
+	}
+
+	return modules
+}
+
+// BeginRunning begin running modules in dependency order
+func (mm *ModuleManager) BeginRunning(option ...ModuleOption) {
+	for _, m := range mm.GetOrderedModules() {
+		m.BeginRunning(option...)
+	}
+}
+
+// EndRunning end running modules in reverse dependency order
+func (mm *ModuleManager) EndRunning(option ...ModuleOption) {
+	modules := mm.GetOrderedModules()
+	for i := len(modules) - 1; i >= 0; i-- {
+		modules[i].EndRunning(option...)
+	}
 }
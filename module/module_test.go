@@ -0,0 +1,272 @@
+package module
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bytelang/kplayer/core"
+	"github.com/bytelang/kplayer/types"
+	kpproto "github.com/bytelang/kplayer/types/core/proto"
+	"github.com/spf13/cobra"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestKeeperContextTrigger(t *testing.T) {
+	keeper := &ModuleKeeper{}
+	keeperCtx := NewKeeperContext("trigger", kpproto.EventMessageAction_EVENT_MESSAGE_ACTION_PLAYER_PAUSE, func(msg string) bool {
+		return true
+	})
+	defer keeperCtx.Close()
+
+	if err := keeper.RegisterKeeperChannel(keeperCtx); err != nil {
+		t.Fatal(err)
+	}
+
+	// triggered before wait
+	keeper.Trigger(&core.Message{KPMessage: &kpproto.KPMessage{Action: kpproto.EventMessageAction_EVENT_MESSAGE_ACTION_PLAYER_PAUSE}})
+	if err := keeperCtx.Wait(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestKeeperContextDeadline(t *testing.T) {
+	keeper := &ModuleKeeper{}
+	keeperCtx := NewKeeperContext("deadline", kpproto.EventMessageAction_EVENT_MESSAGE_ACTION_PLAYER_PAUSE, func(msg string) bool {
+		return true
+	})
+
+	if err := keeper.RegisterKeeperChannel(keeperCtx); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*10)
+	defer cancel()
+
+	err := keeperCtx.Wait(ctx)
+	if status.Code(err) != codes.DeadlineExceeded {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	keeperCtx.Close()
+
+	if keeper.GetKeeperContext("deadline") != nil {
+		t.Fatal("closed keeper context should be unregistered")
+	}
+}
+
+func TestKeeperContextDefaultTimeout(t *testing.T) {
+	defer SetKeeperTimeout(keeperTimeout)
+	SetKeeperTimeout(time.Millisecond * 10)
+
+	keeperCtx := NewKeeperContext("timeout", kpproto.EventMessageAction_EVENT_MESSAGE_ACTION_PLAYER_PAUSE, func(msg string) bool {
+		return true
+	})
+	defer keeperCtx.Close()
+
+	if err := keeperCtx.Wait(context.Background()); status.Code(err) != codes.DeadlineExceeded {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestKeeperContextCanceled(t *testing.T) {
+	keeperCtx := NewKeeperContext("canceled", kpproto.EventMessageAction_EVENT_MESSAGE_ACTION_PLAYER_PAUSE, func(msg string) bool {
+		return true
+	})
+	defer keeperCtx.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if err := keeperCtx.Wait(ctx); status.Code(err) != codes.Canceled {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestKeeperTriggerAbandonedWaiter(t *testing.T) {
+	keeper := &ModuleKeeper{}
+	for _, id := range []string{"first", "second"} {
+		keeperCtx := NewKeeperContext(id, kpproto.EventMessageAction_EVENT_MESSAGE_ACTION_PLAYER_PAUSE, func(msg string) bool {
+			return true
+		})
+		if err := keeper.RegisterKeeperChannel(keeperCtx); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// nobody waits, trigger must not block
+	done := make(chan bool)
+	go func() {
+		keeper.Trigger(&core.Message{KPMessage: &kpproto.KPMessage{Action: kpproto.EventMessageAction_EVENT_MESSAGE_ACTION_PLAYER_PAUSE}})
+		keeper.Trigger(&core.Message{KPMessage: &kpproto.KPMessage{Action: kpproto.EventMessageAction_EVENT_MESSAGE_ACTION_PLAYER_PAUSE}})
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("trigger blocked by abandoned waiter")
+	}
+}
+
+func TestKeeperTriggerCorrelated(t *testing.T) {
+	keeper := &ModuleKeeper{}
+	keepers := map[string]*KeeperContext{}
+	for _, id := range []string{"first", "second"} {
+		keeperCtx := NewKeeperContext(id, kpproto.EventMessageAction_EVENT_MESSAGE_ACTION_PLAYER_SKIP, func(msg string) bool {
+			return true
+		})
+		defer keeperCtx.Close()
+
+		if err := keeper.RegisterKeeperChannel(keeperCtx); err != nil {
+			t.Fatal(err)
+		}
+		keepers[id] = keeperCtx
+	}
+
+	// reply of the second prompt arrived first
+	keeper.Trigger(&core.Message{
+		KPMessage:     &kpproto.KPMessage{Action: kpproto.EventMessageAction_EVENT_MESSAGE_ACTION_PLAYER_SKIP},
+		CorrelationId: "second",
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*10)
+	defer cancel()
+	if err := keepers["first"].Wait(ctx); status.Code(err) != codes.DeadlineExceeded {
+		t.Fatalf("first keeper should not receive the reply of second prompt: %v", err)
+	}
+	if err := keepers["second"].Wait(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestKeeperTriggerCorrelatedOrphan(t *testing.T) {
+	keeper := &ModuleKeeper{}
+	keepers := map[string]*KeeperContext{}
+	for _, item := range []struct {
+		id     string
+		accept bool
+	}{{"waiting", true}, {"rejecting", false}} {
+		accept := item.accept
+		keeperCtx := NewKeeperContext(item.id, kpproto.EventMessageAction_EVENT_MESSAGE_ACTION_PLAYER_SKIP, func(msg string) bool {
+			return accept
+		})
+		defer keeperCtx.Close()
+
+		if err := keeper.RegisterKeeperChannel(keeperCtx); err != nil {
+			t.Fatal(err)
+		}
+		keepers[item.id] = keeperCtx
+	}
+
+	// the reply of the keeper gone and the reply rejected by validator wake nobody
+	for _, id := range []string{"gone", "rejecting"} {
+		keeper.Trigger(&core.Message{
+			KPMessage:     &kpproto.KPMessage{Action: kpproto.EventMessageAction_EVENT_MESSAGE_ACTION_PLAYER_SKIP},
+			CorrelationId: id,
+		})
+	}
+
+	for _, id := range []string{"waiting", "rejecting"} {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*10)
+		err := keepers[id].Wait(ctx)
+		cancel()
+		if status.Code(err) != codes.DeadlineExceeded {
+			t.Fatalf("keeper %s should not be triggered: %v", id, err)
+		}
+	}
+}
+
+type testModule struct {
+	*ModuleKeeper
+	name  string
+	deps  []string
+	trace *[]string
+}
+
+func newTestModule(name string, trace *[]string, deps ...string) *testModule {
+	return &testModule{ModuleKeeper: &ModuleKeeper{}, name: name, deps: deps, trace: trace}
+}
+
+func (m *testModule) GetModuleName() string                   { return m.name }
+func (m *testModule) GetCommand() *cobra.Command              { return nil }
+func (m *testModule) ParseMessage(message *kpproto.KPMessage) {}
+func (m *testModule) TriggerMessage(message *core.Message)    {}
+func (m *testModule) ValidateConfig() error                   { return nil }
+func (m *testModule) DependsOn() []string                     { return m.deps }
+func (m *testModule) BeginRunning(option ...ModuleOption) {
+	*m.trace = append(*m.trace, "begin."+m.name)
+}
+func (m *testModule) EndRunning(option ...ModuleOption) { *m.trace = append(*m.trace, "end."+m.name) }
+func (m *testModule) InitConfig(ctx *types.ClientContext, cfg json.RawMessage) (interface{}, error) {
+	return nil, nil
+}
+
+func TestModuleManagerOrder(t *testing.T) {
+	var trace []string
+	mm, err := NewModuleManager(
+		newTestModule("plugin", &trace, "resource", "output"),
+		newTestModule("resource", &trace, "play"),
+		newTestModule("output", &trace),
+		newTestModule("play", &trace),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if order := strings.Join(mm.Order, ","); order != "play,resource,output,plugin" {
+		t.Fatalf("unexpected order: %s", order)
+	}
+
+	mm.BeginRunning()
+	mm.EndRunning()
+	expected := "begin.play,begin.resource,begin.output,begin.plugin,end.plugin,end.output,end.resource,end.play"
+	if result := strings.Join(trace, ","); result != expected {
+		t.Fatalf("unexpected running order: %s", result)
+	}
+}
+
+func TestModuleManagerInvalidDependency(t *testing.T) {
+	var trace []string
+	if _, err := NewModuleManager(
+		newTestModule("play", &trace, "plugin"),
+		newTestModule("resource", &trace, "play"),
+		newTestModule("plugin", &trace, "resource"),
+	); err == nil || !strings.Contains(err.Error(), "play -> plugin -> resource -> play") {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if _, err := NewModuleManager(newTestModule("resource", &trace, "play")); err == nil {
+		t.Fatal("unknown dependency should be failed")
+	}
+
+	if _, err := NewModuleManager(newTestModule("play", &trace), newTestModule("play", &trace)); err == nil {
+		t.Fatal("duplicate module should be failed")
+	}
+}
+
+func TestRegister(t *testing.T) {
+	defer func() { factories = nil }()
+
+	var trace []string
+	Register(func(engine core.Engine) AppModule {
+		return newTestModule("external", &trace, "play")
+	})
+
+	var modules []AppModule
+	modules = append(modules, newTestModule("play", &trace))
+	for _, factory := range GetRegisteredFactories() {
+		modules = append(modules, factory(nil))
+	}
+
+	mm, err := NewModuleManager(modules...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mm.BeginRunning()
+	if got := strings.Join(trace, ","); got != "begin.play,begin.external" {
+		t.Fatalf("unexpected running order: %s", got)
+	}
+}
@@ -3,7 +3,6 @@ package provider
 import (
 	"context"
 	"fmt"
-	"github.com/bytelang/kplayer/core"
 	"github.com/bytelang/kplayer/module"
 	kptypes "github.com/bytelang/kplayer/types"
 	kpproto "github.com/bytelang/kplayer/types/core/proto"
@@ -21,15 +20,6 @@ func (p *Provider) OutputAdd(ctx context.Context, args *svrproto.OutputAddArgs)
 		outputUnique = kptypes.GetUniqueString(outputPath)
 	}
 
-	if err := p.addOutput(kpmodule.Output{
-		Path:       outputPath,
-		Unique:     outputUnique,
-		CreateTime: uint64(time.Now().Unix()),
-		Connected:  false,
-	}); err != nil {
-		return nil, err
-	}
-
 	// register prompt
 	outputAddMsg := &msg.EventMessageOutputAdd{}
 	keeperCtx := module.NewKeeperContext(kptypes.GetRandString(), kpproto.EventMessageAction_EVENT_MESSAGE_ACTION_OUTPUT_ADD, func(msg string) bool {
@@ -43,8 +33,19 @@ func (p *Provider) OutputAdd(ctx context.Context, args *svrproto.OutputAddArgs)
 		return nil, err
 	}
 
+	if err := p.addOutput(keeperCtx.GetId(), kpmodule.Output{
+		Path:       outputPath,
+		Unique:     outputUnique,
+		CreateTime: uint64(time.Now().Unix()),
+		Connected:  false,
+	}); err != nil {
+		return nil, err
+	}
+
 	// wait context
-	keeperCtx.Wait()
+	if err := keeperCtx.Wait(ctx); err != nil {
+		return nil, err
+	}
 	if len(outputAddMsg.Error) != 0 {
 		return nil, fmt.Errorf("%s", outputAddMsg.Error)
 	}
@@ -78,14 +79,6 @@ func (p *Provider) OutputRemove(ctx context.Context, args *svrproto.OutputRemove
 		}, nil
 	}
 
-	coreKplayer := core.GetLibKplayerInstance()
-
-	if err := coreKplayer.SendPrompt(kpproto.EventPromptAction_EVENT_PROMPT_ACTION_OUTPUT_REMOVE, &kpprompt.EventPromptOutputRemove{
-		Unique: args.Unique,
-	}); err != nil {
-		return nil, err
-	}
-
 	// register prompt
 	outputRemoveMsg := &msg.EventMessageOutputRemove{}
 	keeperCtx := module.NewKeeperContext(kptypes.GetRandString(), kpproto.EventMessageAction_EVENT_MESSAGE_ACTION_OUTPUT_REMOVE, func(msg string) bool {
@@ -98,8 +91,18 @@ func (p *Provider) OutputRemove(ctx context.Context, args *svrproto.OutputRemove
 		return nil, err
 	}
 
+	coreKplayer := p.engine
+
+	if err := coreKplayer.SendCorrelatedPrompt(keeperCtx.GetId(), kpproto.EventPromptAction_EVENT_PROMPT_ACTION_OUTPUT_REMOVE, &kpprompt.EventPromptOutputRemove{
+		Unique: args.Unique,
+	}); err != nil {
+		return nil, err
+	}
+
 	// wait context
-	keeperCtx.Wait()
+	if err := keeperCtx.Wait(ctx); err != nil {
+		return nil, err
+	}
 	if len(outputRemoveMsg.Error) != 0 {
 		return nil, fmt.Errorf("%s", outputRemoveMsg.Error)
 	}
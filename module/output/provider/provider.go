@@ -14,6 +14,7 @@ import (
 	svrproto "github.com/bytelang/kplayer/types/server"
 	log "github.com/sirupsen/logrus"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -33,6 +34,9 @@ type Provider struct {
 	module.ModuleKeeper
 	svrproto.UnimplementedOutputGreeterServer
 
+	// core engine
+	engine core.Engine
+
 	// module outputs
 	configList        Outputs
 	reconnectInternal int32
@@ -44,10 +48,14 @@ type Provider struct {
 
 var _ ProviderI = &Provider{}
 
-func NewProvider() *Provider {
-	return &Provider{
+func NewProvider(engine core.Engine) *Provider {
+	p := &Provider{
+		engine:        engine,
 		reconnectChan: make(chan interface{}, 5),
 	}
+	p.SetKeeperEngine(engine)
+
+	return p
 }
 
 func (p *Provider) InitModule(ctx *kptypes.ClientContext, config *config.Output) {
@@ -90,7 +98,7 @@ func (p *Provider) ParseMessage(message *kpproto.KPMessage) {
 			logFields.Errorf("output add failed. error: %s", msg.Error)
 
 			// send reconnect instance to channel
-			if p.reconnectInternal > 0 {
+			if atomic.LoadInt32(&p.reconnectInternal) > 0 {
 				p.reconnectChan <- config.OutputInstance{
 					Path:   msg.Output.Path,
 					Unique: msg.Output.Unique,
@@ -134,7 +142,7 @@ func (p *Provider) ParseMessage(message *kpproto.KPMessage) {
 		logFields.Error("output disconnection")
 
 		// send reconnect instance to channel
-		if p.reconnectInternal > 0 {
+		if atomic.LoadInt32(&p.reconnectInternal) > 0 {
 			p.reconnectChan <- config.OutputInstance{
 				Path:   msg.Output.Path,
 				Unique: msg.Output.Unique,
@@ -177,16 +185,38 @@ func (p *Provider) ValidateConfig() error {
 	return nil
 }
 
-func (p *Provider) addOutput(output moduletypes.Output) error {
+// GetEffectiveConfig return the output config of the running outputs
+func (p *Provider) GetEffectiveConfig() interface{} {
+	p.configList.lock.Lock()
+	defer p.configList.lock.Unlock()
+
+	cfg := config.Output{
+		ReconnectInternal: atomic.LoadInt32(&p.reconnectInternal),
+	}
+	for _, item := range p.configList.outputs {
+		cfg.Lists = append(cfg.Lists, &config.OutputInstance{
+			Path:   item.Path,
+			Unique: item.Unique,
+		})
+	}
+
+	return cfg
+}
+
+func (p *Provider) addOutput(correlationId string, output moduletypes.Output) error {
 	// validate
 	if p.configList.Exist(output.Unique) {
 		return OutputUniqueHasExisted
 	}
 
+	if err := p.configList.AppendOutput(output); err != nil {
+		return err
+	}
+
 	// send prompt
-	corePlayer := core.GetLibKplayerInstance()
+	corePlayer := p.engine
 
-	if err := corePlayer.SendPrompt(kpproto.EventPromptAction_EVENT_PROMPT_ACTION_OUTPUT_ADD, &kpprompt.EventPromptOutputAdd{
+	if err := corePlayer.SendCorrelatedPrompt(correlationId, kpproto.EventPromptAction_EVENT_PROMPT_ACTION_OUTPUT_ADD, &kpprompt.EventPromptOutputAdd{
 		Output: &kpprompt.PromptOutput{
 			Path:   output.Path,
 			Unique: output.Unique,
@@ -195,10 +225,6 @@ func (p *Provider) addOutput(output moduletypes.Output) error {
 		log.Warn(err)
 	}
 
-	if err := p.configList.AppendOutput(output); err != nil {
-		return err
-	}
-
 	return nil
 }
 
@@ -214,10 +240,11 @@ func (p *Provider) StartReconnect() {
 			ins := instance.(config.OutputInstance)
 			logFields := log.WithFields(log.Fields{"path": ins.Path, "unique": ins.Unique})
 
-			logFields.Infof("will be reconnect on after %d seconds", p.reconnectInternal)
-			time.Sleep(time.Second * time.Duration(p.reconnectInternal))
+			reconnectInternal := atomic.LoadInt32(&p.reconnectInternal)
+			logFields.Infof("will be reconnect on after %d seconds", reconnectInternal)
+			time.Sleep(time.Second * time.Duration(reconnectInternal))
 
-			corePlayer := core.GetLibKplayerInstance()
+			corePlayer := p.engine
 			_ = corePlayer.SendPrompt(kpproto.EventPromptAction_EVENT_PROMPT_ACTION_OUTPUT_ADD, &kpprompt.EventPromptOutputAdd{
 				Output: &kpprompt.PromptOutput{
 					Path:   ins.Path,
@@ -235,7 +262,7 @@ func (p *Provider) StartReconnect() {
 
 func (p *Provider) BeginRunning() {
 	for _, item := range p.configList.outputs {
-		if err := core.GetLibKplayerInstance().AddOutput(&kpprompt.EventPromptOutputAdd{
+		if err := p.engine.AddOutput(&kpprompt.EventPromptOutputAdd{
 			Output: &kpprompt.PromptOutput{
 				Path:   item.Path,
 				Unique: item.Unique,
@@ -246,6 +273,15 @@ func (p *Provider) BeginRunning() {
 	}
 }
 
+// ResumeRunning add the outputs to the core restarted by watchdog
+func (p *Provider) ResumeRunning() {
+	for key := range p.configList.outputs {
+		p.configList.outputs[key].Connected = false
+	}
+
+	p.BeginRunning()
+}
+
 func (p *Provider) EndReconnect() {
 	p.reconnectChan <- nil
 }
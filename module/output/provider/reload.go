@@ -0,0 +1,83 @@
+package provider
+
+import (
+	"context"
+	"sync/atomic"
+
+	"github.com/bytelang/kplayer/types/config"
+	moduletypes "github.com/bytelang/kplayer/types/module"
+	svrproto "github.com/bytelang/kplayer/types/server"
+	log "github.com/sirupsen/logrus"
+)
+
+// ReloadConfig apply the changed output config to the running outputs.
+// the outputs are matched by unique, by path when the unique is not configured
+func (p *Provider) ReloadConfig(ctx context.Context, cfg *config.Output) error {
+	if atomic.SwapInt32(&p.reconnectInternal, cfg.ReconnectInternal) != cfg.ReconnectInternal {
+		log.WithField("reconnect_internal", cfg.ReconnectInternal).Info("output reconnect internal changed")
+	}
+
+	adds, removes := p.diffOutputs(cfg.Lists)
+	for _, item := range removes {
+		logFields := log.WithFields(log.Fields{"path": item.Path, "unique": item.Unique})
+		if _, err := p.OutputRemove(ctx, &svrproto.OutputRemoveArgs{Unique: item.Unique}); err != nil {
+			logFields.WithField("error", err).Error("reload remove output failed")
+			continue
+		}
+		logFields.Info("reload remove output success")
+	}
+	for _, item := range adds {
+		logFields := log.WithFields(log.Fields{"path": item.Path, "unique": item.Unique})
+		reply, err := p.OutputAdd(ctx, &svrproto.OutputAddArgs{Path: item.Path, Unique: item.Unique})
+		if err != nil {
+			logFields.WithField("error", err).Error("reload add output failed")
+			continue
+		}
+		logFields.WithField("unique", reply.Output.Unique).Info("reload add output success")
+	}
+
+	return nil
+}
+
+// diffOutputs return the config items to add and the running outputs to remove. the running outputs are
+// snapshotted under lock, the core messages of adding and removing are waited without it
+func (p *Provider) diffOutputs(lists []*config.OutputInstance) ([]*config.OutputInstance, []moduletypes.Output) {
+	p.configList.lock.Lock()
+	defer p.configList.lock.Unlock()
+
+	matched := map[string]bool{}
+	var adds []*config.OutputInstance
+	for _, item := range lists {
+		if output := p.matchOutput(item, matched); output != nil {
+			matched[output.Unique] = true
+			continue
+		}
+		adds = append(adds, item)
+	}
+
+	var removes []moduletypes.Output
+	for _, item := range p.configList.outputs {
+		if !matched[item.Unique] {
+			removes = append(removes, item)
+		}
+	}
+
+	return adds, removes
+}
+
+// matchOutput return the running output of config item which is not matched yet. the caller must hold configList lock
+func (p *Provider) matchOutput(item *config.OutputInstance, matched map[string]bool) *moduletypes.Output {
+	for key := range p.configList.outputs {
+		output := &p.configList.outputs[key]
+		if matched[output.Unique] || output.Path != item.Path {
+			continue
+		}
+		if item.Unique != "" && item.Unique != output.Unique {
+			continue
+		}
+
+		return output
+	}
+
+	return nil
+}
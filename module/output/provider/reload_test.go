@@ -0,0 +1,51 @@
+package provider
+
+import (
+	"context"
+	"testing"
+
+	"github.com/bytelang/kplayer/core"
+	kptypes "github.com/bytelang/kplayer/types"
+	"github.com/bytelang/kplayer/types/config"
+)
+
+func TestReloadConfig(t *testing.T) {
+	fe := core.NewFakeEngine()
+	p := NewProvider(fe)
+	fe.SetCallBackMessage(func(message *core.Message) {
+		p.ParseMessage(message.KPMessage)
+		p.Trigger(message)
+	})
+
+	resultChan := make(chan int)
+	go func() {
+		resultChan <- fe.Run()
+	}()
+	defer func() {
+		fe.Terminate(0)
+		<-resultChan
+	}()
+
+	p.InitModule(kptypes.DefaultClientContext(), &config.Output{Lists: []*config.OutputInstance{
+		{Path: "rtmp://127.0.0.1/live/a", Unique: "a"},
+		{Path: "rtmp://127.0.0.1/live/b"},
+	}})
+	unique := p.configList.outputs[1].Unique
+
+	if err := p.ReloadConfig(context.Background(), &config.Output{ReconnectInternal: 3, Lists: []*config.OutputInstance{
+		{Path: "rtmp://127.0.0.1/live/b"},
+		{Path: "rtmp://127.0.0.1/live/c", Unique: "c"},
+	}}); err != nil {
+		t.Fatal(err)
+	}
+
+	if p.reconnectInternal != 3 {
+		t.Fatalf("unexpected reconnect internal: %d", p.reconnectInternal)
+	}
+	if len(p.configList.outputs) != 2 {
+		t.Fatalf("unexpected outputs: %v", p.configList.outputs)
+	}
+	if p.configList.outputs[0].Unique != unique || p.configList.outputs[1].Unique != "c" || !p.configList.outputs[1].Connected {
+		t.Fatalf("unexpected outputs: %v", p.configList.outputs)
+	}
+}
@@ -1,13 +1,17 @@
 package play
 
 import (
+	"context"
 	"encoding/json"
+	"github.com/bytelang/kplayer/core"
 	"github.com/bytelang/kplayer/module"
 	"github.com/bytelang/kplayer/module/play/provider"
 	kptypes "github.com/bytelang/kplayer/types"
 	"github.com/bytelang/kplayer/types/config"
-	kpproto "github.com/bytelang/kplayer/types/core/proto"
+	svrproto "github.com/bytelang/kplayer/types/server"
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
 	"github.com/spf13/cobra"
+	"google.golang.org/grpc"
 )
 
 type AppModule struct {
@@ -15,9 +19,11 @@ type AppModule struct {
 }
 
 var _ module.AppModule = &AppModule{}
+var _ module.ServiceAppModule = &AppModule{}
+var _ module.ConfigAppModule = &AppModule{}
 
-func NewAppModule() AppModule {
-	return AppModule{provider.NewProvider()}
+func NewAppModule(engine core.Engine) AppModule {
+	return AppModule{provider.NewProvider(engine)}
 }
 
 func (m AppModule) GetModuleName() string {
@@ -25,7 +31,7 @@ func (m AppModule) GetModuleName() string {
 }
 
 func (m AppModule) GetCommand() *cobra.Command {
-	return provider.GetCommand()
+	return provider.GetCommand(m.Provider)
 }
 
 func (m AppModule) InitConfig(ctx *kptypes.ClientContext, data json.RawMessage) (interface{}, error) {
@@ -43,7 +49,15 @@ func (m AppModule) ValidateConfig() error {
 	return m.Provider.ValidateConfig()
 }
 
-func (m AppModule) TriggerMessage(message *kpproto.KPMessage) {
+func (m AppModule) RegisterGrpcService(s *grpc.Server) {
+	svrproto.RegisterPlayGreeterServer(s, m.Provider)
+}
+
+func (m AppModule) RegisterGatewayHandler(ctx context.Context, mux *runtime.ServeMux, grpcEndpoint string, opts []grpc.DialOption) error {
+	return svrproto.RegisterPlayGreeterHandlerFromEndpoint(ctx, mux, grpcEndpoint, opts)
+}
+
+func (m AppModule) TriggerMessage(message *core.Message) {
 	m.Trigger(message)
 }
 
@@ -8,14 +8,18 @@ import (
 	"io"
 	"io/ioutil"
 	"os"
+	"os/signal"
 	"path/filepath"
 	"strconv"
 	"strings"
 	"syscall"
 	"time"
 
+	"github.com/bytelang/kplayer/channel"
 	"github.com/bytelang/kplayer/module"
 	kptypes "github.com/bytelang/kplayer/types"
+	kpproto "github.com/bytelang/kplayer/types/core/proto"
+	"github.com/bytelang/kplayer/types/core/proto/prompt"
 	kpserver "github.com/bytelang/kplayer/types/server"
 	"github.com/sevlyar/go-daemon"
 
@@ -30,21 +34,28 @@ const (
 	coreLogFilePath = "log/core.log"
 )
 
-func GetCommand() *cobra.Command {
+// the process not exited in the stop timeout after terminated is killed
+const stopTimeout = time.Second * 15
+
+func GetCommand(p *Provider) *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   ModuleName,
 		Short: "Play category",
 		Long:  `App management commands. control kplayer basic status`,
 	}
 
-	cmd.AddCommand(startCommand())
+	cmd.AddCommand(startCommand(p))
 	cmd.AddCommand(stopCommand())
 	cmd.AddCommand(statusCommand())
 	cmd.AddCommand(durationCommand())
 	cmd.AddCommand(pauseCommand())
 	cmd.AddCommand(continueCommand())
 	cmd.AddCommand(skipCommand())
+	cmd.AddCommand(previousCommand())
+	cmd.AddCommand(jumpCommand())
 	cmd.AddCommand(versionCommand())
+	cmd.AddCommand(progressCommand())
+	cmd.AddCommand(configCommand())
 
 	return cmd
 }
@@ -53,7 +64,7 @@ func statusCommand() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "status",
 		Short: "Print kplayer status",
-		Long:  "Get the kplayer application running status",
+		Long:  "Get the kplayer application running status and the playback mode of playlist, schedule, live or interrupt",
 		RunE: func(cmd *cobra.Command, args []string) error {
 			pid, err := getPID()
 			if err != nil {
@@ -61,6 +72,29 @@ func statusCommand() *cobra.Command {
 				return nil
 			}
 			log.WithFields(log.Fields{"status": "on", "pid": pid}).Info("kplayer active running on daemon mode")
+
+			// get client ctx
+			clientCtx := kptypes.GetClientContextFromCommand(cmd)
+
+			// request
+			conn, err := client.GrpcClientRequest(clientCtx.Config.Play.Rpc)
+			if err != nil {
+				return err
+			}
+
+			playClient := kpserver.NewPlayGreeterClient(conn)
+			reply, err := playClient.PlayStatus(context.Background(), &kpserver.PlayStatusArgs{})
+			if err != nil {
+				log.Error(err)
+				return nil
+			}
+
+			yaml, err := kptypes.FormatYamlProtoMessage(reply)
+			if err != nil {
+				return err
+			}
+			fmt.Print(yaml)
+
 			return nil
 		},
 	}
@@ -203,6 +237,90 @@ func skipCommand() *cobra.Command {
 	return cmd
 }
 
+func previousCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "previous",
+		Short: "play the previous resource",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// get client ctx
+			clientCtx := kptypes.GetClientContextFromCommand(cmd)
+
+			// request
+			conn, err := client.GrpcClientRequest(clientCtx.Config.Play.Rpc)
+			if err != nil {
+				return err
+			}
+
+			playClient := kpserver.NewPlayGreeterClient(conn)
+			reply, err := playClient.PlayPrevious(context.Background(), &kpserver.PlayPreviousArgs{})
+			if err != nil {
+				log.Error(err)
+				return nil
+			}
+
+			yaml, err := kptypes.FormatYamlProtoMessage(reply)
+			if err != nil {
+				return err
+			}
+			fmt.Print(yaml)
+
+			return nil
+		},
+	}
+
+	return cmd
+}
+
+func jumpCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "jump [unique]",
+		Short: "play the resource of unique or index",
+		Long: `unique:
+    optional argument. resource unique name. the resource of index flag is played when it is empty`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// get client ctx
+			clientCtx := kptypes.GetClientContextFromCommand(cmd)
+
+			// args
+			var unique string
+			if len(args) > 0 {
+				unique = args[0]
+			} else if !cmd.Flags().Changed(flagJumpIndex) {
+				return fmt.Errorf("unique or index is required")
+			}
+			index, _ := cmd.Flags().GetInt64(flagJumpIndex)
+
+			// request
+			conn, err := client.GrpcClientRequest(clientCtx.Config.Play.Rpc)
+			if err != nil {
+				return err
+			}
+
+			playClient := kpserver.NewPlayGreeterClient(conn)
+			reply, err := playClient.PlayJump(context.Background(), &kpserver.PlayJumpArgs{
+				Index:  index,
+				Unique: unique,
+			})
+			if err != nil {
+				log.Error(err)
+				return nil
+			}
+
+			yaml, err := kptypes.FormatYamlProtoMessage(reply)
+			if err != nil {
+				return err
+			}
+			fmt.Print(yaml)
+
+			return nil
+		},
+	}
+	cmd.Flags().Int64(flagJumpIndex, 0, "index of playlist. start from 0")
+
+	return cmd
+}
+
 func versionCommand() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "info",
@@ -237,6 +355,70 @@ func versionCommand() *cobra.Command {
 	return cmd
 }
 
+func progressCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "progress",
+		Short: "get playing resource progress",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// get client ctx
+			clientCtx := kptypes.GetClientContextFromCommand(cmd)
+
+			// request
+			conn, err := client.GrpcClientRequest(clientCtx.Config.Play.Rpc)
+			if err != nil {
+				return err
+			}
+
+			playClient := kpserver.NewPlayGreeterClient(conn)
+			reply, err := playClient.PlayProgress(context.Background(), &kpserver.PlayProgressArgs{})
+			if err != nil {
+				log.Error(err)
+				return nil
+			}
+
+			yaml, err := kptypes.FormatYamlProtoMessage(reply)
+			if err != nil {
+				return err
+			}
+			fmt.Print(yaml)
+
+			return nil
+		},
+	}
+
+	return cmd
+}
+
+func configCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "config",
+		Short: "get effective config of running kplayer",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// get client ctx
+			clientCtx := kptypes.GetClientContextFromCommand(cmd)
+
+			// request
+			conn, err := client.GrpcClientRequest(clientCtx.Config.Play.Rpc)
+			if err != nil {
+				return err
+			}
+
+			playClient := kpserver.NewPlayGreeterClient(conn)
+			reply, err := playClient.PlayConfig(context.Background(), &kpserver.PlayConfigArgs{})
+			if err != nil {
+				log.Error(err)
+				return nil
+			}
+
+			fmt.Println(reply.Config)
+
+			return nil
+		},
+	}
+
+	return cmd
+}
+
 func stopCommand() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "stop",
@@ -249,11 +431,18 @@ func stopCommand() *cobra.Command {
 				return nil
 			}
 
-			// kill process
-			if err := syscall.Kill(pid, syscall.SIGKILL); err != nil {
-				log.WithField("error", err).Error("kill process failed")
+			// terminate process. the channels are stopped by the process terminated
+			if err := syscall.Kill(pid, syscall.SIGTERM); err != nil {
+				log.WithField("error", err).Error("terminate process failed")
 				return err
 			}
+			if !waitProcessExited(pid, stopTimeout) {
+				log.WithField("timeout", stopTimeout.String()).Warn("kplayer not exited on terminated. kill it")
+				if err := syscall.Kill(pid, syscall.SIGKILL); err != nil {
+					log.WithField("error", err).Error("kill process failed")
+					return err
+				}
+			}
 
 			log.Info("kplayer stop success")
 			return nil
@@ -263,7 +452,7 @@ func stopCommand() *cobra.Command {
 	return cmd
 }
 
-func startCommand() *cobra.Command {
+func startCommand(p *Provider) *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "start",
 		Short: "Start kplayer",
@@ -343,7 +532,7 @@ func startCommand() *cobra.Command {
 
 			cfg := clientCtx.Config
 
-			coreKplayer := core.GetLibKplayerInstance()
+			coreKplayer := p.engine
 			if err := coreKplayer.SetOptions(map[core.CoreKplayerOption]interface{}{
 				core.ProtocolOption:     cfg.Play.EncodeModel,
 				core.VideoWidthOption:   cfg.Play.Encode.VideoWidth,
@@ -387,6 +576,9 @@ func startCommand() *cobra.Command {
 			if cmd.Flag(FlagGenerateCache).Value.String() == FlagYesValue {
 				moduleOptions = append(moduleOptions, module.ModuleOptionGenerateCache)
 			}
+			if cmd.Flag(FlagResume).Value.String() == FlagYesValue || cfg.Play.Resume {
+				moduleOptions = append(moduleOptions, module.ModuleOptionResume)
+			}
 
 			// knock api
 			timeTicker := time.NewTicker(time.Second * (KnockIntervalMinutes * 60))
@@ -415,9 +607,29 @@ func startCommand() *cobra.Command {
 				}
 			}()
 
+			// channels
+			channelManager, err := channel.NewManager(cfg.Channels)
+			if err != nil {
+				log.Fatal(err)
+			}
+			channelManager.Start(level)
+			defer channelManager.Stop()
+
+			// stop the core on terminated, the modules and channels are stopped on the way out
+			terminated := make(chan os.Signal, 1)
+			signal.Notify(terminated, syscall.SIGTERM)
+			defer signal.Stop(terminated)
+			go func() {
+				<-terminated
+				log.Info("receive SIGTERM. stop kplayer")
+				if err := coreKplayer.SendPrompt(kpproto.EventPromptAction_EVENT_PROMPT_ACTION_PLAYER_STOP, &prompt.EventPromptPlayerStop{}); err != nil {
+					log.WithField("error", err).Warn("stop core failed")
+				}
+			}()
+
 			go func() {
 				if cfg.Play.Rpc.On {
-					(svrCreator).(kpserver.ServerCreator).StartServer(serverStopChan, mm,
+					(svrCreator).(kpserver.ServerCreator).StartServer(serverStopChan, mm, channelManager,
 						cfg.Auth.AuthOn,
 						cfg.Auth.Token,
 					)
@@ -436,17 +648,36 @@ func startCommand() *cobra.Command {
 				coreKplayer.Initialization()
 
 				// begin running
-				for _, m := range mm.Modules {
-					m.BeginRunning(moduleOptions...)
-				}
-				defer func() {
-					for _, m := range mm.Modules {
-						m.EndRunning()
+				mm.BeginRunning(moduleOptions...)
+				defer mm.EndRunning()
+
+				// reload config on changed. the generate cache mode not be reloaded
+				if cmd.Flag(FlagGenerateCache).Value.String() != FlagYesValue {
+					reloader, err := kptypes.GetCommandContext(cmd, kptypes.ConfigReloaderContextKey)
+					if err != nil {
+						log.Fatal(err)
+					}
+					configFileName, err := kptypes.GetConfigFileName(cmd)
+					if err != nil {
+						log.Fatal(err)
 					}
-				}()
 
-				// start core
-				coreKplayer.Run()
+					reloadCtx, cancelReload := context.WithCancel(context.Background())
+					defer cancelReload()
+					go reloader.(kptypes.ConfigReloader).WatchConfig(reloadCtx, configFileName)
+
+					// write the changes made through api back to config file
+					go p.PersistConfig(reloadCtx)
+				}
+
+				// start core. modules restore their state to the core restarted by watchdog
+				p.watchdog.Run(func() {
+					for _, m := range mm.GetOrderedModules() {
+						if resumeModule, ok := m.(module.ResumeAppModule); ok {
+							resumeModule.ResumeRunning()
+						}
+					}
+				})
 				serverStopChan <- true
 			}
 
@@ -456,6 +687,7 @@ func startCommand() *cobra.Command {
 
 	cmd.PersistentFlags().BoolP(FlagDaemonMode, "d", false, "use daemon mode run kplayer")
 	cmd.PersistentFlags().BoolP(FlagGenerateCache, "g", false, "only generate file cache. not push to output")
+	cmd.PersistentFlags().BoolP(FlagResume, "", false, "resume the playback position of the last running")
 
 	return cmd
 }
@@ -486,3 +718,16 @@ func getPID() (int, error) {
 
 	return pid, nil
 }
+
+// waitProcessExited wait for the process exited in the timeout. return false on timeout
+func waitProcessExited(pid int, timeout time.Duration) bool {
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		if err := syscall.Kill(pid, syscall.Signal(0)); err != nil {
+			return true
+		}
+		time.Sleep(time.Millisecond * 100)
+	}
+
+	return false
+}
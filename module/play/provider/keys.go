@@ -1,5 +1,24 @@
 package provider
 
+import (
+	kptypes "github.com/bytelang/kplayer/types"
+	svrproto "github.com/bytelang/kplayer/types/server"
+)
+
+// ConfigDumper return the effective config of running kplayer in json with secrets redacted
+type ConfigDumper func(ctx *kptypes.ClientContext) ([]byte, error)
+
+// ConfigSaver write the module state back to config files and return the written files
+type ConfigSaver func(ctx *kptypes.ClientContext) ([]string, error)
+
+// ResourceJumper select the resource played after the current resource. it is the previous resource of play model
+// when previous is true, otherwise the resource of unique or index. return whether the current resource is playing
+// and should be skipped
+type ResourceJumper func(previous bool, index int64, unique string) (res *svrproto.Resource, playing bool, err error)
+
+// ResourceStatusReporter return the playback mode of resource module and the resource playing
+type ResourceStatusReporter func() *svrproto.PlayStatusReply
+
 const (
 	// module name
 	ModuleName = "play"
@@ -10,6 +29,9 @@ const (
 	// flag keys
 	FlagDaemonMode    = "daemon"
 	FlagGenerateCache = "generate_cache"
+	FlagResume        = "resume"
 	FlagYesValue      = "true"
 	FlagNoValue       = "false"
+
+	flagJumpIndex = "index"
 )
@@ -0,0 +1,68 @@
+package provider
+
+import (
+	"context"
+	"time"
+
+	"github.com/bytelang/kplayer/eventbus"
+	kpproto "github.com/bytelang/kplayer/types/core/proto"
+	log "github.com/sirupsen/logrus"
+)
+
+// the changes made by a batch of api requests are written once
+const persistDebounce = time.Second
+
+// persistActions the core messages of module state changes written back to config file
+var persistActions = []kpproto.EventMessageAction{
+	kpproto.EventMessageAction_EVENT_MESSAGE_ACTION_RESOURCE_ADD,
+	kpproto.EventMessageAction_EVENT_MESSAGE_ACTION_RESOURCE_REMOVE,
+	kpproto.EventMessageAction_EVENT_MESSAGE_ACTION_OUTPUT_ADD,
+	kpproto.EventMessageAction_EVENT_MESSAGE_ACTION_OUTPUT_REMOVE,
+	kpproto.EventMessageAction_EVENT_MESSAGE_ACTION_PLUGIN_ADD,
+	kpproto.EventMessageAction_EVENT_MESSAGE_ACTION_PLUGIN_REMOVE,
+	kpproto.EventMessageAction_EVENT_MESSAGE_ACTION_PLUGIN_UPDATE,
+}
+
+// PersistConfig write the module state back to config files on changed until the context done.
+// it does nothing when the persist mode is off
+func (p *Provider) PersistConfig(ctx context.Context) {
+	if !p.persist || p.configSaver == nil {
+		return
+	}
+
+	sub, err := eventbus.Subscribe("play.persist",
+		eventbus.WithKinds(eventbus.KindMessage),
+		eventbus.WithActions(persistActions...),
+	)
+	if err != nil {
+		log.WithField("error", err).Error("subscribe module changes failed. config persist disabled")
+		return
+	}
+	defer sub.Close()
+
+	log.Info("config persist mode on")
+
+	var debounce <-chan time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case _, ok := <-sub.C():
+			if !ok {
+				log.Error("module changes subscription disconnected. config persist disabled")
+				return
+			}
+			debounce = time.After(persistDebounce)
+		case <-debounce:
+			debounce = nil
+			files, err := p.configSaver(p.clientCtx)
+			if err != nil {
+				log.WithField("error", err).Error("persist config failed")
+				continue
+			}
+			if len(files) != 0 {
+				log.WithField("files", files).Info("persist config success")
+			}
+		}
+	}
+}
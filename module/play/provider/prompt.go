@@ -3,7 +3,6 @@ package provider
 import (
 	"context"
 	"fmt"
-	"github.com/bytelang/kplayer/core"
 	"github.com/bytelang/kplayer/module"
 	kptypes "github.com/bytelang/kplayer/types"
 	"github.com/bytelang/kplayer/types/config"
@@ -15,11 +14,6 @@ import (
 )
 
 func (p *Provider) PlayStop(ctx context.Context, args *svrproto.PlayStopArgs) (*svrproto.PlayStopReply, error) {
-	coreKplayer := core.GetLibKplayerInstance()
-	if err := coreKplayer.SendPrompt(kpproto.EventPromptAction_EVENT_PROMPT_ACTION_PLAYER_STOP, &prompt.EventPromptPlayerStop{}); err != nil {
-		return nil, err
-	}
-
 	// register prompt
 	endedMsg := &msg.EventMessagePlayerEnded{}
 	keeperCtx := module.NewKeeperContext(kptypes.GetRandString(), kpproto.EventMessageAction_EVENT_MESSAGE_ACTION_PLAYER_STOP, func(msg string) bool {
@@ -32,8 +26,15 @@ func (p *Provider) PlayStop(ctx context.Context, args *svrproto.PlayStopArgs) (*
 		return nil, err
 	}
 
+	coreKplayer := p.engine
+	if err := coreKplayer.SendCorrelatedPrompt(keeperCtx.GetId(), kpproto.EventPromptAction_EVENT_PROMPT_ACTION_PLAYER_STOP, &prompt.EventPromptPlayerStop{}); err != nil {
+		return nil, err
+	}
+
 	// wait context
-	keeperCtx.Wait()
+	if err := keeperCtx.Wait(ctx); err != nil {
+		return nil, err
+	}
 	if len(endedMsg.Error) != 0 {
 		return nil, fmt.Errorf("%s", endedMsg.Error)
 	}
@@ -42,11 +43,6 @@ func (p *Provider) PlayStop(ctx context.Context, args *svrproto.PlayStopArgs) (*
 }
 
 func (p *Provider) PlayPause(ctx context.Context, args *svrproto.PlayPauseArgs) (*svrproto.PlayPauseReply, error) {
-	coreKplayer := core.GetLibKplayerInstance()
-	if err := coreKplayer.SendPrompt(kpproto.EventPromptAction_EVENT_PROMPT_ACTION_PLAYER_PAUSE, &prompt.EventPromptPlayerPause{}); err != nil {
-		return nil, err
-	}
-
 	// register prompt
 	pauseMsg := &msg.EventMessagePlayerPause{}
 	keeperCtx := module.NewKeeperContext(kptypes.GetRandString(), kpproto.EventMessageAction_EVENT_MESSAGE_ACTION_PLAYER_PAUSE, func(msg string) bool {
@@ -59,8 +55,15 @@ func (p *Provider) PlayPause(ctx context.Context, args *svrproto.PlayPauseArgs)
 		return nil, err
 	}
 
+	coreKplayer := p.engine
+	if err := coreKplayer.SendCorrelatedPrompt(keeperCtx.GetId(), kpproto.EventPromptAction_EVENT_PROMPT_ACTION_PLAYER_PAUSE, &prompt.EventPromptPlayerPause{}); err != nil {
+		return nil, err
+	}
+
 	// wait context
-	keeperCtx.Wait()
+	if err := keeperCtx.Wait(ctx); err != nil {
+		return nil, err
+	}
 	if len(pauseMsg.Error) != 0 {
 		return nil, fmt.Errorf("%s", pauseMsg.Error)
 	}
@@ -69,12 +72,6 @@ func (p *Provider) PlayPause(ctx context.Context, args *svrproto.PlayPauseArgs)
 }
 
 func (p *Provider) PlaySkip(ctx context.Context, args *svrproto.PlaySkipArgs) (*svrproto.PlaySkipReply, error) {
-	// send skip prompt
-	coreKplayer := core.GetLibKplayerInstance()
-	if err := coreKplayer.SendPrompt(kpproto.EventPromptAction_EVENT_PROMPT_ACTION_PLAYER_SKIP, &prompt.EventPromptPlayerSkip{}); err != nil {
-		return nil, err
-	}
-
 	// register prompt
 	skipMsg := &msg.EventMessagePlayerSkip{}
 	keeperCtx := module.NewKeeperContext(kptypes.GetRandString(), kpproto.EventMessageAction_EVENT_MESSAGE_ACTION_PLAYER_SKIP, func(msg string) bool {
@@ -87,8 +84,16 @@ func (p *Provider) PlaySkip(ctx context.Context, args *svrproto.PlaySkipArgs) (*
 		return nil, err
 	}
 
+	// send skip prompt
+	coreKplayer := p.engine
+	if err := coreKplayer.SendCorrelatedPrompt(keeperCtx.GetId(), kpproto.EventPromptAction_EVENT_PROMPT_ACTION_PLAYER_SKIP, &prompt.EventPromptPlayerSkip{}); err != nil {
+		return nil, err
+	}
+
 	// wait context
-	keeperCtx.Wait()
+	if err := keeperCtx.Wait(ctx); err != nil {
+		return nil, err
+	}
 	if len(skipMsg.Error) != 0 {
 		return nil, fmt.Errorf("%s", skipMsg.Error)
 	}
@@ -96,12 +101,56 @@ func (p *Provider) PlaySkip(ctx context.Context, args *svrproto.PlaySkipArgs) (*
 	return &svrproto.PlaySkipReply{}, nil
 }
 
-func (p *Provider) PlayContinue(ctx context.Context, args *svrproto.PlayContinueArgs) (*svrproto.PlayContinueReply, error) {
-	coreKplayer := core.GetLibKplayerInstance()
-	if err := coreKplayer.SendPrompt(kpproto.EventPromptAction_EVENT_PROMPT_ACTION_PLAYER_CONTINUE, &prompt.EventPromptPlayerContinue{}); err != nil {
+// PlayPrevious skip the current resource to the previous resource of play model
+func (p *Provider) PlayPrevious(ctx context.Context, args *svrproto.PlayPreviousArgs) (*svrproto.PlayPreviousReply, error) {
+	res, err := p.jumpResource(ctx, true, 0, "")
+	if err != nil {
+		return nil, err
+	}
+
+	return &svrproto.PlayPreviousReply{Resource: res}, nil
+}
+
+// PlayJump skip the current resource to the resource of unique or index
+func (p *Provider) PlayJump(ctx context.Context, args *svrproto.PlayJumpArgs) (*svrproto.PlayJumpReply, error) {
+	res, err := p.jumpResource(ctx, false, args.Index, args.Unique)
+	if err != nil {
+		return nil, err
+	}
+
+	return &svrproto.PlayJumpReply{Resource: res}, nil
+}
+
+func (p *Provider) jumpResource(ctx context.Context, previous bool, index int64, unique string) (*svrproto.Resource, error) {
+	if p.jumper == nil {
+		return nil, fmt.Errorf("resource jumper not set")
+	}
+
+	res, playing, err := p.jumper(previous, index, unique)
+	if err != nil {
 		return nil, err
 	}
 
+	// the selected resource is played on the current resource finished
+	if playing {
+		if _, err := p.PlaySkip(ctx, &svrproto.PlaySkipArgs{}); err != nil {
+			return nil, err
+		}
+	}
+
+	return res, nil
+}
+
+// PlayStatus return the playback mode of playlist, schedule slot, live source or interrupt, and the resource playing
+func (p *Provider) PlayStatus(ctx context.Context, args *svrproto.PlayStatusArgs) (*svrproto.PlayStatusReply, error) {
+	if p.reporter == nil {
+		return nil, fmt.Errorf("resource status reporter not set")
+	}
+
+	return p.reporter(), nil
+}
+
+func (p *Provider) PlayContinue(ctx context.Context, args *svrproto.PlayContinueArgs) (*svrproto.PlayContinueReply, error) {
 	// register prompt
 	continueMsg := &msg.EventMessagePlayerContinue{}
 	keeperCtx := module.NewKeeperContext(kptypes.GetRandString(), kpproto.EventMessageAction_EVENT_MESSAGE_ACTION_PLAYER_CONTINUE, func(msg string) bool {
@@ -114,8 +163,15 @@ func (p *Provider) PlayContinue(ctx context.Context, args *svrproto.PlayContinue
 		return nil, err
 	}
 
+	coreKplayer := p.engine
+	if err := coreKplayer.SendCorrelatedPrompt(keeperCtx.GetId(), kpproto.EventPromptAction_EVENT_PROMPT_ACTION_PLAYER_CONTINUE, &prompt.EventPromptPlayerContinue{}); err != nil {
+		return nil, err
+	}
+
 	// wait context
-	keeperCtx.Wait()
+	if err := keeperCtx.Wait(ctx); err != nil {
+		return nil, err
+	}
 	if len(continueMsg.Error) != 0 {
 		return nil, fmt.Errorf("%s", continueMsg.Error)
 	}
@@ -132,7 +188,7 @@ func (p *Provider) PlayDuration(ctx context.Context, args *svrproto.PlayDuration
 }
 
 func (p *Provider) PlayInformation(ctx context.Context, args *svrproto.PlayInformationArgs) (*svrproto.PlayInformationReply, error) {
-	coreKplayer := core.GetLibKplayerInstance()
+	coreKplayer := p.engine
 	// get core information
 	info := coreKplayer.GetInformation()
 
@@ -148,6 +204,32 @@ func (p *Provider) PlayInformation(ctx context.Context, args *svrproto.PlayInfor
 	return reply, nil
 }
 
+func (p *Provider) PlayProgress(ctx context.Context, args *svrproto.PlayProgressArgs) (*svrproto.PlayProgressReply, error) {
+	p.progressLock.RLock()
+	progress := p.progress
+	p.progressLock.RUnlock()
+
+	reply := &svrproto.PlayProgressReply{
+		ResourceUnique: progress.unique,
+		Percent:        progress.percent,
+		BitRate:        int64(progress.bitRate),
+	}
+	if !progress.startTime.IsZero() {
+		reply.ResourceStartTimestamp = uint64(progress.startTime.Unix())
+	}
+	if !progress.updateTime.IsZero() {
+		reply.UpdateTimestamp = uint64(progress.updateTime.Unix())
+	}
+
+	// estimate by the elapsed time of the playing resource
+	if progress.percent > 0 && progress.percent < 100 && !progress.updateTime.IsZero() {
+		elapsed := progress.updateTime.Sub(progress.startTime).Seconds()
+		reply.EstimatedRemaining = uint64(elapsed / progress.percent * (100 - progress.percent))
+	}
+
+	return reply, nil
+}
+
 func (p *Provider) GetRPCParams() config.Server {
 	return p.rpc
 }
@@ -157,11 +239,6 @@ func (p *Provider) GetCacheOn() bool {
 }
 
 func (p *Provider) PlayGetEncodeConfig(ctx context.Context, args *svrproto.PlayEncodeConfigArgs) (*svrproto.PlayEncodeConfigReplay, error) {
-	coreKplayer := core.GetLibKplayerInstance()
-	if err := coreKplayer.SendPrompt(kpproto.EventPromptAction_EVENT_PROMPT_ACTION_PLAYER_OUTPUT_OPTION, &prompt.EventPromptPlayerOutputOption{}); err != nil {
-		return nil, err
-	}
-
 	// register prompt
 	outputOptionMsg := &msg.EventMessagePlayerOutputOption{}
 	keeperCtx := module.NewKeeperContext(kptypes.GetRandString(), kpproto.EventMessageAction_EVENT_MESSAGE_ACTION_PLAYER_OUTPUT_OPTION, func(msg string) bool {
@@ -174,8 +251,15 @@ func (p *Provider) PlayGetEncodeConfig(ctx context.Context, args *svrproto.PlayE
 		return nil, err
 	}
 
+	coreKplayer := p.engine
+	if err := coreKplayer.SendCorrelatedPrompt(keeperCtx.GetId(), kpproto.EventPromptAction_EVENT_PROMPT_ACTION_PLAYER_OUTPUT_OPTION, &prompt.EventPromptPlayerOutputOption{}); err != nil {
+		return nil, err
+	}
+
 	// wait context
-	keeperCtx.Wait()
+	if err := keeperCtx.Wait(ctx); err != nil {
+		return nil, err
+	}
 	if len(outputOptionMsg.Error) != 0 {
 		return nil, fmt.Errorf("%s", outputOptionMsg.Error)
 	}
@@ -193,11 +277,6 @@ func (p *Provider) PlayGetEncodeConfig(ctx context.Context, args *svrproto.PlayE
 }
 
 func (p *Provider) PlayEncodeSetAvgQuality(ctx context.Context, args *svrproto.PlayEncodeSetAvgQualityArgs) (*svrproto.PlayEncodeSetAvgQualityReplay, error) {
-	coreKplayer := core.GetLibKplayerInstance()
-	if err := coreKplayer.SendPrompt(kpproto.EventPromptAction_EVENT_PROMPT_ACTION_PLAYER_SET_QUALITY, &prompt.EventPromptPlayerSetQuality{Quality: args.AvgQuality}); err != nil {
-		return nil, err
-	}
-
 	// register prompt
 	setQualityMsg := &msg.EventMessagePlayerSetQuality{}
 	keeperCtx := module.NewKeeperContext(kptypes.GetRandString(), kpproto.EventMessageAction_EVENT_MESSAGE_ACTION_PLAYER_SET_QUALITY, func(msg string) bool {
@@ -210,12 +289,59 @@ func (p *Provider) PlayEncodeSetAvgQuality(ctx context.Context, args *svrproto.P
 		return nil, err
 	}
 
+	coreKplayer := p.engine
+	if err := coreKplayer.SendCorrelatedPrompt(keeperCtx.GetId(), kpproto.EventPromptAction_EVENT_PROMPT_ACTION_PLAYER_SET_QUALITY, &prompt.EventPromptPlayerSetQuality{Quality: args.AvgQuality}); err != nil {
+		return nil, err
+	}
+
 	// wait context
-	keeperCtx.Wait()
+	if err := keeperCtx.Wait(ctx); err != nil {
+		return nil, err
+	}
 	if len(setQualityMsg.Error) != 0 {
 		return nil, fmt.Errorf("%s", setQualityMsg.Error)
 	}
 
+	p.configLock.Lock()
+	if p.config.Encode != nil {
+		encode := *p.config.Encode
+		encode.AvgQuality = uint32(args.AvgQuality)
+		p.config.Encode = &encode
+	}
+	p.configLock.Unlock()
+
 	reply := &svrproto.PlayEncodeSetAvgQualityReplay{}
 	return reply, nil
 }
+
+// PlayConfig return the effective config of running kplayer, including the changes made through the api
+func (p *Provider) PlayConfig(ctx context.Context, args *svrproto.PlayConfigArgs) (*svrproto.PlayConfigReply, error) {
+	if p.configDumper == nil || p.clientCtx == nil {
+		return nil, fmt.Errorf("config dump is not available")
+	}
+
+	d, err := p.configDumper(p.clientCtx)
+	if err != nil {
+		return nil, err
+	}
+
+	return &svrproto.PlayConfigReply{
+		Config: string(d),
+	}, nil
+}
+
+// PlaySaveConfig write the resources, outputs and plugins of running kplayer back to config files
+func (p *Provider) PlaySaveConfig(ctx context.Context, args *svrproto.PlaySaveConfigArgs) (*svrproto.PlaySaveConfigReply, error) {
+	if p.configSaver == nil || p.clientCtx == nil {
+		return nil, fmt.Errorf("config save is not available")
+	}
+
+	files, err := p.configSaver(p.clientCtx)
+	if err != nil {
+		return nil, err
+	}
+
+	return &svrproto.PlaySaveConfigReply{
+		Files: files,
+	}, nil
+}
@@ -0,0 +1,151 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/bytelang/kplayer/core"
+	kptypes "github.com/bytelang/kplayer/types"
+	"github.com/bytelang/kplayer/types/config"
+	kpproto "github.com/bytelang/kplayer/types/core/proto"
+	kpprompt "github.com/bytelang/kplayer/types/core/proto/prompt"
+	svrproto "github.com/bytelang/kplayer/types/server"
+)
+
+func newTestProvider(t *testing.T) (*Provider, *core.FakeEngine) {
+	fe := core.NewFakeEngine()
+	p := NewProvider(fe)
+
+	fe.SetCallBackMessage(func(message *core.Message) {
+		p.ParseMessage(message.KPMessage)
+		p.Trigger(message)
+	})
+	fe.SetCallBackProgress(p.ParseProgress)
+
+	resultChan := make(chan int)
+	go func() {
+		resultChan <- fe.Run()
+	}()
+	t.Cleanup(func() {
+		fe.Terminate(0)
+		<-resultChan
+	})
+
+	return p, fe
+}
+
+func TestPlayPauseAndContinue(t *testing.T) {
+	p, _ := newTestProvider(t)
+
+	if _, err := p.PlayPause(context.Background(), &svrproto.PlayPauseArgs{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.PlayPause(context.Background(), &svrproto.PlayPauseArgs{}); err == nil {
+		t.Fatal("pause a paused player should be failed")
+	}
+
+	if _, err := p.PlayContinue(context.Background(), &svrproto.PlayContinueArgs{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.PlayContinue(context.Background(), &svrproto.PlayContinueArgs{}); err == nil {
+		t.Fatal("continue a playing player should be failed")
+	}
+}
+
+func TestPlayInformation(t *testing.T) {
+	p, _ := newTestProvider(t)
+
+	reply, err := p.PlayInformation(context.Background(), &svrproto.PlayInformationArgs{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reply.LibkplayerVersion != core.FakeMajorVersion {
+		t.Fatalf("unexpected libkplayer version: %s", reply.LibkplayerVersion)
+	}
+}
+
+func TestPlayStop(t *testing.T) {
+	p, _ := newTestProvider(t)
+
+	if _, err := p.PlayStop(context.Background(), &svrproto.PlayStopArgs{}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPlayProgress(t *testing.T) {
+	p, fe := newTestProvider(t)
+	fe.SetResourceDuration("short.flv", time.Second*100)
+
+	if err := fe.SendPrompt(kpproto.EventPromptAction_EVENT_PROMPT_ACTION_RESOURCE_ADD, &kpprompt.EventPromptResourceAdd{Resource: &kpproto.PromptResource{
+		Path:   "short.flv",
+		Unique: "test",
+	}}); err != nil {
+		t.Fatal(err)
+	}
+
+	// wait for resource start
+	timeout := time.After(time.Second * 5)
+	for {
+		reply, err := p.PlayProgress(context.Background(), &svrproto.PlayProgressArgs{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if reply.ResourceUnique == "test" {
+			break
+		}
+
+		select {
+		case <-timeout:
+			t.Fatal("wait for resource start timeout")
+		case <-time.After(time.Millisecond):
+		}
+	}
+
+	fe.Advance(time.Second * 25)
+	reply, err := p.PlayProgress(context.Background(), &svrproto.PlayProgressArgs{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reply.Percent != 25 {
+		t.Fatalf("unexpected progress percent: %f", reply.Percent)
+	}
+	if reply.UpdateTimestamp == 0 {
+		t.Fatal("progress update timestamp should be set")
+	}
+}
+
+func TestPlayConfig(t *testing.T) {
+	p, _ := newTestProvider(t)
+
+	if _, err := p.PlayConfig(context.Background(), &svrproto.PlayConfigArgs{}); err == nil {
+		t.Fatal("play config without dumper should be failed")
+	}
+
+	p.InitModule(kptypes.DefaultClientContext(), &config.Play{
+		StartPoint: 1,
+		PlayModel:  "list",
+		Rpc:        &config.Server{},
+		Encode:     &config.Encode{AvgQuality: 0},
+	})
+	p.SetConfigDumper(func(ctx *kptypes.ClientContext) ([]byte, error) {
+		return json.Marshal(p.GetEffectiveConfig())
+	})
+
+	if _, err := p.PlayEncodeSetAvgQuality(context.Background(), &svrproto.PlayEncodeSetAvgQualityArgs{AvgQuality: 10}); err != nil {
+		t.Fatal(err)
+	}
+	reply, err := p.PlayConfig(context.Background(), &svrproto.PlayConfigArgs{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var cfg config.Play
+	if err := json.Unmarshal([]byte(reply.Config), &cfg); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Encode == nil || cfg.Encode.AvgQuality != 10 {
+		t.Fatalf("unexpected effective config: %s", reply.Config)
+	}
+}
@@ -2,13 +2,16 @@ package provider
 
 import (
 	"context"
+	"github.com/bytelang/kplayer/core"
 	"github.com/bytelang/kplayer/module"
 	kptypes "github.com/bytelang/kplayer/types"
 	"github.com/bytelang/kplayer/types/config"
 	kpproto "github.com/bytelang/kplayer/types/core/proto"
+	"github.com/bytelang/kplayer/types/core/proto/msg"
 	svrproto "github.com/bytelang/kplayer/types/server"
 	log "github.com/sirupsen/logrus"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -20,9 +23,13 @@ type ProviderI interface {
 	PlayStop(ctx context.Context, args *svrproto.PlayStopArgs) (*svrproto.PlayStopReply, error)
 	PlayPause(ctx context.Context, args *svrproto.PlayPauseArgs) (*svrproto.PlayPauseReply, error)
 	PlaySkip(ctx context.Context, args *svrproto.PlaySkipArgs) (*svrproto.PlaySkipReply, error)
+	PlayPrevious(ctx context.Context, args *svrproto.PlayPreviousArgs) (*svrproto.PlayPreviousReply, error)
+	PlayJump(ctx context.Context, args *svrproto.PlayJumpArgs) (*svrproto.PlayJumpReply, error)
+	PlayStatus(ctx context.Context, args *svrproto.PlayStatusArgs) (*svrproto.PlayStatusReply, error)
 	PlayContinue(ctx context.Context, args *svrproto.PlayContinueArgs) (*svrproto.PlayContinueReply, error)
 	PlayDuration(ctx context.Context, args *svrproto.PlayDurationArgs) (*svrproto.PlayDurationReply, error)
 	PlayInformation(ctx context.Context, args *svrproto.PlayInformationArgs) (*svrproto.PlayInformationReply, error)
+	PlayProgress(ctx context.Context, args *svrproto.PlayProgressArgs) (*svrproto.PlayProgressReply, error)
 }
 
 var _ ProviderI = &Provider{}
@@ -32,19 +39,53 @@ type Provider struct {
 	module.ModuleKeeper
 	svrproto.UnimplementedPlayGreeterServer
 
+	// core engine
+	engine core.Engine
+
 	// config
 	startPoint uint32
 	playMode   config.PLAY_MODEL
 	rpc        config.Server
 	cacheOn    bool
 
+	// effective config
+	clientCtx    *kptypes.ClientContext
+	config       config.Play
+	configLock   sync.Mutex
+	configDumper ConfigDumper
+	configSaver  ConfigSaver
+	jumper       ResourceJumper
+	reporter     ResourceStatusReporter
+	persist      bool
+
 	// module member
-	startTime time.Time
+	startTime    time.Time
+	progress     playProgress
+	progressLock sync.RWMutex
+	watchdog     *watchdog
+}
+
+// playProgress the latest progress reported by core of the playing resource
+type playProgress struct {
+	unique     string
+	playing    bool
+	paused     bool
+	percent    float64
+	bitRate    int
+	startTime  time.Time
+	updateTime time.Time
+	resumeTime time.Time
 }
 
 // NewProvider return provider
-func NewProvider() *Provider {
-	return &Provider{}
+func NewProvider(engine core.Engine) *Provider {
+	p := &Provider{
+		engine: engine,
+	}
+	p.watchdog = newWatchdog(p, nil)
+	p.SetKeeperEngine(engine)
+
+	return p
 }
 
 // InitConfig set module config on kplayer started
@@ -58,17 +99,131 @@ func (p *Provider) InitModule(ctx *kptypes.ClientContext, cfg *config.Play) {
 	p.playMode = config.PLAY_MODEL(playModel)
 
 	p.rpc = *cfg.Rpc
+	module.SetKeeperTimeout(time.Duration(cfg.Rpc.Timeout) * time.Second)
 	p.cacheOn = cfg.CacheOn
+	p.watchdog = newWatchdog(p, cfg.Watchdog)
+
+	p.clientCtx = ctx
+	p.persist = cfg.Persist
+	p.configLock.Lock()
+	p.config = *cfg
+	p.configLock.Unlock()
+}
+
+// SetConfigDumper set the dumper of PlayConfig
+func (p *Provider) SetConfigDumper(dumper ConfigDumper) {
+	p.configDumper = dumper
+}
+
+// SetConfigSaver set the saver of PlaySaveConfig and persist mode
+func (p *Provider) SetConfigSaver(saver ConfigSaver) {
+	p.configSaver = saver
+}
+
+// SetResourceJumper set the jumper of PlayPrevious and PlayJump
+func (p *Provider) SetResourceJumper(jumper ResourceJumper) {
+	p.jumper = jumper
+}
+
+// SetResourceStatusReporter set the reporter of PlayStatus
+func (p *Provider) SetResourceStatusReporter(reporter ResourceStatusReporter) {
+	p.reporter = reporter
+}
+
+// GetEffectiveConfig return the play config with the encode changes made on running
+func (p *Provider) GetEffectiveConfig() interface{} {
+	p.configLock.Lock()
+	defer p.configLock.Unlock()
+
+	return p.config
 }
 
 func (p *Provider) ParseMessage(message *kpproto.KPMessage) {
 	switch message.Action {
 	case kpproto.EventMessageAction_EVENT_MESSAGE_ACTION_PLAYER_STARTED:
 		log.Info("kplayer start success")
-		p.startTime = time.Now()
+		// keep the start time across the core restarted by watchdog
+		if p.startTime.IsZero() {
+			p.startTime = time.Now()
+		}
+
+		p.resetProgress()
+	case kpproto.EventMessageAction_EVENT_MESSAGE_ACTION_RESOURCE_START:
+		startMsg := &msg.EventMessageResourceStart{}
+		kptypes.UnmarshalProtoMessage(message.Body, startMsg)
+
+		p.progressLock.Lock()
+		p.progress = playProgress{
+			unique:    startMsg.Resource.Unique,
+			playing:   true,
+			paused:    p.progress.paused,
+			startTime: time.Now(),
+		}
+		p.progressLock.Unlock()
+	case kpproto.EventMessageAction_EVENT_MESSAGE_ACTION_RESOURCE_FINISH:
+		p.progressLock.Lock()
+		p.progress.playing = false
+		p.progressLock.Unlock()
+	case kpproto.EventMessageAction_EVENT_MESSAGE_ACTION_PLAYER_PAUSE:
+		pauseMsg := &msg.EventMessagePlayerPause{}
+		kptypes.UnmarshalProtoMessage(message.Body, pauseMsg)
+		if len(pauseMsg.Error) != 0 {
+			break
+		}
+
+		p.progressLock.Lock()
+		p.progress.paused = true
+		p.progressLock.Unlock()
+	case kpproto.EventMessageAction_EVENT_MESSAGE_ACTION_PLAYER_CONTINUE:
+		continueMsg := &msg.EventMessagePlayerContinue{}
+		kptypes.UnmarshalProtoMessage(message.Body, continueMsg)
+		if len(continueMsg.Error) != 0 {
+			break
+		}
+
+		p.progressLock.Lock()
+		p.progress.paused = false
+		p.progress.resumeTime = time.Now()
+		p.progressLock.Unlock()
 	}
 }
 
+// ParseProgress record the progress of the playing resource and the encoder bit rate
+func (p *Provider) ParseProgress(percent float64, bitRate int) {
+	p.progressLock.Lock()
+	defer p.progressLock.Unlock()
+
+	p.progress.percent = percent
+	p.progress.bitRate = bitRate
+	p.progress.updateTime = time.Now()
+}
+
+func (p *Provider) resetProgress() {
+	p.progressLock.Lock()
+	defer p.progressLock.Unlock()
+
+	p.progress = playProgress{}
+}
+
+// progressStalled whether the playing resource has no progress over the timeout
+func (p *Provider) progressStalled(timeout time.Duration) bool {
+	p.progressLock.RLock()
+	defer p.progressLock.RUnlock()
+
+	if !p.progress.playing || p.progress.paused {
+		return false
+	}
+
+	last := p.progress.startTime
+	for _, item := range []time.Time{p.progress.updateTime, p.progress.resumeTime} {
+		if item.After(last) {
+			last = item
+		}
+	}
+
+	return time.Since(last) > timeout
+}
+
 func (p *Provider) ValidateConfig() error {
 	return nil
 }
@@ -0,0 +1,157 @@
+package provider
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/bytelang/kplayer/eventbus"
+	"github.com/bytelang/kplayer/types/config"
+	kpproto "github.com/bytelang/kplayer/types/core/proto"
+	"github.com/bytelang/kplayer/types/core/proto/prompt"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	watchdogMinBackoff = time.Second
+
+	// a core running longer than the stable period refresh the restart attempts
+	watchdogStablePeriod = time.Minute * 10
+)
+
+const (
+	WatchdogEventRestart = "core_restart"
+	WatchdogEventAbandon = "core_restart_abandon"
+)
+
+const (
+	watchdogReasonTerminated = "abnormal termination"
+	watchdogReasonStalled    = "stalled progress"
+)
+
+// watchdog run the core, restart it on abnormal termination or stalled progress
+type watchdog struct {
+	provider *Provider
+
+	maxRestarts  int
+	stallTimeout time.Duration
+	minBackoff   time.Duration
+	maxBackoff   time.Duration
+}
+
+// newWatchdog return watchdog. the core runs only once when the watchdog config is off
+func newWatchdog(p *Provider, cfg *config.Watchdog) *watchdog {
+	w := &watchdog{
+		provider:   p,
+		minBackoff: watchdogMinBackoff,
+	}
+	if cfg == nil || !cfg.On {
+		return w
+	}
+
+	w.maxRestarts = int(cfg.MaxRestarts)
+	w.stallTimeout = time.Duration(cfg.StallTimeout) * time.Second
+	w.maxBackoff = time.Duration(cfg.MaxBackoff) * time.Second
+
+	return w
+}
+
+// Run block until the core stopped normally or the restart attempts exceed the limit.
+// resume is called on every restart after the core initialized, the modules restore their state by it
+func (w *watchdog) Run(resume func()) int {
+	attempt := 0
+	for {
+		begin := time.Now()
+		resultCode, reason := w.runOnce()
+		if len(reason) == 0 {
+			return resultCode
+		}
+
+		if time.Since(begin) >= watchdogStablePeriod {
+			attempt = 0
+		}
+
+		logFields := log.WithFields(log.Fields{"code": resultCode, "reason": reason, "attempt": attempt})
+		if attempt >= w.maxRestarts {
+			logFields.Error("core restart abandoned")
+			eventbus.PublishEvent(&eventbus.Event{
+				Module: ModuleName,
+				Name:   WatchdogEventAbandon,
+				Body:   map[string]interface{}{"code": resultCode, "reason": reason, "attempts": attempt},
+			})
+			return resultCode
+		}
+
+		attempt = attempt + 1
+		delay := w.backoff(attempt)
+		logFields.WithField("delay", delay.String()).Warn("core will be restarted")
+		eventbus.PublishEvent(&eventbus.Event{
+			Module: ModuleName,
+			Name:   WatchdogEventRestart,
+			Body:   map[string]interface{}{"code": resultCode, "reason": reason, "attempt": attempt, "delay": delay.Seconds()},
+		})
+		time.Sleep(delay)
+
+		w.provider.engine.Initialization()
+		resume()
+	}
+}
+
+// runOnce run the core, return the result code and the restart reason. empty reason means stopped normally
+func (w *watchdog) runOnce() (int, string) {
+	w.provider.resetProgress()
+
+	var stalled int32
+	done := make(chan bool)
+	if w.stallTimeout > 0 {
+		go w.monitor(done, &stalled)
+	}
+
+	resultCode := w.provider.engine.Run()
+	close(done)
+
+	if atomic.LoadInt32(&stalled) == 1 {
+		return resultCode, watchdogReasonStalled
+	}
+	if resultCode < 0 {
+		return resultCode, watchdogReasonTerminated
+	}
+
+	return resultCode, ""
+}
+
+// monitor stop the core once the playing resource has no progress over the stall timeout
+func (w *watchdog) monitor(done chan bool, stalled *int32) {
+	ticker := time.NewTicker(w.stallTimeout / 2)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			if !w.provider.progressStalled(w.stallTimeout) {
+				continue
+			}
+
+			log.WithField("timeout", w.stallTimeout.String()).Warn("core progress stalled. stop core")
+			atomic.StoreInt32(stalled, 1)
+			if err := w.provider.engine.SendPrompt(kpproto.EventPromptAction_EVENT_PROMPT_ACTION_PLAYER_STOP, &prompt.EventPromptPlayerStop{}); err != nil {
+				log.Warn(err)
+			}
+			return
+		}
+	}
+}
+
+// backoff return the delay before the restart attempt, doubled on every attempt
+func (w *watchdog) backoff(attempt int) time.Duration {
+	delay := w.minBackoff
+	for i := 1; i < attempt; i++ {
+		delay = delay * 2
+		if w.maxBackoff > 0 && delay >= w.maxBackoff {
+			return w.maxBackoff
+		}
+	}
+
+	return delay
+}
@@ -0,0 +1,128 @@
+package provider
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bytelang/kplayer/core"
+	"github.com/bytelang/kplayer/eventbus"
+	"github.com/bytelang/kplayer/types/config"
+	kpproto "github.com/bytelang/kplayer/types/core/proto"
+	kpprompt "github.com/bytelang/kplayer/types/core/proto/prompt"
+)
+
+func newTestWatchdog(t *testing.T, maxRestarts uint32) (*Provider, *core.FakeEngine, <-chan eventbus.Envelope) {
+	fe := core.NewFakeEngine()
+	p := NewProvider(fe)
+	fe.SetCallBackMessage(func(message *core.Message) {
+		p.ParseMessage(message.KPMessage)
+		p.Trigger(message)
+	})
+	fe.SetCallBackProgress(p.ParseProgress)
+
+	p.watchdog = newWatchdog(p, &config.Watchdog{On: true, MaxRestarts: maxRestarts})
+	p.watchdog.minBackoff = time.Millisecond
+
+	sub, err := eventbus.Subscribe(t.Name(), eventbus.WithKinds(eventbus.KindEvent))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(sub.Close)
+
+	return p, fe, sub.C()
+}
+
+func runTestWatchdog(p *Provider) (chan int, chan bool) {
+	resumed := make(chan bool, 10)
+	resultChan := make(chan int)
+	go func() {
+		resultChan <- p.watchdog.Run(func() {
+			resumed <- true
+		})
+	}()
+
+	return resultChan, resumed
+}
+
+func waitResumed(t *testing.T, resumed chan bool) {
+	select {
+	case <-resumed:
+	case <-time.After(time.Second * 5):
+		t.Fatal("wait for core restarted timeout")
+	}
+}
+
+func TestWatchdogRestart(t *testing.T) {
+	p, fe, events := newTestWatchdog(t, 2)
+	resultChan, resumed := runTestWatchdog(p)
+
+	fe.Terminate(-1)
+	waitResumed(t, resumed)
+	fe.Terminate(-1)
+	waitResumed(t, resumed)
+
+	// restart attempts exceed the limit
+	fe.Terminate(-1)
+	if result := <-resultChan; result != -1 {
+		t.Fatalf("unexpected result code: %d", result)
+	}
+	if len(resumed) != 0 {
+		t.Fatal("core should not be restarted over the limit")
+	}
+
+	for _, name := range []string{WatchdogEventRestart, WatchdogEventRestart, WatchdogEventAbandon} {
+		event := (<-events).Event
+		if event.Module != ModuleName || event.Name != name {
+			t.Fatalf("unexpected event: %s.%s", event.Module, event.Name)
+		}
+	}
+}
+
+func TestWatchdogNormalStop(t *testing.T) {
+	p, fe, _ := newTestWatchdog(t, 2)
+	resultChan, resumed := runTestWatchdog(p)
+
+	fe.Terminate(0)
+	if result := <-resultChan; result != 0 {
+		t.Fatalf("unexpected result code: %d", result)
+	}
+	if len(resumed) != 0 {
+		t.Fatal("core stopped normally should not be restarted")
+	}
+}
+
+func TestWatchdogStalled(t *testing.T) {
+	p, fe, events := newTestWatchdog(t, 1)
+	p.watchdog.stallTimeout = time.Millisecond * 20
+	resultChan, resumed := runTestWatchdog(p)
+
+	// the clock never advance, the playing resource has no progress
+	if err := fe.SendPrompt(kpproto.EventPromptAction_EVENT_PROMPT_ACTION_RESOURCE_ADD, &kpprompt.EventPromptResourceAdd{Resource: &kpproto.PromptResource{
+		Path:   "short.flv",
+		Unique: "test",
+	}}); err != nil {
+		t.Fatal(err)
+	}
+	waitResumed(t, resumed)
+
+	event := (<-events).Event
+	if event.Name != WatchdogEventRestart || event.Body["reason"] != watchdogReasonStalled {
+		t.Fatalf("unexpected event: %s %v", event.Name, event.Body)
+	}
+
+	fe.Terminate(0)
+	if result := <-resultChan; result != 0 {
+		t.Fatalf("unexpected result code: %d", result)
+	}
+}
+
+func TestWatchdogBackoff(t *testing.T) {
+	w := newWatchdog(&Provider{}, &config.Watchdog{On: true, MaxRestarts: 10, MaxBackoff: 5})
+
+	expected := []time.Duration{time.Second, time.Second * 2, time.Second * 4, time.Second * 5, time.Second * 5}
+	for key, item := range expected {
+		if delay := w.backoff(key + 1); delay != item {
+			t.Fatalf("unexpected backoff of attempt %d: %s", key+1, delay)
+		}
+	}
+}
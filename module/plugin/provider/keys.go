@@ -97,14 +97,14 @@ func GetPluginPath(name string) string {
 	return path.Join("plugin", name+PluginExtensionName)
 }
 
-func InitPluginFile(name string, filePath string) error {
+func InitPluginFile(name string, filePath string, pluginVersion string) error {
 	logField := log.WithFields(log.Fields{"name": name, "path": filePath})
 
 	// download file
 	logField.Debug("get plugin file config")
 	resp, err := kptypes.GetPlugin(&api.PluginInformationRequest{
 		Name:    name,
-		Version: kptypes.GetCorePluginVersion(),
+		Version: pluginVersion,
 	})
 	if err != nil {
 		return err
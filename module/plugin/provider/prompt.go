@@ -3,7 +3,6 @@ package provider
 import (
 	"context"
 	"fmt"
-	"github.com/bytelang/kplayer/core"
 	"github.com/bytelang/kplayer/module"
 	kptypes "github.com/bytelang/kplayer/types"
 	kpproto "github.com/bytelang/kplayer/types/core/proto"
@@ -24,7 +23,7 @@ func (p *Provider) PluginAdd(ctx context.Context, args *svrproto.PluginAddArgs)
 		return nil, fmt.Errorf("plugin path cannot be empty")
 	}
 	logField := log.WithFields(log.Fields{"name": pluginName, "path": args.Path})
-	if err := InitPluginFile(pluginName, GetPluginPath(args.Path)); err != nil {
+	if err := InitPluginFile(pluginName, GetPluginPath(args.Path), kptypes.GetCorePluginVersion(p.engine)); err != nil {
 		if _, ok := err.(kptypes.ApiError); ok {
 			logField.Error("plugin request information failed")
 			return nil, err
@@ -39,7 +38,7 @@ func (p *Provider) PluginAdd(ctx context.Context, args *svrproto.PluginAddArgs)
 	}
 
 	// add plugin prompt
-	if err := p.addPlugin(moduletypes.Plugin{
+	if err := p.addPlugin(ctx, moduletypes.Plugin{
 		Path:       GetPluginPath(args.Path),
 		Unique:     args.Unique,
 		CreateTime: uint64(time.Now().Unix()),
@@ -70,14 +69,6 @@ func (p *Provider) PluginRemove(ctx context.Context, args *svrproto.PluginRemove
 		return nil, PluginUniqueNotFound
 	}
 
-	// send prompt
-	coreKplayer := core.GetLibKplayerInstance()
-	if err := coreKplayer.SendPrompt(kpproto.EventPromptAction_EVENT_PROMPT_ACTION_PLUGIN_REMOVE, &kpprompt.EventPromptPluginRemove{
-		Unique: args.Unique,
-	}); err != nil {
-		return nil, err
-	}
-
 	pluginRemoveMsg := &msg.EventMessagePluginRemove{}
 	keeperCtx := module.NewKeeperContext(kptypes.GetRandString(), kpproto.EventMessageAction_EVENT_MESSAGE_ACTION_PLUGIN_REMOVE, func(msg string) bool {
 		kptypes.UnmarshalProtoMessage(msg, pluginRemoveMsg)
@@ -89,8 +80,18 @@ func (p *Provider) PluginRemove(ctx context.Context, args *svrproto.PluginRemove
 		return nil, err
 	}
 
+	// send prompt
+	coreKplayer := p.engine
+	if err := coreKplayer.SendCorrelatedPrompt(keeperCtx.GetId(), kpproto.EventPromptAction_EVENT_PROMPT_ACTION_PLUGIN_REMOVE, &kpprompt.EventPromptPluginRemove{
+		Unique: args.Unique,
+	}); err != nil {
+		return nil, err
+	}
+
 	// wait context
-	keeperCtx.Wait()
+	if err := keeperCtx.Wait(ctx); err != nil {
+		return nil, err
+	}
 	if len(pluginRemoveMsg.Error) != 0 {
 		return nil, fmt.Errorf("%s", pluginRemoveMsg.Error)
 	}
@@ -122,11 +123,6 @@ func (p *Provider) PluginList(ctx context.Context, args *svrproto.PluginListArgs
 }
 
 func (p *Provider) PluginListFromCore(ctx context.Context, args *svrproto.PluginListArgs) (*svrproto.PluginListReply, error) {
-	coreKplayer := core.GetLibKplayerInstance()
-	if err := coreKplayer.SendPrompt(kpproto.EventPromptAction_EVENT_PROMPT_ACTION_PLUGIN_LIST, &kpprompt.EventPromptPluginList{}); err != nil {
-		return nil, err
-	}
-
 	pluginListMsg := &msg.EventMessagePluginList{}
 	keeperCtx := module.NewKeeperContext(kptypes.GetRandString(), kpproto.EventMessageAction_EVENT_MESSAGE_ACTION_PLUGIN_LIST, func(msg string) bool {
 		kptypes.UnmarshalProtoMessage(msg, pluginListMsg)
@@ -138,8 +134,15 @@ func (p *Provider) PluginListFromCore(ctx context.Context, args *svrproto.Plugin
 		return nil, err
 	}
 
+	coreKplayer := p.engine
+	if err := coreKplayer.SendCorrelatedPrompt(keeperCtx.GetId(), kpproto.EventPromptAction_EVENT_PROMPT_ACTION_PLUGIN_LIST, &kpprompt.EventPromptPluginList{}); err != nil {
+		return nil, err
+	}
+
 	// wait context
-	keeperCtx.Wait()
+	if err := keeperCtx.Wait(ctx); err != nil {
+		return nil, err
+	}
 	if len(pluginListMsg.Error) != 0 {
 		return nil, fmt.Errorf("%s", pluginListMsg.Error)
 	}
@@ -167,33 +170,35 @@ func (p *Provider) PluginUpdate(ctx context.Context, args *svrproto.PluginUpdate
 		return nil, PluginUniqueNotFound
 	}
 
+	pluginUpdateMsg := &msg.EventMessagePluginUpdate{}
+	keeperCtx := module.NewKeeperContext(kptypes.GetRandString(), kpproto.EventMessageAction_EVENT_MESSAGE_ACTION_PLUGIN_UPDATE, func(msg string) bool {
+		kptypes.UnmarshalProtoMessage(msg, pluginUpdateMsg)
+		return true
+	})
+	defer keeperCtx.Close()
+
+	if err := p.RegisterKeeperChannel(keeperCtx); err != nil {
+		return nil, err
+	}
+
 	// send prompt
-	coreKplayer := core.GetLibKplayerInstance()
+	coreKplayer := p.engine
 
 	argParams := map[string]string{}
 	for k, v := range args.Params {
 		argParams[k] = v
 	}
-	if err := coreKplayer.SendPrompt(kpproto.EventPromptAction_EVENT_PROMPT_ACTION_PLUGIN_UPDATE, &kpprompt.EventPromptPluginUpdate{
+	if err := coreKplayer.SendCorrelatedPrompt(keeperCtx.GetId(), kpproto.EventPromptAction_EVENT_PROMPT_ACTION_PLUGIN_UPDATE, &kpprompt.EventPromptPluginUpdate{
 		Unique: args.Unique,
 		Params: argParams,
 	}); err != nil {
 		return nil, err
 	}
 
-	pluginUpdateMsg := &msg.EventMessagePluginUpdate{}
-	keeperCtx := module.NewKeeperContext(kptypes.GetRandString(), kpproto.EventMessageAction_EVENT_MESSAGE_ACTION_PLUGIN_UPDATE, func(msg string) bool {
-		kptypes.UnmarshalProtoMessage(msg, pluginUpdateMsg)
-		return true
-	})
-	defer keeperCtx.Close()
-
-	if err := p.RegisterKeeperChannel(keeperCtx); err != nil {
+	// wait context
+	if err := keeperCtx.Wait(ctx); err != nil {
 		return nil, err
 	}
-
-	// wait context
-	keeperCtx.Wait()
 	if len(pluginUpdateMsg.Error) != 0 {
 		return nil, fmt.Errorf("%s", pluginUpdateMsg.Error)
 	}
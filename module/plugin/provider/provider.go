@@ -29,14 +29,22 @@ type Provider struct {
 	module.ModuleKeeper
 	svrproto.UnimplementedPluginGreeterServer
 
+	// core engine
+	engine core.Engine
+
 	// config
 	list Plugins
 }
 
 var _ ProviderI = &Provider{}
 
-func NewProvider() *Provider {
-	return &Provider{}
+func NewProvider(engine core.Engine) *Provider {
+	p := &Provider{
+		engine: engine,
+	}
+	p.SetKeeperEngine(engine)
+
+	return p
 }
 
 func (p *Provider) InitModule(ctx *kptypes.ClientContext, config *config.Plugin) {
@@ -59,9 +67,26 @@ func (p *Provider) InitModule(ctx *kptypes.ClientContext, config *config.Plugin)
 	}
 }
 
+// GetEffectiveConfig return the plugin config of the loaded plugins. the plugin file path is reported by plugin name
+func (p *Provider) GetEffectiveConfig() interface{} {
+	p.list.lock.Lock()
+	defer p.list.lock.Unlock()
+
+	cfg := config.Plugin{}
+	for _, item := range p.list.plugins {
+		cfg.Lists = append(cfg.Lists, &config.PluginInstance{
+			Path:   strings.TrimSuffix(filepath.Base(item.Path), filepath.Ext(item.Path)),
+			Unique: item.Unique,
+			Params: item.Params,
+		})
+	}
+
+	return cfg
+}
+
 func (p *Provider) ValidateConfig() error {
 	// get version
-	kptypes.GetCorePluginVersion()
+	pluginVersion := kptypes.GetCorePluginVersion(p.engine)
 	existName := []string{}
 
 	// init plugin
@@ -75,7 +100,7 @@ func (p *Provider) ValidateConfig() error {
 		}
 
 		logField := log.WithFields(log.Fields{"name": pluginName, "path": item.Path})
-		if err := InitPluginFile(pluginName, item.Path); err != nil {
+		if err := InitPluginFile(pluginName, item.Path, pluginVersion); err != nil {
 			if _, ok := err.(kptypes.ApiError); ok {
 				logField.Error("plugin request information failed")
 				return err
@@ -187,7 +212,7 @@ func (p *Provider) ParseMessage(message *kpproto.KPMessage) {
 					params[k] = v
 				}
 
-				coreKplayer := core.GetLibKplayerInstance()
+				coreKplayer := p.engine
 				if err := coreKplayer.SendPrompt(kpproto.EventPromptAction_EVENT_PROMPT_ACTION_PLUGIN_ADD, &kpprompt.EventPromptPluginAdd{
 					Plugin: &kpprompt.PromptPlugin{
 						Path:   item.Path,
@@ -210,7 +235,7 @@ func (p *Provider) BeginRunning() {
 			log.WithFields(log.Fields{"path": item.Path, "unique": item.Unique}).Fatal("read plugin file failed")
 		}
 
-		if err := core.GetLibKplayerInstance().AddPlugin(&kpprompt.EventPromptPluginAdd{
+		if err := p.engine.AddPlugin(&kpprompt.EventPromptPluginAdd{
 			Plugin: &kpprompt.PromptPlugin{
 				Path:    item.Path,
 				Content: fileContent,
@@ -223,7 +248,12 @@ func (p *Provider) BeginRunning() {
 	}
 }
 
-func (p *Provider) addPlugin(plugin moduletypes.Plugin) error {
+// ResumeRunning add the plugins to the core restarted by watchdog
+func (p *Provider) ResumeRunning() {
+	p.BeginRunning()
+}
+
+func (p *Provider) addPlugin(ctx context.Context, plugin moduletypes.Plugin) error {
 	// validate
 	if p.list.Exist(plugin.Unique) {
 		return PluginUniqueHasExist
@@ -235,7 +265,7 @@ func (p *Provider) addPlugin(plugin moduletypes.Plugin) error {
 		params[k] = v
 	}
 
-	coreKplayer := core.GetLibKplayerInstance()
+	coreKplayer := p.engine
 
 	// read plugin file
 	fileContent, err := kptypes.ReadPlugin(plugin.Path)
@@ -244,17 +274,6 @@ func (p *Provider) addPlugin(plugin moduletypes.Plugin) error {
 		return err
 	}
 
-	if err := coreKplayer.SendPrompt(kpproto.EventPromptAction_EVENT_PROMPT_ACTION_PLUGIN_ADD, &kpprompt.EventPromptPluginAdd{
-		Plugin: &kpprompt.PromptPlugin{
-			Path:    plugin.Path,
-			Content: fileContent,
-			Unique:  plugin.Unique,
-			Params:  params,
-		},
-	}); err != nil {
-		return err
-	}
-
 	// append list
 	if err := p.list.AppendPlugin(plugin); err != nil {
 		return err
@@ -273,8 +292,23 @@ func (p *Provider) addPlugin(plugin moduletypes.Plugin) error {
 		return err
 	}
 
+	if err := coreKplayer.SendCorrelatedPrompt(keeperCtx.GetId(), kpproto.EventPromptAction_EVENT_PROMPT_ACTION_PLUGIN_ADD, &kpprompt.EventPromptPluginAdd{
+		Plugin: &kpprompt.PromptPlugin{
+			Path:    plugin.Path,
+			Content: fileContent,
+			Unique:  plugin.Unique,
+			Params:  params,
+		},
+	}); err != nil {
+		_, _ = p.list.RemovePluginByUnique(plugin.Unique)
+		return err
+	}
+
 	// wait context
-	keeperCtx.Wait()
+	if err := keeperCtx.Wait(ctx); err != nil {
+		_, _ = p.list.RemovePluginByUnique(plugin.Unique)
+		return err
+	}
 
 	if len(pluginAddMsg.Error) != 0 {
 		_, _ = p.list.RemovePluginByUnique(plugin.Unique)
@@ -0,0 +1,96 @@
+package provider
+
+import (
+	"context"
+	"reflect"
+
+	kptypes "github.com/bytelang/kplayer/types"
+	"github.com/bytelang/kplayer/types/config"
+	moduletypes "github.com/bytelang/kplayer/types/module"
+	svrproto "github.com/bytelang/kplayer/types/server"
+	log "github.com/sirupsen/logrus"
+)
+
+// ReloadConfig apply the changed plugin config to the loaded plugins.
+// the plugins are matched by unique, by path when the unique is not configured. changed params are updated
+func (p *Provider) ReloadConfig(ctx context.Context, cfg *config.Plugin) error {
+	matched := map[string]bool{}
+	var adds []*config.PluginInstance
+	var updates []*svrproto.PluginUpdateArgs
+	for _, item := range cfg.Lists {
+		plugin := p.matchPlugin(item, matched)
+		if plugin == nil {
+			adds = append(adds, item)
+			continue
+		}
+
+		matched[plugin.Unique] = true
+		if !equalPluginParams(plugin.Params, item.Params) {
+			updates = append(updates, &svrproto.PluginUpdateArgs{Unique: plugin.Unique, Params: item.Params})
+		}
+	}
+
+	var removes []moduletypes.Plugin
+	for _, item := range p.list.plugins {
+		if !matched[item.Unique] {
+			removes = append(removes, item)
+		}
+	}
+
+	for _, item := range removes {
+		logFields := log.WithFields(log.Fields{"path": item.Path, "unique": item.Unique})
+		if _, err := p.PluginRemove(ctx, &svrproto.PluginRemoveArgs{Unique: item.Unique}); err != nil {
+			logFields.WithField("error", err).Error("reload remove plugin failed")
+			continue
+		}
+		logFields.Info("reload remove plugin success")
+	}
+	for _, item := range updates {
+		logFields := log.WithFields(log.Fields{"unique": item.Unique, "params": item.Params})
+		if _, err := p.PluginUpdate(ctx, item); err != nil {
+			logFields.WithField("error", err).Error("reload update plugin failed")
+			continue
+		}
+		logFields.Info("reload update plugin success")
+	}
+	for _, item := range adds {
+		unique := item.Unique
+		if len(unique) == 0 {
+			unique = kptypes.GetRandString(6)
+		}
+
+		logFields := log.WithFields(log.Fields{"path": item.Path, "unique": unique})
+		if _, err := p.PluginAdd(ctx, &svrproto.PluginAddArgs{Path: item.Path, Unique: unique, Params: item.Params}); err != nil {
+			logFields.WithField("error", err).Error("reload add plugin failed")
+			continue
+		}
+		logFields.Info("reload add plugin success")
+	}
+
+	return nil
+}
+
+// matchPlugin return the loaded plugin of config item which is not matched yet
+func (p *Provider) matchPlugin(item *config.PluginInstance, matched map[string]bool) *moduletypes.Plugin {
+	for key := range p.list.plugins {
+		plugin := &p.list.plugins[key]
+		if matched[plugin.Unique] || plugin.Path != GetPluginPath(item.Path) {
+			continue
+		}
+		if item.Unique != "" && item.Unique != plugin.Unique {
+			continue
+		}
+
+		return plugin
+	}
+
+	return nil
+}
+
+func equalPluginParams(a map[string]string, b map[string]string) bool {
+	if len(a) == 0 && len(b) == 0 {
+		return true
+	}
+
+	return reflect.DeepEqual(a, b)
+}
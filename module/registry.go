@@ -0,0 +1,41 @@
+package module
+
+import (
+	"context"
+	"sync"
+
+	"github.com/bytelang/kplayer/core"
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"google.golang.org/grpc"
+)
+
+// Factory create the app module on core engine
+type Factory func(engine core.Engine) AppModule
+
+var (
+	factories     []Factory
+	factoriesLock sync.Mutex
+)
+
+// Register add a module factory. downstream binaries register their modules before cmd.Execute,
+// the modules are created together with the built-in modules on execute and ordered by DependsOn
+func Register(factory Factory) {
+	factoriesLock.Lock()
+	defer factoriesLock.Unlock()
+
+	factories = append(factories, factory)
+}
+
+// GetRegisteredFactories return module factories in registration order
+func GetRegisteredFactories() []Factory {
+	factoriesLock.Lock()
+	defer factoriesLock.Unlock()
+
+	return append([]Factory{}, factories...)
+}
+
+// ServiceAppModule module serving grpc service and grpc-gateway routes on the rpc server
+type ServiceAppModule interface {
+	RegisterGrpcService(s *grpc.Server)
+	RegisterGatewayHandler(ctx context.Context, mux *runtime.ServeMux, grpcEndpoint string, opts []grpc.DialOption) error
+}
@@ -1,14 +1,18 @@
 package play
 
 import (
+	"context"
 	"encoding/json"
+	"github.com/bytelang/kplayer/core"
 	"github.com/bytelang/kplayer/module"
 	playprovider "github.com/bytelang/kplayer/module/play/provider"
 	"github.com/bytelang/kplayer/module/resource/provider"
 	kptypes "github.com/bytelang/kplayer/types"
 	"github.com/bytelang/kplayer/types/config"
-	kpproto "github.com/bytelang/kplayer/types/core/proto"
+	svrproto "github.com/bytelang/kplayer/types/server"
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
 	"github.com/spf13/cobra"
+	"google.golang.org/grpc"
 )
 
 type AppModule struct {
@@ -16,15 +20,24 @@ type AppModule struct {
 }
 
 var _ module.AppModule = &AppModule{}
+var _ module.ServiceAppModule = &AppModule{}
+var _ module.ConfigAppModule = &AppModule{}
+var _ module.ReloadAppModule = &AppModule{}
+var _ module.DependentAppModule = &AppModule{}
 
-func NewAppModule(playProvider playprovider.ProviderI) AppModule {
-	return AppModule{provider.NewProvider(playProvider)}
+func NewAppModule(engine core.Engine, playProvider playprovider.ProviderI) AppModule {
+	return AppModule{provider.NewProvider(engine, playProvider)}
 }
 
 func (m AppModule) GetModuleName() string {
 	return provider.ModuleName
 }
 
+// DependsOn resource module reads the start point and play model of play module
+func (m AppModule) DependsOn() []string {
+	return []string{playprovider.ModuleName}
+}
+
 func (m AppModule) GetCommand() *cobra.Command {
 	return provider.GetCommand()
 }
@@ -40,16 +53,46 @@ func (m AppModule) InitConfig(ctx *kptypes.ClientContext, data json.RawMessage)
 	return cfg, nil
 }
 
+func (m AppModule) ReloadConfig(ctx context.Context, data json.RawMessage) error {
+	var cfg config.Resource
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return err
+	}
+
+	return m.Provider.ReloadConfig(ctx, &cfg)
+}
+
 func (m AppModule) ValidateConfig() error {
 	return m.Provider.ValidateConfig()
 }
 
-func (m AppModule) TriggerMessage(message *kpproto.KPMessage) {
+func (m AppModule) RegisterGrpcService(s *grpc.Server) {
+	svrproto.RegisterResourceGreeterServer(s, m.Provider)
+}
+
+func (m AppModule) RegisterGatewayHandler(ctx context.Context, mux *runtime.ServeMux, grpcEndpoint string, opts []grpc.DialOption) error {
+	return svrproto.RegisterResourceGreeterHandlerFromEndpoint(ctx, mux, grpcEndpoint, opts)
+}
+
+func (m AppModule) TriggerMessage(message *core.Message) {
 	m.Trigger(message)
 }
 
 func (m AppModule) BeginRunning(option ...module.ModuleOption) {
+	if module.HasModuleOption(option, module.ModuleOptionGenerateCache) {
+		return
+	}
+	if module.HasModuleOption(option, module.ModuleOptionResume) {
+		m.Provider.RestoreJournal()
+	}
+	m.Provider.StartJournal()
+	m.Provider.StartHotFolder()
+	m.Provider.StartSchedule()
 }
 
 func (m AppModule) EndRunning(option ...module.ModuleOption) {
+	m.Provider.EndLive()
+	m.Provider.EndSchedule()
+	m.Provider.EndHotFolder()
+	m.Provider.EndJournal()
 }
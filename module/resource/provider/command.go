@@ -9,7 +9,29 @@ import (
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 	"google.golang.org/grpc/metadata"
+	"path/filepath"
 	"strconv"
+	"strings"
+)
+
+const (
+	flagPlaylistFormat = "format"
+
+	flagDirectoryRecursive      = "recursive"
+	flagDirectoryInclude        = "include"
+	flagDirectoryExclude        = "exclude"
+	flagDirectorySort           = "sort"
+	flagDirectoryHidden         = "hidden"
+	flagDirectoryFollowSymlinks = "follow-symlinks"
+
+	flagInsertIndex  = "index"
+	flagInsertBefore = "before"
+	flagInsertAfter  = "after"
+
+	flagInterruptUnique   = "unique"
+	flagInterruptPriority = "priority"
+
+	flagLiveUnique = "unique"
 )
 
 func GetCommand() *cobra.Command {
@@ -24,6 +46,15 @@ func GetCommand() *cobra.Command {
 	cmd.AddCommand(AllCommand())
 	cmd.AddCommand(CurrentCommand())
 	cmd.AddCommand(SeekCommand())
+	cmd.AddCommand(ImportCommand())
+	cmd.AddCommand(ExportCommand())
+	cmd.AddCommand(MoveCommand())
+	cmd.AddCommand(InsertCommand())
+	cmd.AddCommand(PlayNextCommand())
+	cmd.AddCommand(ScheduleCommand())
+	cmd.AddCommand(InterruptCommand())
+	cmd.AddCommand(LiveCommand())
+	cmd.AddCommand(LiveStopCommand())
 
 	return cmd
 }
@@ -33,7 +64,8 @@ func AddCommand() *cobra.Command {
 		Use:   "add <input_path> [unique] [seek] [end]",
 		Short: "add resource to playlist",
 		Long: `input_path:
-    resource file path. support [file/rtmp/ftp] protocel
+    resource file path. support [file/rtmp/ftp] protocel.
+    the files of directory are added with the unique suffixed by their number
 unique:
     optional argument. resource unique name
 seek:
@@ -77,12 +109,25 @@ end:
 				return err
 			}
 
+			recursive, _ := cmd.Flags().GetBool(flagDirectoryRecursive)
+			include, _ := cmd.Flags().GetStringSlice(flagDirectoryInclude)
+			exclude, _ := cmd.Flags().GetStringSlice(flagDirectoryExclude)
+			sortOrder, _ := cmd.Flags().GetString(flagDirectorySort)
+			hidden, _ := cmd.Flags().GetBool(flagDirectoryHidden)
+			followSymlinks, _ := cmd.Flags().GetBool(flagDirectoryFollowSymlinks)
+
 			resourceClient := kpserver.NewResourceGreeterClient(conn)
 			reply, err := resourceClient.ResourceAdd(context.Background(), &kpserver.ResourceAddArgs{
-				Path:   path,
-				Unique: unique,
-				Seek:   seek,
-				End:    end,
+				Path:           path,
+				Unique:         unique,
+				Seek:           seek,
+				End:            end,
+				Recursive:      recursive,
+				Include:        include,
+				Exclude:        exclude,
+				Sort:           sortOrder,
+				Hidden:         hidden,
+				FollowSymlinks: followSymlinks,
 			})
 			if err != nil {
 				log.Error(err)
@@ -98,6 +143,12 @@ end:
 			return nil
 		},
 	}
+	cmd.Flags().Bool(flagDirectoryRecursive, false, "add the files of sub directories")
+	cmd.Flags().StringSlice(flagDirectoryInclude, nil, "glob patterns of the files added in directory")
+	cmd.Flags().StringSlice(flagDirectoryExclude, nil, "glob patterns of the files and directories skipped in directory")
+	cmd.Flags().String(flagDirectorySort, DirectorySortName, "order of the files in directory. "+strings.Join(DirectorySortOrders, ", "))
+	cmd.Flags().Bool(flagDirectoryHidden, false, "add the hidden files in directory")
+	cmd.Flags().Bool(flagDirectoryFollowSymlinks, false, "follow the symbolic links in directory")
 
 	return cmd
 }
@@ -292,3 +343,420 @@ seek:
 
 	return cmd
 }
+
+func ImportCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "import <playlist_path>",
+		Short: "append the resources of playlist file to playlist",
+		Long: `playlist_path:
+    playlist file path. support [m3u/m3u8/pls/xspf] format`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// get client ctx
+			clientCtx := kptypes.GetClientContextFromCommand(cmd)
+
+			// args
+			path, err := filepath.Abs(args[0])
+			if err != nil {
+				return err
+			}
+			format, _ := cmd.Flags().GetString(flagPlaylistFormat)
+
+			// request
+			conn, err := client.GrpcClientRequest(clientCtx.Config.Play.Rpc)
+			if err != nil {
+				return err
+			}
+
+			resourceClient := kpserver.NewResourceGreeterClient(conn)
+			reply, err := resourceClient.ResourceImport(context.Background(), &kpserver.ResourceImportArgs{
+				Path:   path,
+				Format: format,
+			})
+			if err != nil {
+				log.Error(err)
+				return nil
+			}
+
+			yaml, err := kptypes.FormatYamlProtoMessage(reply)
+			if err != nil {
+				return err
+			}
+			fmt.Print(yaml)
+
+			return nil
+		},
+	}
+	cmd.Flags().String(flagPlaylistFormat, "", "playlist format. detected by file extension when it is empty")
+
+	return cmd
+}
+
+func ExportCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "export",
+		Short: "print the playlist of all resources",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// get client ctx
+			clientCtx := kptypes.GetClientContextFromCommand(cmd)
+
+			// args
+			format, _ := cmd.Flags().GetString(flagPlaylistFormat)
+
+			// request
+			conn, err := client.GrpcClientRequest(clientCtx.Config.Play.Rpc)
+			if err != nil {
+				return err
+			}
+
+			resourceClient := kpserver.NewResourceGreeterClient(conn)
+			reply, err := resourceClient.ResourceExport(context.Background(), &kpserver.ResourceExportArgs{
+				Format: format,
+			})
+			if err != nil {
+				log.Error(err)
+				return nil
+			}
+			fmt.Print(reply.Content)
+
+			return nil
+		},
+	}
+	cmd.Flags().String(flagPlaylistFormat, PlaylistFormatM3U, "playlist format. "+strings.Join(PlaylistExportFormats, ", "))
+
+	return cmd
+}
+
+func MoveCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "move <unique> <index>",
+		Short: "move resource to index of playlist",
+		Long: `unique:
+    resource unique name
+index:
+    index of playlist. start from 0`,
+		Args: cobra.MinimumNArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// get client ctx
+			clientCtx := kptypes.GetClientContextFromCommand(cmd)
+
+			// args
+			index, err := strconv.ParseInt(args[1], 10, 64)
+			if err != nil {
+				return err
+			}
+
+			// request
+			conn, err := client.GrpcClientRequest(clientCtx.Config.Play.Rpc)
+			if err != nil {
+				return err
+			}
+
+			resourceClient := kpserver.NewResourceGreeterClient(conn)
+			reply, err := resourceClient.ResourceMove(context.Background(), &kpserver.ResourceMoveArgs{
+				Unique: args[0],
+				Index:  index,
+			})
+			if err != nil {
+				log.Error(err)
+				return nil
+			}
+
+			yaml, err := kptypes.FormatYamlProtoMessage(reply)
+			if err != nil {
+				return err
+			}
+			fmt.Print(yaml)
+
+			return nil
+		},
+	}
+
+	return cmd
+}
+
+func InsertCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "insert <input_path> [unique] [seek] [end]",
+		Short: "insert resource to playlist",
+		Long: `input_path:
+    resource file path. support [file/rtmp/ftp] protocel
+unique:
+    optional argument. resource unique name
+seek:
+    optional argument. start seek seconds position
+end:
+    optional argument. end seek seconds position`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// get client ctx
+			clientCtx := kptypes.GetClientContextFromCommand(cmd)
+
+			// args
+			var err error
+			var seek, end int64
+			path := args[0]
+			unique := kptypes.GetUniqueString(path)
+			if len(args) > 1 {
+				unique = args[1]
+			}
+			if len(args) > 2 {
+				seek, err = strconv.ParseInt(args[2], 10, 64)
+				if err != nil {
+					return err
+				}
+			}
+			if len(args) > 3 {
+				end, err = strconv.ParseInt(args[3], 10, 64)
+				if err != nil {
+					return err
+				}
+			}
+			index, _ := cmd.Flags().GetInt64(flagInsertIndex)
+			before, _ := cmd.Flags().GetString(flagInsertBefore)
+			after, _ := cmd.Flags().GetString(flagInsertAfter)
+
+			// request
+			conn, err := client.GrpcClientRequest(clientCtx.Config.Play.Rpc)
+			if err != nil {
+				return err
+			}
+
+			resourceClient := kpserver.NewResourceGreeterClient(conn)
+			reply, err := resourceClient.ResourceInsert(context.Background(), &kpserver.ResourceInsertArgs{
+				Path:   path,
+				Unique: unique,
+				Seek:   seek,
+				End:    end,
+				Index:  index,
+				Before: before,
+				After:  after,
+			})
+			if err != nil {
+				log.Error(err)
+				return nil
+			}
+
+			yaml, err := kptypes.FormatYamlProtoMessage(reply)
+			if err != nil {
+				return err
+			}
+			fmt.Print(yaml)
+
+			return nil
+		},
+	}
+	cmd.Flags().Int64(flagInsertIndex, 0, "index of playlist inserted at. start from 0")
+	cmd.Flags().String(flagInsertBefore, "", "unique of the resource inserted before")
+	cmd.Flags().String(flagInsertAfter, "", "unique of the resource inserted after")
+
+	return cmd
+}
+
+func PlayNextCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "play-next <unique> [input_path]",
+		Short: "play resource after the current resource",
+		Long: `unique:
+    resource unique name. the resource in playlist is moved after the current resource
+input_path:
+    optional argument. resource file path inserted after the current resource with the unique`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// get client ctx
+			clientCtx := kptypes.GetClientContextFromCommand(cmd)
+
+			// args
+			var path string
+			if len(args) > 1 {
+				path = args[1]
+			}
+
+			// request
+			conn, err := client.GrpcClientRequest(clientCtx.Config.Play.Rpc)
+			if err != nil {
+				return err
+			}
+
+			resourceClient := kpserver.NewResourceGreeterClient(conn)
+			reply, err := resourceClient.ResourcePlayNext(context.Background(), &kpserver.ResourcePlayNextArgs{
+				Unique: args[0],
+				Path:   path,
+			})
+			if err != nil {
+				log.Error(err)
+				return nil
+			}
+
+			yaml, err := kptypes.FormatYamlProtoMessage(reply)
+			if err != nil {
+				return err
+			}
+			fmt.Print(yaml)
+
+			return nil
+		},
+	}
+
+	return cmd
+}
+
+func ScheduleCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "schedule",
+		Short: "show the slots of schedule",
+		Long:  `show the slot playing, the slot waiting for the playing resource finished and the next slot of schedule`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// get client ctx
+			clientCtx := kptypes.GetClientContextFromCommand(cmd)
+
+			// request
+			conn, err := client.GrpcClientRequest(clientCtx.Config.Play.Rpc)
+			if err != nil {
+				return err
+			}
+
+			resourceClient := kpserver.NewResourceGreeterClient(conn)
+			reply, err := resourceClient.ResourceSchedule(context.Background(), &kpserver.ResourceScheduleArgs{})
+			if err != nil {
+				log.Error(err)
+				return nil
+			}
+
+			yaml, err := kptypes.FormatYamlProtoMessage(reply)
+			if err != nil {
+				return err
+			}
+			fmt.Print(yaml)
+
+			return nil
+		},
+	}
+
+	return cmd
+}
+
+func InterruptCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "interrupt <input_path>...",
+		Short: "interrupt the playing resource",
+		Long: `input_path:
+    resource file paths played in order. the playing resource is resumed from the position cut after them finished`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// get client ctx
+			clientCtx := kptypes.GetClientContextFromCommand(cmd)
+
+			// args
+			unique, _ := cmd.Flags().GetString(flagInterruptUnique)
+			priority, _ := cmd.Flags().GetInt32(flagInterruptPriority)
+
+			// request
+			conn, err := client.GrpcClientRequest(clientCtx.Config.Play.Rpc)
+			if err != nil {
+				return err
+			}
+
+			resourceClient := kpserver.NewResourceGreeterClient(conn)
+			reply, err := resourceClient.ResourceInterrupt(context.Background(), &kpserver.ResourceInterruptArgs{
+				Paths:    args,
+				Unique:   unique,
+				Priority: priority,
+			})
+			if err != nil {
+				log.Error(err)
+				return nil
+			}
+
+			yaml, err := kptypes.FormatYamlProtoMessage(reply)
+			if err != nil {
+				return err
+			}
+			fmt.Print(yaml)
+
+			return nil
+		},
+	}
+	cmd.Flags().String(flagInterruptUnique, "", "unique name of interrupt. generated when empty")
+	cmd.Flags().Int32(flagInterruptPriority, 0, "priority of interrupt. the queued interrupt of higher priority is played first")
+
+	return cmd
+}
+
+func LiveCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "live <input_url>",
+		Short: "take over the playlist with live source",
+		Long: `input_url:
+    live source url. the playlist is resumed from the position cut when it stopped or dropped, the dropped source is retried`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// get client ctx
+			clientCtx := kptypes.GetClientContextFromCommand(cmd)
+
+			// args
+			unique, _ := cmd.Flags().GetString(flagLiveUnique)
+
+			// request
+			conn, err := client.GrpcClientRequest(clientCtx.Config.Play.Rpc)
+			if err != nil {
+				return err
+			}
+
+			resourceClient := kpserver.NewResourceGreeterClient(conn)
+			reply, err := resourceClient.ResourceLive(context.Background(), &kpserver.ResourceLiveArgs{
+				Path:   args[0],
+				Unique: unique,
+			})
+			if err != nil {
+				log.Error(err)
+				return nil
+			}
+
+			yaml, err := kptypes.FormatYamlProtoMessage(reply)
+			if err != nil {
+				return err
+			}
+			fmt.Print(yaml)
+
+			return nil
+		},
+	}
+	cmd.Flags().String(flagLiveUnique, "", "unique name of live source. generated when empty")
+
+	return cmd
+}
+
+func LiveStopCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "live-stop",
+		Short: "stop the live source and resume the playlist",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// get client ctx
+			clientCtx := kptypes.GetClientContextFromCommand(cmd)
+
+			// request
+			conn, err := client.GrpcClientRequest(clientCtx.Config.Play.Rpc)
+			if err != nil {
+				return err
+			}
+
+			resourceClient := kpserver.NewResourceGreeterClient(conn)
+			reply, err := resourceClient.ResourceLiveStop(context.Background(), &kpserver.ResourceLiveStopArgs{})
+			if err != nil {
+				log.Error(err)
+				return nil
+			}
+
+			yaml, err := kptypes.FormatYamlProtoMessage(reply)
+			if err != nil {
+				return err
+			}
+			fmt.Print(yaml)
+
+			return nil
+		},
+	}
+
+	return cmd
+}
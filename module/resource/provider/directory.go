@@ -0,0 +1,382 @@
+package provider
+
+import (
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	kptypes "github.com/bytelang/kplayer/types"
+	"github.com/bytelang/kplayer/types/config"
+	moduletypes "github.com/bytelang/kplayer/types/module"
+	log "github.com/sirupsen/logrus"
+)
+
+// liveDirectory the directory resource re-scanned each time playback reaches it
+type liveDirectory struct {
+	config *config.DirectoryResource
+
+	// the unique of resource before the files of directory. the files are inserted after it when the directory
+	// has no file in playlist. empty is the head of playlist
+	anchor string
+}
+
+// liveDirectoryKey return the key of live directory. it is the unique when configured, otherwise the directory path
+func liveDirectoryKey(res *config.DirectoryResource) string {
+	if res.Unique != "" {
+		return res.Unique
+	}
+
+	return filepath.Clean(res.Directory)
+}
+
+// directoryFile the file found in directory
+type directoryFile struct {
+	path    string
+	modTime time.Time
+}
+
+// ScanDirectoryResource return the files of directory resource of allowed extensions in the configured order
+func ScanDirectoryResource(res *config.DirectoryResource, allowExtensions []string) ([]string, error) {
+	stat, err := os.Stat(res.Directory)
+	if err != nil {
+		return nil, err
+	}
+	if !stat.IsDir() {
+		return nil, fmt.Errorf("not directory")
+	}
+	if err := ValidateGlobPatterns(res.Include); err != nil {
+		return nil, err
+	}
+	if err := ValidateGlobPatterns(res.Exclude); err != nil {
+		return nil, err
+	}
+	if res.Sort != "" && !kptypes.ArrayInString(DirectorySortOrders, res.Sort) {
+		return nil, fmt.Errorf("invalid sort order %s. available: %v", res.Sort, DirectorySortOrders)
+	}
+
+	var files []directoryFile
+	if err := scanDirectory(res, allowExtensions, res.Directory, "", map[string]bool{}, &files); err != nil {
+		return nil, err
+	}
+	sortDirectoryFiles(files, res.Sort)
+
+	paths := []string{}
+	for _, f := range files {
+		paths = append(paths, f.path)
+	}
+
+	return paths, nil
+}
+
+// scanDirectory append the matched files of directory. the walked directories are recorded by their real path
+// against the symbolic link loop
+func scanDirectory(res *config.DirectoryResource, allowExtensions []string, dir string, rel string, visited map[string]bool, files *[]directoryFile) error {
+	realPath, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		return err
+	}
+	if visited[realPath] {
+		return nil
+	}
+	visited[realPath] = true
+
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		name := entry.Name()
+		if !res.Hidden && strings.HasPrefix(name, ".") {
+			continue
+		}
+		path, entryRel := filepath.Join(dir, name), filepath.Join(rel, name)
+
+		info := entry
+		if info.Mode()&os.ModeSymlink != 0 {
+			if !res.FollowSymlinks {
+				continue
+			}
+			// the broken link is skipped
+			if info, err = os.Stat(path); err != nil {
+				continue
+			}
+		}
+
+		if info.IsDir() {
+			if !res.Recursive || matchGlobPatterns(res.Exclude, entryRel) {
+				continue
+			}
+			if err := scanDirectory(res, allowExtensions, path, entryRel, visited, files); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if len(res.Include) != 0 && !matchGlobPatterns(res.Include, entryRel) {
+			continue
+		}
+		if matchGlobPatterns(res.Exclude, entryRel) {
+			continue
+		}
+		ext := strings.TrimPrefix(filepath.Ext(name), ".")
+		if allowExtensions != nil && !kptypes.ArrayInString(allowExtensions, ext) {
+			continue
+		}
+
+		*files = append(*files, directoryFile{path: path, modTime: info.ModTime()})
+	}
+
+	return nil
+}
+
+// sortDirectoryFiles sort the files in order. the files of same modification time are sorted by name
+func sortDirectoryFiles(files []directoryFile, order string) {
+	sort.SliceStable(files, func(i, j int) bool {
+		return files[i].path < files[j].path
+	})
+
+	switch order {
+	case DirectorySortNatural:
+		sort.SliceStable(files, func(i, j int) bool {
+			return naturalLess(files[i].path, files[j].path)
+		})
+	case DirectorySortMtime:
+		sort.SliceStable(files, func(i, j int) bool {
+			return files[i].modTime.Before(files[j].modTime)
+		})
+	case DirectorySortRandom:
+		rand.Shuffle(len(files), func(i, j int) {
+			files[i], files[j] = files[j], files[i]
+		})
+	}
+}
+
+// naturalLess compare the strings with the digit sequences compared by numeric value. e.g. 2.mp4 before 10.mp4
+func naturalLess(a, b string) bool {
+	for a != "" && b != "" {
+		aDigits, bDigits := leadingDigits(a), leadingDigits(b)
+		if aDigits == "" || bDigits == "" {
+			if a[0] != b[0] {
+				return a[0] < b[0]
+			}
+			a, b = a[1:], b[1:]
+			continue
+		}
+
+		aNumber, bNumber := strings.TrimLeft(aDigits, "0"), strings.TrimLeft(bDigits, "0")
+		if len(aNumber) != len(bNumber) {
+			return len(aNumber) < len(bNumber)
+		}
+		if aNumber != bNumber {
+			return aNumber < bNumber
+		}
+		if len(aDigits) != len(bDigits) {
+			return len(aDigits) < len(bDigits)
+		}
+		a, b = a[len(aDigits):], b[len(bDigits):]
+	}
+
+	return len(a) < len(b)
+}
+
+func leadingDigits(s string) string {
+	i := 0
+	for i < len(s) && s[i] >= '0' && s[i] <= '9' {
+		i++
+	}
+
+	return s[:i]
+}
+
+// ValidateGlobPatterns return error when the glob pattern is malformed
+func ValidateGlobPatterns(patterns []string) error {
+	for _, pattern := range patterns {
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid glob pattern %s. error: %s", pattern, err)
+		}
+	}
+
+	return nil
+}
+
+// matchGlobPatterns return whether the file name or the relative path matched any of the glob patterns
+func matchGlobPatterns(patterns []string, rel string) bool {
+	rel = filepath.ToSlash(rel)
+	for _, pattern := range patterns {
+		if ok, _ := filepath.Match(pattern, rel); ok {
+			return true
+		}
+		if ok, _ := filepath.Match(pattern, filepath.Base(rel)); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// refreshLiveDirectories re-scan the live directories before the resource of current index played. all of them are
+// re-scanned when the loop restarted from the head, otherwise the directory is re-scanned when playback enters it or its file has
+// been removed. the schedule slot playing has no live directory. the input mutex must be held
+func (p *Provider) refreshLiveDirectories(previous string, restarted bool) {
+	if len(p.liveDirectories) == 0 || p.scheduleActive != nil {
+		return
+	}
+
+	if restarted {
+		var keys []string
+		for key := range p.liveDirectories {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			p.reconcileLiveDirectory(key)
+		}
+		p.currentIndex = 0
+		return
+	}
+
+	res, err := p.inputs.GetResourceByIndex(p.currentIndex)
+	if err != nil || res.LiveDirectory == "" {
+		return
+	}
+	if res.LiveDirectory == previous {
+		if _, err := os.Stat(res.Path); err == nil {
+			return
+		}
+	}
+	p.reconcileLiveDirectory(res.LiveDirectory)
+}
+
+// reconcileLiveDirectory remove the files no longer in live directory from playlist and insert the new files beside
+// the others of it in the configured order. the rest of playlist is kept. the input mutex must be held
+func (p *Provider) reconcileLiveDirectory(key string) {
+	dir, ok := p.liveDirectories[key]
+	if !ok {
+		return
+	}
+
+	logFields := log.WithFields(log.Fields{"directory": dir.config.Directory, "key": key})
+	files, err := ScanDirectoryResource(dir.config, p.allowExtensions)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			logFields.WithField("error", err).Warn("scan live directory failed")
+			return
+		}
+		files = nil
+	}
+	exists := map[string]bool{}
+	for _, f := range files {
+		exists[f] = true
+	}
+
+	// remove the files no longer exist
+	present := map[string]bool{}
+	for index := 0; index < len(p.inputs.resources); {
+		res := p.inputs.resources[index]
+		if res.LiveDirectory != key {
+			index++
+			continue
+		}
+		if exists[res.Path] && !present[res.Path] {
+			present[res.Path] = true
+			index++
+			continue
+		}
+
+		if _, _, err := p.inputs.RemoveResourceByUnique(res.Unique); err != nil {
+			logFields.WithField("unique", res.Unique).Warn(err)
+			index++
+			continue
+		}
+		delete(p.resetInputs, res.Unique)
+		if index < p.currentIndex {
+			p.currentIndex = p.currentIndex - 1
+		}
+		logFields.WithFields(log.Fields{"path": res.Path, "unique": res.Unique}).Info("remove live directory resource success")
+	}
+
+	// insert the new files
+	for position, f := range files {
+		if present[f] {
+			continue
+		}
+
+		// the file inserted at the current index is played next only when playback is in the directory
+		index := p.liveDirectoryInsertIndex(key, files[:position], present)
+		shift := index < p.currentIndex
+		if current, err := p.inputs.GetResourceByIndex(p.currentIndex); err == nil && index == p.currentIndex {
+			shift = current.LiveDirectory != key
+		}
+		res := setResourceUniqueName(moduletypes.Resource{
+			Path:          f,
+			Seek:          0,
+			End:           -1,
+			CreateTime:    uint64(time.Now().Unix()),
+			LiveDirectory: key,
+		})
+		if err := p.inputs.InsertResource(index, res); err != nil {
+			logFields.WithFields(log.Fields{"path": f, "error": err}).Warn("add live directory resource failed")
+			continue
+		}
+		if shift {
+			p.currentIndex = p.currentIndex + 1
+		}
+		present[f] = true
+		logFields.WithFields(log.Fields{"path": res.Path, "unique": res.Unique}).Info("add live directory resource success")
+	}
+
+	// keep the position of directory for the files added after it emptied
+	for index, res := range p.inputs.resources {
+		if res.LiveDirectory != key {
+			continue
+		}
+		dir.anchor = ""
+		if index > 0 {
+			dir.anchor = p.inputs.resources[index-1].Unique
+		}
+		break
+	}
+}
+
+// liveDirectoryInsertIndex return the index of the new file of live directory inserted. it is after the file of
+// directory ordered before it in playlist, or before the first file of directory. the input mutex must be held
+func (p *Provider) liveDirectoryInsertIndex(key string, before []string, present map[string]bool) int {
+	previous := ""
+	for i := len(before) - 1; i >= 0; i-- {
+		if present[before[i]] {
+			previous = before[i]
+			break
+		}
+	}
+
+	first := -1
+	for index, res := range p.inputs.resources {
+		if res.LiveDirectory != key {
+			continue
+		}
+		if first == -1 {
+			first = index
+		}
+		if previous != "" && res.Path == previous {
+			return index + 1
+		}
+	}
+	if first != -1 {
+		return first
+	}
+
+	anchor := p.liveDirectories[key].anchor
+	if anchor == "" {
+		return 0
+	}
+	if _, index, err := p.inputs.GetResourceByUnique(anchor); err == nil {
+		return index + 1
+	}
+
+	return len(p.inputs.resources)
+}
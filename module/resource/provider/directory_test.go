@@ -0,0 +1,274 @@
+package provider
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/bytelang/kplayer/core"
+	playprovider "github.com/bytelang/kplayer/module/play/provider"
+	kptypes "github.com/bytelang/kplayer/types"
+	"github.com/bytelang/kplayer/types/config"
+	svrproto "github.com/bytelang/kplayer/types/server"
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes"
+	"google.golang.org/protobuf/types/known/anypb"
+)
+
+func writeDirectoryFiles(t *testing.T, dir string, names ...string) {
+	for _, name := range names {
+		path := filepath.Join(dir, name)
+		if err := kptypes.MkDir(filepath.Dir(path)); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte("content"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestScanDirectoryResource(t *testing.T) {
+	dir := t.TempDir()
+	writeDirectoryFiles(t, dir, "b.mp4", "a.mp4", "c.txt", "sub/d.mp4", "sub/skip/e.mp4", "tmp.mp4")
+
+	files, err := ScanDirectoryResource(&config.DirectoryResource{Directory: dir}, []string{"mp4"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{filepath.Join(dir, "a.mp4"), filepath.Join(dir, "b.mp4"), filepath.Join(dir, "tmp.mp4")}
+	if !reflect.DeepEqual(files, expected) {
+		t.Fatalf("unexpected files: %v", files)
+	}
+
+	files, err = ScanDirectoryResource(&config.DirectoryResource{
+		Directory: dir,
+		Recursive: true,
+		Include:   []string{"*.mp4"},
+		Exclude:   []string{"tmp.*", "sub/skip"},
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected = []string{filepath.Join(dir, "a.mp4"), filepath.Join(dir, "b.mp4"), filepath.Join(dir, "sub/d.mp4")}
+	if !reflect.DeepEqual(files, expected) {
+		t.Fatalf("unexpected files: %v", files)
+	}
+
+	if _, err := ScanDirectoryResource(&config.DirectoryResource{Directory: dir, Include: []string{"["}}, nil); err == nil {
+		t.Fatal("expected invalid glob pattern")
+	}
+	if _, err := ScanDirectoryResource(&config.DirectoryResource{Directory: dir, Sort: "size"}, nil); err == nil {
+		t.Fatal("expected invalid sort order")
+	}
+}
+
+func TestScanDirectoryResourceOptions(t *testing.T) {
+	dir := t.TempDir()
+	writeDirectoryFiles(t, dir, "10.mp4", "2.mp4", ".hidden.mp4", ".cache/a.mp4")
+	other := t.TempDir()
+	writeDirectoryFiles(t, other, "linked.mp4")
+	if err := os.Symlink(other, filepath.Join(dir, "link")); err != nil {
+		t.Fatal(err)
+	}
+	// the loop of symbolic link is walked once
+	if err := os.Symlink(dir, filepath.Join(other, "loop")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Join(dir, "missing.mp4"), filepath.Join(dir, "broken.mp4")); err != nil {
+		t.Fatal(err)
+	}
+
+	scan := func(res *config.DirectoryResource) []string {
+		res.Directory = dir
+		files, err := ScanDirectoryResource(res, []string{"mp4"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var names []string
+		for _, f := range files {
+			rel, _ := filepath.Rel(dir, f)
+			names = append(names, filepath.ToSlash(rel))
+		}
+		return names
+	}
+
+	if names := scan(&config.DirectoryResource{Recursive: true}); !reflect.DeepEqual(names, []string{"10.mp4", "2.mp4"}) {
+		t.Fatalf("unexpected files: %v", names)
+	}
+	if names := scan(&config.DirectoryResource{Sort: DirectorySortNatural}); !reflect.DeepEqual(names, []string{"2.mp4", "10.mp4"}) {
+		t.Fatalf("unexpected files: %v", names)
+	}
+	names := scan(&config.DirectoryResource{Recursive: true, Hidden: true, FollowSymlinks: true})
+	if !reflect.DeepEqual(names, []string{".cache/a.mp4", ".hidden.mp4", "10.mp4", "2.mp4", "link/linked.mp4"}) {
+		t.Fatalf("unexpected files: %v", names)
+	}
+
+	past := time.Now().Add(-time.Hour)
+	if err := os.Chtimes(filepath.Join(dir, "2.mp4"), past, past); err != nil {
+		t.Fatal(err)
+	}
+	if names := scan(&config.DirectoryResource{Sort: DirectorySortMtime}); !reflect.DeepEqual(names, []string{"2.mp4", "10.mp4"}) {
+		t.Fatalf("unexpected files: %v", names)
+	}
+	if names := scan(&config.DirectoryResource{Sort: DirectorySortRandom}); len(names) != 2 {
+		t.Fatalf("unexpected files: %v", names)
+	}
+}
+
+func TestNaturalLess(t *testing.T) {
+	for _, item := range []struct {
+		a, b string
+		less bool
+	}{
+		{"2.mp4", "10.mp4", true},
+		{"10.mp4", "2.mp4", false},
+		{"ep2-part10", "ep2-part9", false},
+		{"2.mp4", "02.mp4", true},
+		{"a.mp4", "b.mp4", true},
+		{"a", "a1", true},
+		{"a.mp4", "a.mp4", false},
+	} {
+		if less := naturalLess(item.a, item.b); less != item.less {
+			t.Errorf("unexpected natural order of %s and %s: %v", item.a, item.b, less)
+		}
+	}
+}
+
+func TestLiveDirectory(t *testing.T) {
+	dir := t.TempDir()
+	writeDirectoryFiles(t, dir, "a.mp4", "c.mp4")
+
+	var lists []*anypb.Any
+	for _, item := range []proto.Message{
+		&config.SingleResource{Path: "/video/head.mp4", Unique: "head"},
+		&config.DirectoryResource{Directory: dir, Unique: "live", Live: true},
+		&config.SingleResource{Path: "/video/tail.mp4", Unique: "tail"},
+	} {
+		any, err := ptypes.MarshalAny(item)
+		if err != nil {
+			t.Fatal(err)
+		}
+		lists = append(lists, any)
+	}
+
+	fe := core.NewFakeEngine()
+	pp := playprovider.NewProvider(fe)
+	pp.InitModule(kptypes.DefaultClientContext(), &config.Play{
+		StartPoint: 1,
+		PlayModel:  "loop",
+		Rpc:        &config.Server{},
+		Encode:     &config.Encode{},
+	})
+	p := NewProvider(fe, pp)
+	p.InitModule(kptypes.DefaultClientContext(), &config.Resource{Lists: lists, Extensions: []string{"mp4"}})
+	if err := p.ValidateConfig(); err != nil {
+		t.Fatal(err)
+	}
+	playlist := func() []string {
+		var paths []string
+		for _, item := range p.inputs.resources {
+			paths = append(paths, filepath.Base(item.Path))
+		}
+		return paths
+	}
+	if !reflect.DeepEqual(playlist(), []string{"head.mp4", "a.mp4", "c.mp4", "tail.mp4"}) {
+		t.Fatalf("unexpected playlist: %v", playlist())
+	}
+
+	// the changes are reconciled when playback enters the directory
+	writeDirectoryFiles(t, dir, "b.mp4", "0.mp4")
+	if err := os.Remove(filepath.Join(dir, "a.mp4")); err != nil {
+		t.Fatal(err)
+	}
+	p.currentIndex = 1
+	p.refreshLiveDirectories("", false)
+	if !reflect.DeepEqual(playlist(), []string{"head.mp4", "0.mp4", "b.mp4", "c.mp4", "tail.mp4"}) {
+		t.Fatalf("unexpected playlist: %v", playlist())
+	}
+	if p.currentIndex != 1 {
+		t.Fatalf("unexpected current index: %d", p.currentIndex)
+	}
+
+	// the removed file is reconciled before played
+	if err := os.Remove(filepath.Join(dir, "b.mp4")); err != nil {
+		t.Fatal(err)
+	}
+	p.currentIndex = 2
+	p.refreshLiveDirectories("live", false)
+	if !reflect.DeepEqual(playlist(), []string{"head.mp4", "0.mp4", "c.mp4", "tail.mp4"}) {
+		t.Fatalf("unexpected playlist: %v", playlist())
+	}
+	if p.currentIndex != 2 || p.inputs.resources[2].Path != filepath.Join(dir, "c.mp4") {
+		t.Fatalf("unexpected current index: %d", p.currentIndex)
+	}
+
+	// the emptied directory is kept in place
+	for _, name := range []string{"0.mp4", "c.mp4"} {
+		if err := os.Remove(filepath.Join(dir, name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	p.currentIndex = 3
+	p.refreshLiveDirectories("", true)
+	if !reflect.DeepEqual(playlist(), []string{"head.mp4", "tail.mp4"}) || p.currentIndex != 0 {
+		t.Fatalf("unexpected playlist: %v, current index: %d", playlist(), p.currentIndex)
+	}
+	cfg := p.GetEffectiveConfig().(effectiveResourceConfig)
+	if len(cfg.Lists) != 3 {
+		t.Fatalf("unexpected effective config: %v", cfg.Lists)
+	}
+	if _, ok := cfg.Lists[1].(*config.DirectoryResource); !ok {
+		t.Fatalf("unexpected effective config: %v", cfg.Lists)
+	}
+
+	// the files added to the emptied directory are inserted after its anchor
+	writeDirectoryFiles(t, dir, "d.mp4")
+	p.currentIndex = 1
+	p.refreshLiveDirectories("", false)
+	if !reflect.DeepEqual(playlist(), []string{"head.mp4", "tail.mp4"}) {
+		t.Fatalf("unexpected playlist: %v", playlist())
+	}
+	p.refreshLiveDirectories("", true)
+	if !reflect.DeepEqual(playlist(), []string{"head.mp4", "d.mp4", "tail.mp4"}) || p.currentIndex != 0 {
+		t.Fatalf("unexpected playlist: %v, current index: %d", playlist(), p.currentIndex)
+	}
+}
+
+func TestResourceAddDirectory(t *testing.T) {
+	dir := t.TempDir()
+	writeDirectoryFiles(t, dir, "1.mp4", "10.mp4", "9.mp4", "sub/a.mp4", "b.txt")
+
+	p := NewProvider(core.NewFakeEngine(), nil)
+	p.allowExtensions = []string{"mp4"}
+	reply, err := p.ResourceAdd(context.Background(), &svrproto.ResourceAddArgs{
+		Path:   dir,
+		Unique: "dir",
+		Sort:   DirectorySortNatural,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var uniques, names []string
+	for _, item := range reply.Resources {
+		uniques = append(uniques, item.Unique)
+		names = append(names, filepath.Base(item.Path))
+	}
+	if !reflect.DeepEqual(uniques, []string{"dir-1", "dir-2", "dir-3"}) || !reflect.DeepEqual(names, []string{"1.mp4", "9.mp4", "10.mp4"}) {
+		t.Fatalf("unexpected resources: %v", reply.Resources)
+	}
+	if reply.Resource.Unique != "dir-1" || len(p.inputs.resources) != 3 {
+		t.Fatalf("unexpected playlist: %v", p.inputs.resources)
+	}
+
+	// the unique existed
+	if _, err := p.ResourceAdd(context.Background(), &svrproto.ResourceAddArgs{Path: dir, Unique: "dir", Recursive: true}); err == nil {
+		t.Fatal("expected unique existed")
+	}
+	if len(p.inputs.resources) != 3 {
+		t.Fatalf("unexpected playlist: %v", p.inputs.resources)
+	}
+}
@@ -0,0 +1,220 @@
+package provider
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	kptypes "github.com/bytelang/kplayer/types"
+	"github.com/bytelang/kplayer/types/config"
+	moduletypes "github.com/bytelang/kplayer/types/module"
+	log "github.com/sirupsen/logrus"
+)
+
+// hotFolderFile the file found in hot folder. it is appended when the size and modification time unchanged
+// over the stable time
+type hotFolderFile struct {
+	size     int64
+	modTime  time.Time
+	since    time.Time
+	ingested bool
+}
+
+// StartHotFolder scan the hot folder periodically until EndHotFolder called. it does nothing when hot folder not set
+func (p *Provider) StartHotFolder() {
+	p.hotFolderOn = true
+
+	p.input_mutex.Lock()
+	cfg := p.hotFolder
+	p.input_mutex.Unlock()
+	if cfg == nil || cfg.Path == "" {
+		return
+	}
+
+	folderPath, err := filepath.Abs(cfg.Path)
+	if err != nil {
+		log.WithFields(log.Fields{"path": cfg.Path, "error": err}).Error("hot folder invalid")
+		return
+	}
+	scanInterval := time.Duration(cfg.ScanInterval) * time.Second
+	if scanInterval <= 0 {
+		scanInterval = time.Duration(kptypes.DefaultHotFolderScanInterval) * time.Second
+	}
+	stableTime := time.Duration(cfg.StableTime) * time.Second
+
+	p.hotFolderStop = make(chan bool)
+	p.hotFolderDone = make(chan bool)
+	go func(stop chan bool, done chan bool) {
+		defer close(done)
+
+		ticker := time.NewTicker(scanInterval)
+		defer ticker.Stop()
+
+		log.WithField("path", folderPath).Info("watching hot folder")
+		files := map[string]*hotFolderFile{}
+		for {
+			p.scanHotFolder(folderPath, stableTime, files)
+
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+			}
+		}
+	}(p.hotFolderStop, p.hotFolderDone)
+}
+
+// EndHotFolder stop scanning the hot folder
+func (p *Provider) EndHotFolder() {
+	p.hotFolderOn = false
+	if p.hotFolderStop == nil {
+		return
+	}
+
+	close(p.hotFolderStop)
+	<-p.hotFolderDone
+	p.hotFolderStop = nil
+}
+
+// scanHotFolder append the files of allowed extensions written completely to playlist
+func (p *Provider) scanHotFolder(folderPath string, stableTime time.Duration, files map[string]*hotFolderFile) {
+	entries, err := ioutil.ReadDir(folderPath)
+	if err != nil {
+		log.WithFields(log.Fields{"path": folderPath, "error": err}).Warn("scan hot folder failed")
+		return
+	}
+
+	p.input_mutex.Lock()
+	allowExtensions := p.allowExtensions
+	p.input_mutex.Unlock()
+
+	found := map[string]bool{}
+	for _, entry := range entries {
+		// the hidden files are regarded as being written
+		if entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		ext := strings.TrimPrefix(filepath.Ext(entry.Name()), ".")
+		if allowExtensions != nil && !kptypes.ArrayInString(allowExtensions, ext) {
+			continue
+		}
+
+		path := filepath.Join(folderPath, entry.Name())
+		found[path] = true
+
+		file, ok := files[path]
+		if ok && file.ingested {
+			continue
+		}
+		if !ok || file.size != entry.Size() || !file.modTime.Equal(entry.ModTime()) {
+			files[path] = &hotFolderFile{size: entry.Size(), modTime: entry.ModTime(), since: time.Now()}
+			continue
+		}
+		if time.Since(file.since) < stableTime {
+			continue
+		}
+
+		file.ingested = true
+		p.ingestHotFolderFile(path)
+	}
+
+	// the removed files can be added again
+	for path := range files {
+		if !found[path] {
+			delete(files, path)
+		}
+	}
+}
+
+func (p *Provider) ingestHotFolderFile(path string) {
+	p.input_mutex.Lock()
+	defer p.input_mutex.Unlock()
+
+	for _, item := range p.inputs.resources {
+		if item.Path == path {
+			return
+		}
+	}
+
+	res := setResourceUniqueName(moduletypes.Resource{
+		Path:       path,
+		End:        -1,
+		CreateTime: uint64(time.Now().Unix()),
+	})
+	if err := p.inputs.AppendResource(res); err != nil {
+		log.WithFields(log.Fields{"path": path, "error": err}).Warn("add hot folder resource failed")
+		return
+	}
+	p.hotFolderUniques[res.Unique] = path
+	log.WithFields(log.Fields{"path": res.Path, "unique": res.Unique}).Info("add hot folder resource success")
+
+	p.resumeWaitingResource()
+}
+
+// finishHotFolderResource move the finished file of hot folder to the done or failed directory and remove it from
+// playlist. the current index is kept pointing to the resource before it. return whether the resource removed.
+// the input mutex must be held
+func (p *Provider) finishHotFolderResource(unique string, failed bool) bool {
+	path, ok := p.hotFolderUniques[unique]
+	if !ok || p.hotFolder == nil {
+		return false
+	}
+	dir := p.hotFolder.DonePath
+	if failed {
+		dir = p.hotFolder.FailedPath
+	}
+	if dir == "" {
+		return false
+	}
+
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		log.WithFields(log.Fields{"path": dir, "error": err}).Warn("create hot folder directory failed")
+		return false
+	}
+	target := filepath.Join(dir, filepath.Base(path))
+	if _, err := os.Stat(target); err == nil {
+		ext := filepath.Ext(target)
+		target = fmt.Sprintf("%s.%d%s", strings.TrimSuffix(target, ext), time.Now().Unix(), ext)
+	}
+	logFields := log.WithFields(log.Fields{"path": path, "unique": unique, "target": target})
+	if err := os.Rename(path, target); err != nil {
+		logFields.WithField("error", err).Warn("move hot folder resource failed")
+		return false
+	}
+	delete(p.hotFolderUniques, unique)
+
+	_, index, err := p.inputs.RemoveResourceByUnique(unique)
+	if err != nil {
+		logFields.Warn(err)
+		return false
+	}
+	if index <= p.currentIndex {
+		p.currentIndex = p.currentIndex - 1
+	}
+	logFields.Info("move hot folder resource success")
+
+	return true
+}
+
+// resumeWaitingResource add the next resource to core when the player is waiting for resource added.
+// the input mutex must be held
+func (p *Provider) resumeWaitingResource() {
+	if !p.waitingResource || p.currentIndex < 0 || p.currentIndex >= len(p.inputs.resources) {
+		return
+	}
+
+	p.addNextResourceToCore()
+}
+
+// hotFolderChanged whether the hot folder config changed
+func hotFolderChanged(a, b *config.HotFolder) bool {
+	if a == nil || b == nil {
+		return (a == nil || a.Path == "") != (b == nil || b.Path == "")
+	}
+
+	return a.Path != b.Path || a.DonePath != b.DonePath || a.FailedPath != b.FailedPath ||
+		a.ScanInterval != b.ScanInterval || a.StableTime != b.StableTime
+}
@@ -0,0 +1,106 @@
+package provider
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/bytelang/kplayer/core"
+	playprovider "github.com/bytelang/kplayer/module/play/provider"
+	kptypes "github.com/bytelang/kplayer/types"
+	"github.com/bytelang/kplayer/types/config"
+)
+
+func waitHotFolder(t *testing.T, condition func() bool) {
+	for i := 0; i < 200; i++ {
+		if condition() {
+			return
+		}
+		time.Sleep(time.Millisecond * 10)
+	}
+	t.Fatal("wait hot folder timeout")
+}
+
+func TestHotFolder(t *testing.T) {
+	dir := t.TempDir()
+	folder, done, failed := filepath.Join(dir, "in"), filepath.Join(dir, "done"), filepath.Join(dir, "failed")
+	if err := kptypes.MkDir(folder); err != nil {
+		t.Fatal(err)
+	}
+
+	fe := core.NewFakeEngine()
+	pp := playprovider.NewProvider(fe)
+	pp.InitModule(kptypes.DefaultClientContext(), &config.Play{
+		StartPoint: 1,
+		PlayModel:  "queue",
+		Rpc:        &config.Server{},
+		Encode:     &config.Encode{},
+	})
+	p := NewProvider(fe, pp)
+	p.InitModule(kptypes.DefaultClientContext(), &config.Resource{
+		Extensions: []string{"mp4"},
+		HotFolder:  &config.HotFolder{Path: folder, DonePath: done, FailedPath: failed},
+	})
+	if err := p.ValidateConfig(); err != nil {
+		t.Fatal(err)
+	}
+	fe.SetCallBackMessage(func(message *core.Message) {
+		p.ParseMessage(message.KPMessage)
+		p.Trigger(message)
+	})
+
+	resultChan := make(chan int)
+	go func() {
+		resultChan <- fe.Run()
+	}()
+	defer func() {
+		fe.Terminate(0)
+		<-resultChan
+	}()
+
+	waiting := func() bool {
+		p.input_mutex.Lock()
+		defer p.input_mutex.Unlock()
+		return p.waitingResource
+	}
+	waitHotFolder(t, waiting)
+
+	// the file is appended after its size unchanged
+	for _, name := range []string{"a.mp4", "b.txt", ".c.mp4"} {
+		if err := ioutil.WriteFile(filepath.Join(folder, name), []byte("content"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	files := map[string]*hotFolderFile{}
+	p.scanHotFolder(folder, 0, files)
+	if len(p.inputs.resources) != 0 {
+		t.Fatal("unexpected file appended before stable")
+	}
+	p.scanHotFolder(folder, 0, files)
+	if len(p.inputs.resources) != 1 || p.inputs.resources[0].Path != filepath.Join(folder, "a.mp4") {
+		t.Fatalf("unexpected playlist: %v", p.inputs.resources)
+	}
+	waitHotFolder(t, func() bool { return !waiting() })
+
+	// finished file is moved to done directory
+	fe.Advance(core.FakeDefaultResourceDuration)
+	waitHotFolder(t, func() bool { return kptypes.FileExists(filepath.Join(done, "a.mp4")) && waiting() })
+	if len(p.inputs.resources) != 0 {
+		t.Fatalf("unexpected playlist: %v", p.inputs.resources)
+	}
+
+	// failed file is moved to failed directory
+	fe.SetResourceError(filepath.Join(folder, "d.mp4"), "invalid resource")
+	if err := ioutil.WriteFile(filepath.Join(folder, "d.mp4"), []byte("content"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	p.scanHotFolder(folder, 0, files)
+	p.scanHotFolder(folder, 0, files)
+	waitHotFolder(t, func() bool { return kptypes.FileExists(filepath.Join(failed, "d.mp4")) && waiting() })
+
+	// the resources of hot folder are not in config
+	if cfg := p.GetEffectiveConfig().(effectiveResourceConfig); len(cfg.Lists) != 0 {
+		t.Fatalf("unexpected effective config: %v", cfg.Lists)
+	}
+}
@@ -0,0 +1,308 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/bytelang/kplayer/eventbus"
+	"github.com/bytelang/kplayer/module"
+	kptypes "github.com/bytelang/kplayer/types"
+	kpproto "github.com/bytelang/kplayer/types/core/proto"
+	kpmsg "github.com/bytelang/kplayer/types/core/proto/msg"
+	"github.com/bytelang/kplayer/types/core/proto/prompt"
+	moduletypes "github.com/bytelang/kplayer/types/module"
+	svrproto "github.com/bytelang/kplayer/types/server"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	ResourceEventInterruptStart = "interrupt_start"
+	ResourceEventInterruptEnd   = "interrupt_end"
+)
+
+// interrupt the resources played in place of the playlist
+type interrupt struct {
+	unique    string
+	priority  int32
+	resources []moduletypes.Resource
+
+	// the index of resource playing
+	index int
+}
+
+// ResourceInterrupt cut the playing resource and play the resources of interrupt. the interrupt is queued by
+// priority when another one is playing, the interrupt playing is not cut. the playlist resource is resumed from the
+// position it was cut after all the interrupts finished, the live source cut is reconnected instead
+func (p *Provider) ResourceInterrupt(ctx context.Context, args *svrproto.ResourceInterruptArgs) (*svrproto.ResourceInterruptReply, error) {
+	if len(args.Paths) == 0 {
+		return nil, ResourcePathCanNotBeEmpty
+	}
+
+	p.input_mutex.Lock()
+	defer p.input_mutex.Unlock()
+
+	unique := args.Unique
+	if unique == "" {
+		unique = kptypes.GetRandString(6)
+	}
+	if p.inputs.Exist(unique) || p.interruptExist(unique) {
+		return nil, ResourceUniqueHasExisted
+	}
+
+	item := &interrupt{unique: unique, priority: args.Priority}
+	for key, path := range args.Paths {
+		if err := checkResourcePath(path); err != nil {
+			return nil, err
+		}
+		resourceUnique := fmt.Sprintf("%s-%d", unique, key+1)
+		if p.inputs.Exist(resourceUnique) {
+			return nil, ResourceUniqueHasExisted
+		}
+		item.resources = append(item.resources, moduletypes.Resource{
+			Path:       path,
+			Unique:     resourceUnique,
+			Seek:       0,
+			End:        -1,
+			CreateTime: uint64(time.Now().Unix()),
+		})
+	}
+
+	// the interrupt is placed after the queued ones of the same or higher priority
+	position := len(p.interrupts)
+	for key, queued := range p.interrupts {
+		if queued.priority < item.priority {
+			position = key
+			break
+		}
+	}
+	p.interrupts = append(p.interrupts[:position], append([]*interrupt{item}, p.interrupts[position:]...)...)
+	log.WithFields(log.Fields{"unique": unique, "priority": item.priority, "position": position}).Info("queue interrupt success")
+
+	reply := &svrproto.ResourceInterruptReply{Unique: unique, Position: int64(position)}
+	for _, res := range item.resources {
+		reply.Resources = append(reply.Resources, TransferModuleToServerResource(res))
+	}
+	if p.interruptActive != nil {
+		reply.Position = reply.Position + 1
+		return reply, nil
+	}
+	if p.takeoverCutting {
+		return reply, nil
+	}
+
+	// the interrupt is played at once when no resource playing
+	if p.waitingResource {
+		p.interruptedUnique = ""
+		p.startInterrupt()
+		return reply, nil
+	}
+
+	p.takeoverCutting = true
+	if err := p.cutPlaying(func(err error) {
+		// the playing resource is not cut, the interrupt is dropped
+		if p.removeInterrupt(item) {
+			p.takeoverCutting = false
+			log.WithFields(log.Fields{"unique": unique, "error": err}).Warn("interrupt dropped on cutting the playing resource failed")
+		}
+	}); err != nil {
+		p.takeoverCutting = false
+		p.removeInterrupt(item)
+		return nil, err
+	}
+
+	return reply, nil
+}
+
+// cutPlaying send the skip prompt cutting the playing resource for the interrupt, live source or schedule slot.
+// failed is called with the input mutex held when the core answers the skip with error or never answers. the input
+// mutex must be held
+func (p *Provider) cutPlaying(failed func(err error)) error {
+	skipMsg := &kpmsg.EventMessagePlayerSkip{}
+	keeperCtx := module.NewKeeperContext(kptypes.GetRandString(), kpproto.EventMessageAction_EVENT_MESSAGE_ACTION_PLAYER_SKIP, func(msg string) bool {
+		kptypes.UnmarshalProtoMessage(msg, skipMsg)
+		return true
+	})
+	if err := p.RegisterKeeperChannel(keeperCtx); err != nil {
+		return err
+	}
+	if err := p.engine.SendCorrelatedPrompt(keeperCtx.GetId(), kpproto.EventPromptAction_EVENT_PROMPT_ACTION_PLAYER_SKIP, &prompt.EventPromptPlayerSkip{}); err != nil {
+		keeperCtx.Close()
+		return err
+	}
+
+	// the reply is dispatched with the messages taking the input mutex, it is waited aside
+	go func() {
+		defer keeperCtx.Close()
+
+		err := keeperCtx.Wait(context.Background())
+		if err == nil && len(skipMsg.Error) != 0 {
+			err = fmt.Errorf("%s", skipMsg.Error)
+		}
+		if err == nil {
+			return
+		}
+
+		p.input_mutex.Lock()
+		defer p.input_mutex.Unlock()
+		failed(err)
+	}()
+
+	return nil
+}
+
+// removeInterrupt remove the interrupt queued. return false when it is not queued. the input mutex must be held
+func (p *Provider) removeInterrupt(item *interrupt) bool {
+	for key, queued := range p.interrupts {
+		if queued == item {
+			p.interrupts = append(p.interrupts[:key], p.interrupts[key+1:]...)
+			return true
+		}
+	}
+
+	return false
+}
+
+// finishInterrupt play the next resource of interrupt, the next interrupt, or resume the playback after the resource
+// finished. return false when the resource is not played for interrupt. the input mutex must be held
+func (p *Provider) finishInterrupt(unique string) bool {
+	active := p.interruptActive
+	if active == nil || active.resources[active.index].Unique != unique {
+		return false
+	}
+
+	active.index = active.index + 1
+	if active.index < len(active.resources) {
+		p.addResourceToCore(&active.resources[active.index])
+		return true
+	}
+
+	log.WithFields(log.Fields{"unique": active.unique, "priority": active.priority}).Info("interrupt finished")
+	p.interruptActive = nil
+	eventbus.PublishEvent(&eventbus.Event{
+		Module: ModuleName,
+		Name:   ResourceEventInterruptEnd,
+		Body:   map[string]interface{}{"unique": active.unique, "priority": active.priority, "interrupted": p.interruptedUnique, "queued": len(p.interrupts)},
+	})
+	if len(p.interrupts) != 0 {
+		p.startInterrupt()
+		return true
+	}
+
+	p.resumePlayback()
+	return true
+}
+
+// finishCut record the position of the playlist resource cut by the interrupt or live source, and play the one cut
+// for. the interrupts are played before the live source. return false when no resource cutting. the input mutex
+// must be held
+func (p *Provider) finishCut(unique string) bool {
+	if !p.takeoverCutting {
+		return false
+	}
+	p.takeoverCutting = false
+
+	// the playlist resource is replayed from the position cut
+	if res, _, err := p.inputs.GetResourceByUnique(unique); err == nil {
+		if p.currentSeek > res.Seek {
+			if _, ok := p.resetInputs[res.Unique]; !ok {
+				p.resetInputs[res.Unique] = res.Seek
+			}
+			res.Seek = p.currentSeek
+		}
+		p.interruptedUnique = res.Unique
+		log.WithFields(log.Fields{"unique": res.Unique, "path": res.Path, "seek": res.Seek}).Info("resource interrupted")
+	}
+	if len(p.interrupts) != 0 {
+		p.startInterrupt()
+		return true
+	}
+
+	// the playlist is resumed when the live source stopped before the cut finished
+	p.resumePlayback()
+	return true
+}
+
+// startInterrupt play the first resource of the interrupt of highest priority. the input mutex must be held
+func (p *Provider) startInterrupt() {
+	active := p.interrupts[0]
+	p.interrupts = p.interrupts[1:]
+	p.interruptActive = active
+
+	var paths []string
+	for _, res := range active.resources {
+		paths = append(paths, res.Path)
+	}
+	log.WithFields(log.Fields{"unique": active.unique, "priority": active.priority, "interrupted": p.interruptedUnique}).Info("interrupt started")
+	eventbus.PublishEvent(&eventbus.Event{
+		Module: ModuleName,
+		Name:   ResourceEventInterruptStart,
+		Body:   map[string]interface{}{"unique": active.unique, "priority": active.priority, "paths": paths, "interrupted": p.interruptedUnique},
+	})
+
+	p.addResourceToCore(&active.resources[active.index])
+}
+
+// resumePlayback play the live source taken over after all the interrupts finished, otherwise resume the playlist.
+// the slot of schedule started during the interrupts is played instead of the playlist. the input mutex must be held
+func (p *Provider) resumePlayback() {
+	if p.replayLive() {
+		return
+	}
+	if p.interruptedUnique != "" {
+		log.WithField("unique", p.interruptedUnique).Info("resume interrupted resource")
+	}
+	p.interruptedUnique = ""
+
+	p.applyScheduleSlot()
+	if !p.checkCurrentIndex() {
+		return
+	}
+	p.addNextResourceToCore()
+}
+
+// replayInterrupt play the interrupt again on the player started. the resource of interrupt playing is replayed from
+// the beginning. return false when there is no interrupt. the input mutex must be held
+func (p *Provider) replayInterrupt() bool {
+	if active := p.interruptActive; active != nil {
+		p.addResourceToCore(&active.resources[active.index])
+		return true
+	}
+	if len(p.interrupts) == 0 {
+		return false
+	}
+
+	p.startInterrupt()
+	return true
+}
+
+// takeoverResource return the resource of interrupt or live source playing by unique. the input mutex must be held
+func (p *Provider) takeoverResource(unique string) *moduletypes.Resource {
+	if active := p.interruptActive; active != nil && active.resources[active.index].Unique == unique {
+		return &active.resources[active.index]
+	}
+	if p.live != nil && p.live.resource.Unique == unique {
+		return &p.live.resource
+	}
+
+	return nil
+}
+
+// interruptExist whether the unique is used by the interrupt playing or queued. the input mutex must be held
+func (p *Provider) interruptExist(unique string) bool {
+	for _, item := range append([]*interrupt{p.interruptActive}, p.interrupts...) {
+		if item == nil {
+			continue
+		}
+		if item.unique == unique {
+			return true
+		}
+		for _, res := range item.resources {
+			if res.Unique == unique {
+				return true
+			}
+		}
+	}
+
+	return false
+}
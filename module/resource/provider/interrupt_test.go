@@ -0,0 +1,194 @@
+package provider
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/bytelang/kplayer/core"
+	"github.com/bytelang/kplayer/eventbus"
+	playprovider "github.com/bytelang/kplayer/module/play/provider"
+	kptypes "github.com/bytelang/kplayer/types"
+	"github.com/bytelang/kplayer/types/config"
+	kpproto "github.com/bytelang/kplayer/types/core/proto"
+	moduletypes "github.com/bytelang/kplayer/types/module"
+	svrproto "github.com/bytelang/kplayer/types/server"
+)
+
+func TestResourceInterrupt(t *testing.T) {
+	dir := t.TempDir()
+	writeDirectoryFiles(t, dir, "alert1.mp4", "alert2.mp4", "low.mp4", "high.mp4")
+
+	fe := core.NewFakeEngine()
+	pp := playprovider.NewProvider(fe)
+	pp.InitModule(kptypes.DefaultClientContext(), &config.Play{
+		StartPoint: 1,
+		PlayModel:  "loop",
+		Rpc:        &config.Server{},
+		Encode:     &config.Encode{},
+	})
+	p := NewProvider(fe, pp)
+	for _, unique := range []string{"a", "b"} {
+		if err := p.inputs.AppendResource(moduletypes.Resource{Path: "/video/" + unique + ".mp4", Unique: unique, End: -1}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	fe.SetCallBackMessage(func(message *core.Message) {
+		p.ParseMessage(message.KPMessage)
+		p.Trigger(message)
+	})
+	fe.SetCallBackProgress(p.ParseProgress)
+
+	sub, err := eventbus.Subscribe(t.Name(), eventbus.WithKinds(eventbus.KindEvent))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sub.Close()
+
+	resultChan := make(chan int)
+	go func() {
+		resultChan <- fe.Run()
+	}()
+	defer func() {
+		fe.Terminate(0)
+		<-resultChan
+	}()
+	waitPlaying(t, p, "a")
+	fe.Advance(time.Second * 30)
+
+	ctx := context.Background()
+	if _, err := p.ResourceInterrupt(ctx, &svrproto.ResourceInterruptArgs{Paths: []string{filepath.Join(dir, "low.mp4")}, Unique: "a"}); err != ResourceUniqueHasExisted {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := p.ResourceInterrupt(ctx, &svrproto.ResourceInterruptArgs{
+		Paths:  []string{filepath.Join(dir, "alert1.mp4"), filepath.Join(dir, "alert2.mp4")},
+		Unique: "alert",
+	}); err != nil {
+		t.Fatal(err)
+	}
+	waitPlaying(t, p, "alert-1")
+
+	// the interrupts are queued in order of priority
+	for _, item := range []struct {
+		unique   string
+		priority int32
+	}{
+		{"low", 0},
+		{"high", 5},
+	} {
+		reply, err := p.ResourceInterrupt(ctx, &svrproto.ResourceInterruptArgs{
+			Paths:    []string{filepath.Join(dir, item.unique+".mp4")},
+			Unique:   item.unique,
+			Priority: item.priority,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if reply.Position != 1 {
+			t.Fatalf("unexpected position of %s: %d", item.unique, reply.Position)
+		}
+	}
+
+	for _, unique := range []string{"alert-2", "high-1", "low-1", "a"} {
+		fe.Advance(core.FakeDefaultResourceDuration)
+		waitPlaying(t, p, unique)
+	}
+
+	// the interrupted resource is resumed from the position cut
+	reply, err := p.ResourceCurrent(ctx, &svrproto.ResourceCurrentArgs{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reply.Resource.Seek != 30 || p.inputs.resources[0].Seek != 0 {
+		t.Fatalf("unexpected resumed seek: %d, resource seek: %d", reply.Resource.Seek, p.inputs.resources[0].Seek)
+	}
+
+	for _, item := range []struct {
+		name   string
+		unique string
+	}{
+		{ResourceEventInterruptStart, "alert"},
+		{ResourceEventInterruptEnd, "alert"},
+		{ResourceEventInterruptStart, "high"},
+		{ResourceEventInterruptEnd, "high"},
+		{ResourceEventInterruptStart, "low"},
+		{ResourceEventInterruptEnd, "low"},
+	} {
+		select {
+		case envelope := <-sub.C():
+			event := envelope.Event
+			if event.Module != ModuleName || event.Name != item.name || event.Body["unique"] != item.unique {
+				t.Fatalf("unexpected event: %s.%s %v", event.Module, event.Name, event.Body)
+			}
+			if event.Body["interrupted"] != "a" {
+				t.Fatalf("unexpected interrupted resource: %v", event.Body)
+			}
+		case <-time.After(time.Second * 5):
+			t.Fatalf("wait for event %s of %s timeout", item.name, item.unique)
+		}
+	}
+}
+
+// waitTakeover wait until the takeover state of provider satisfies the condition
+func waitTakeover(t *testing.T, p *Provider, condition func() bool) {
+	for i := 0; i < 200; i++ {
+		p.input_mutex.Lock()
+		ok := condition()
+		p.input_mutex.Unlock()
+		if ok {
+			return
+		}
+		time.Sleep(time.Millisecond * 10)
+	}
+	t.Fatal("wait takeover timeout")
+}
+
+func TestResourceInterruptSkipFailed(t *testing.T) {
+	dir := t.TempDir()
+	writeDirectoryFiles(t, dir, "alert.mp4")
+
+	fe := core.NewFakeEngine()
+	pp := playprovider.NewProvider(fe)
+	pp.InitModule(kptypes.DefaultClientContext(), &config.Play{
+		StartPoint: 1,
+		PlayModel:  "loop",
+		Rpc:        &config.Server{},
+		Encode:     &config.Encode{},
+	})
+	p := NewProvider(fe, pp)
+	if err := p.inputs.AppendResource(moduletypes.Resource{Path: "/video/a.mp4", Unique: "a", End: -1}); err != nil {
+		t.Fatal(err)
+	}
+	fe.SetCallBackMessage(func(message *core.Message) {
+		p.ParseMessage(message.KPMessage)
+		p.Trigger(message)
+	})
+
+	resultChan := make(chan int)
+	go func() {
+		resultChan <- fe.Run()
+	}()
+	defer func() {
+		fe.Terminate(0)
+		<-resultChan
+	}()
+	waitPlaying(t, p, "a")
+
+	// the interrupt failed to cut the playing resource is not kept
+	ctx := context.Background()
+	args := &svrproto.ResourceInterruptArgs{Paths: []string{filepath.Join(dir, "alert.mp4")}, Unique: "alert"}
+	fe.SetPromptError(kpproto.EventPromptAction_EVENT_PROMPT_ACTION_PLAYER_SKIP, "skip failed")
+	if _, err := p.ResourceInterrupt(ctx, args); err != nil {
+		t.Fatal(err)
+	}
+	waitTakeover(t, p, func() bool {
+		return !p.takeoverCutting && len(p.interrupts) == 0
+	})
+
+	fe.SetPromptError(kpproto.EventPromptAction_EVENT_PROMPT_ACTION_PLAYER_SKIP, "")
+	if _, err := p.ResourceInterrupt(ctx, args); err != nil {
+		t.Fatal(err)
+	}
+	waitPlaying(t, p, "alert-1")
+}
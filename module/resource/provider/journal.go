@@ -0,0 +1,217 @@
+package provider
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	kptypes "github.com/bytelang/kplayer/types"
+	"github.com/bytelang/kplayer/types/config"
+	log "github.com/sirupsen/logrus"
+)
+
+// resumeJournal the playback position journaled on running
+type resumeJournal struct {
+	Unique        string   `json:"unique"`
+	Path          string   `json:"path"`
+	Seek          int64    `json:"seek"`
+	PlayModel     string   `json:"play_model"`
+	RandomHistory []string `json:"random_history"`
+	UpdateTime    int64    `json:"update_time"`
+}
+
+// StartJournal write the playback position to journal periodically until EndJournal called
+func (p *Provider) StartJournal() {
+	p.journalStop = make(chan bool)
+	p.journalDone = make(chan bool)
+
+	go func() {
+		defer close(p.journalDone)
+
+		ticker := time.NewTicker(journalInterval)
+		defer ticker.Stop()
+
+		var last resumeJournal
+		for {
+			select {
+			case <-p.journalStop:
+				p.writeJournal(&last)
+				return
+			case <-ticker.C:
+				p.writeJournal(&last)
+			}
+		}
+	}()
+}
+
+// EndJournal stop journaling and write the last playback position
+func (p *Provider) EndJournal() {
+	if p.journalStop == nil {
+		return
+	}
+
+	close(p.journalStop)
+	<-p.journalDone
+	p.journalStop = nil
+}
+
+// writeJournal write the playback position when it changed since the last written
+func (p *Provider) writeJournal(last *resumeJournal) {
+	journal, ok := p.currentJournal()
+	if !ok {
+		return
+	}
+
+	// the update time is not compared
+	journal.UpdateTime = last.UpdateTime
+	if journalEqual(journal, *last) {
+		return
+	}
+	journal.UpdateTime = time.Now().Unix()
+
+	if err := saveJournal(journalFilePath, journal); err != nil {
+		log.WithField("error", err).Warn("write playback journal failed")
+		return
+	}
+	*last = journal
+}
+
+func (p *Provider) currentJournal() (resumeJournal, bool) {
+	p.input_mutex.Lock()
+	defer p.input_mutex.Unlock()
+
+	if p.currentIndex < 0 || p.currentIndex >= len(p.inputs.resources) {
+		return resumeJournal{}, false
+	}
+	res := p.inputs.resources[p.currentIndex]
+
+	// the resource cut by interrupt or live source is resumed from the position cut
+	seek := p.currentSeek
+	if p.takenOver() {
+		seek = res.Seek
+	}
+
+	return resumeJournal{
+		Unique:        res.Unique,
+		Path:          res.Path,
+		Seek:          seek,
+		PlayModel:     strings.ToLower(p.playProvider.GetPlayModel().String()),
+		RandomHistory: append([]string{}, p.randomModeUniqueNameHistory...),
+	}, true
+}
+
+// RestoreJournal restore the journaled playback position. the resource is matched by unique, then by path when
+// the playlist has been edited. the random history is restored when the play model not changed
+func (p *Provider) RestoreJournal() {
+	journal, err := loadJournal(journalFilePath)
+	if os.IsNotExist(err) {
+		log.Info("playback journal not found. play from start point")
+		return
+	} else if err != nil {
+		log.WithField("error", err).Warn("read playback journal failed. play from start point")
+		return
+	}
+
+	p.input_mutex.Lock()
+	defer p.input_mutex.Unlock()
+
+	logFields := log.WithFields(log.Fields{"unique": journal.Unique, "path": journal.Path})
+
+	index := -1
+	for key, item := range p.inputs.resources {
+		if item.Unique == journal.Unique {
+			index = key
+			break
+		}
+	}
+	if index < 0 {
+		for key, item := range p.inputs.resources {
+			if item.Path == journal.Path {
+				index = key
+				break
+			}
+		}
+	}
+	if index < 0 {
+		logFields.Warn("journaled resource not in playlist. play from start point")
+		return
+	}
+
+	res := &p.inputs.resources[index]
+	p.currentIndex = index
+	if journal.Seek > res.Seek && (res.End <= 0 || journal.Seek < res.End) {
+		p.resetInputs[res.Unique] = res.Seek
+		res.Seek = journal.Seek
+	}
+	p.currentSeek = res.Seek
+
+	playModel := p.playProvider.GetPlayModel()
+	if playModel == config.PLAY_MODEL_RANDOM && journal.PlayModel == strings.ToLower(playModel.String()) {
+		p.randomModeUniqueNameHistory = []string{}
+		for _, unique := range journal.RandomHistory {
+			if p.inputs.Exist(unique) {
+				p.randomModeUniqueNameHistory = append(p.randomModeUniqueNameHistory, unique)
+			}
+		}
+	}
+
+	log.WithFields(log.Fields{"unique": res.Unique, "path": res.Path, "seek": res.Seek}).Info("resume playback position")
+}
+
+func journalEqual(a, b resumeJournal) bool {
+	if a.Unique != b.Unique || a.Path != b.Path || a.Seek != b.Seek || a.PlayModel != b.PlayModel ||
+		a.UpdateTime != b.UpdateTime || len(a.RandomHistory) != len(b.RandomHistory) {
+		return false
+	}
+	for key := range a.RandomHistory {
+		if a.RandomHistory[key] != b.RandomHistory[key] {
+			return false
+		}
+	}
+
+	return true
+}
+
+func loadJournal(path string) (resumeJournal, error) {
+	journal := resumeJournal{}
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return journal, err
+	}
+	err = json.Unmarshal(content, &journal)
+
+	return journal, err
+}
+
+// saveJournal write the journal atomically. the journal is intact when kplayer crashed on writing
+func saveJournal(path string, journal resumeJournal) error {
+	content, err := json.Marshal(journal)
+	if err != nil {
+		return err
+	}
+	if err := kptypes.MkDir(filepath.Dir(path)); err != nil {
+		return err
+	}
+
+	tmpPath := path + ".tmp"
+	f, err := os.OpenFile(tmpPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(content); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmpPath, path)
+}
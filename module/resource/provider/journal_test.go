@@ -0,0 +1,92 @@
+package provider
+
+import (
+	"os"
+	"testing"
+
+	"github.com/bytelang/kplayer/core"
+	playprovider "github.com/bytelang/kplayer/module/play/provider"
+	moduletypes "github.com/bytelang/kplayer/types/module"
+)
+
+func newJournalProvider(t *testing.T) *Provider {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = os.Chdir(wd) })
+
+	fe := core.NewFakeEngine()
+	p := NewProvider(fe, playprovider.NewProvider(fe))
+	for _, item := range []moduletypes.Resource{
+		{Path: "/video/a.mp4", Unique: "a", End: -1},
+		{Path: "/video/b.mp4", Unique: "b", Seek: 5, End: -1},
+		{Path: "/video/c.mp4", Unique: "c", End: 60},
+	} {
+		if err := p.inputs.AppendResource(item); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return p
+}
+
+func TestJournal(t *testing.T) {
+	p := newJournalProvider(t)
+	p.currentIndex, p.currentSeek = 1, 42
+
+	p.StartJournal()
+	p.EndJournal()
+
+	journal, err := loadJournal(journalFilePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if journal.Unique != "b" || journal.Path != "/video/b.mp4" || journal.Seek != 42 || journal.PlayModel != "list" {
+		t.Fatalf("unexpected journal: %+v", journal)
+	}
+
+	// restore on the playlist edited
+	p.currentIndex, p.currentSeek = 0, 0
+	p.inputs.resources[1].Unique = "renamed"
+	p.RestoreJournal()
+
+	if p.currentIndex != 1 || p.inputs.resources[1].Seek != 42 || p.resetInputs["renamed"] != 5 {
+		t.Fatalf("unexpected restored position. index: %d, seek: %d", p.currentIndex, p.inputs.resources[1].Seek)
+	}
+}
+
+func TestRestoreJournal(t *testing.T) {
+	cases := map[string]struct {
+		journal resumeJournal
+		index   int
+		seek    int64
+	}{
+		"match unique":      {resumeJournal{Unique: "c", Path: "/video/other.mp4", Seek: 30}, 2, 30},
+		"match path":        {resumeJournal{Unique: "other", Path: "/video/a.mp4", Seek: 10}, 0, 10},
+		"seek over end":     {resumeJournal{Unique: "c", Seek: 90}, 2, 0},
+		"seek before start": {resumeJournal{Unique: "b", Seek: 2}, 1, 5},
+		"not found":         {resumeJournal{Unique: "other", Path: "/video/other.mp4", Seek: 10}, -1, 0},
+	}
+
+	for name, item := range cases {
+		t.Run(name, func(t *testing.T) {
+			p := newJournalProvider(t)
+			p.currentIndex = -1
+			if err := saveJournal(journalFilePath, item.journal); err != nil {
+				t.Fatal(err)
+			}
+
+			p.RestoreJournal()
+			if p.currentIndex != item.index {
+				t.Fatalf("unexpected index. expect: %d, got: %d", item.index, p.currentIndex)
+			}
+			if item.index >= 0 && p.inputs.resources[item.index].Seek != item.seek {
+				t.Fatalf("unexpected seek. expect: %d, got: %d", item.seek, p.inputs.resources[item.index].Seek)
+			}
+		})
+	}
+}
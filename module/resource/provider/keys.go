@@ -6,6 +6,7 @@ import (
 	moduletypes "github.com/bytelang/kplayer/types/module"
 	"github.com/bytelang/kplayer/types/server"
 	"sync"
+	"time"
 )
 
 const (
@@ -17,6 +18,13 @@ const (
 	ResourceNotFound            ResourceError = "resource not found"
 	ResourceUniqueHasExisted    ResourceError = "resource unique name has existed"
 	ResourcePathCanNotBeEmpty   ResourceError = "resource path can not be empty"
+	LiveDirectoryHasExisted     ResourceError = "live directory has existed"
+	ResourceIndexOutOfRange     ResourceError = "resource index out of range"
+	CannotPlayNextCurrent       ResourceError = "can not play next the playing resource"
+	NoPreviousResource          ResourceError = "no previous resource"
+	ScheduleSlotHasExisted      ResourceError = "schedule slot unique has existed"
+	LiveSourceHasStarted        ResourceError = "live source has started"
+	LiveSourceNotStarted        ResourceError = "live source not started"
 )
 
 type ResourceError string
@@ -40,7 +48,7 @@ func (rs *Resources) GetResourceByUnique(unique string) (*moduletypes.Resource,
 }
 
 func (rs *Resources) GetResourceByIndex(index int) (*moduletypes.Resource, error) {
-	if index > len(rs.resources) {
+	if index < 0 || index >= len(rs.resources) {
 		return nil, ResourceNotFound
 	}
 
@@ -91,6 +99,54 @@ func (rs *Resources) AppendResource(resource moduletypes.Resource) error {
 	return nil
 }
 
+// InsertResource insert the resource before the index. the resource is appended when the index out of range
+func (rs *Resources) InsertResource(index int, resource moduletypes.Resource) error {
+	rs.lock.Lock()
+	defer rs.lock.Unlock()
+
+	res, _, _ := rs.GetResourceByUnique(resource.Unique)
+	if res != nil {
+		return ResourceUniqueHasExisted
+	}
+
+	if len(resource.Path) == 0 {
+		return ResourcePathCanNotBeEmpty
+	}
+
+	if index < 0 || index > len(rs.resources) {
+		index = len(rs.resources)
+	}
+	var newResource []moduletypes.Resource
+	newResource = append(newResource, rs.resources[:index]...)
+	newResource = append(newResource, resource)
+	newResource = append(newResource, rs.resources[index:]...)
+
+	rs.resources = newResource
+	return nil
+}
+
+// MoveResource move the resource to index. return the index the resource moved from
+func (rs *Resources) MoveResource(unique string, index int) (int, error) {
+	rs.lock.Lock()
+	defer rs.lock.Unlock()
+
+	if index < 0 || index >= len(rs.resources) {
+		return 0, ResourceIndexOutOfRange
+	}
+	res, from, err := rs.GetResourceByUnique(unique)
+	if err != nil {
+		return 0, err
+	}
+
+	var newResource []moduletypes.Resource
+	newResource = append(newResource, rs.resources[:from]...)
+	newResource = append(newResource, rs.resources[from+1:]...)
+	newResource = append(newResource[:index], append([]moduletypes.Resource{*res}, newResource[index:]...)...)
+
+	rs.resources = newResource
+	return from, nil
+}
+
 // CalcMixResourceGroupPrimaryPath
 // Under mixed resources, gets which resource should be selected as the primary resource
 func CalcMixResourceGroupPrimaryPath(groups []*moduletypes.MixResourceGroup) (firstVideoResourceGroup *moduletypes.MixResourceGroup, firstAudioResourceGroup *moduletypes.MixResourceGroup, primaryResourceGroup *moduletypes.MixResourceGroup) {
@@ -165,6 +221,62 @@ func TransferServerToModuleResourceGroup(serverResourceGroups []*server.MixResou
 	return groups
 }
 
+func TransferModuleToServerResourceGroup(moduleResourceGroups []*moduletypes.MixResourceGroup) []*server.MixResourceGroup {
+	var groups []*server.MixResourceGroup
+
+	for _, item := range moduleResourceGroups {
+		mediaType := server.ResourceMediaType_video
+		if item.MediaType == moduletypes.ResourceMediaType_audio {
+			mediaType = server.ResourceMediaType_audio
+		}
+		group := &server.MixResourceGroup{
+			Path:           item.Path,
+			MediaType:      mediaType,
+			PersistentLoop: item.PersistentLoop,
+		}
+
+		groups = append(groups, group)
+	}
+
+	return groups
+}
+
+func TransferModuleToServerResource(res moduletypes.Resource) *server.Resource {
+	return &server.Resource{
+		Path:            res.Path,
+		Unique:          res.Unique,
+		Seek:            res.Seek,
+		End:             res.End,
+		CreateTime:      res.CreateTime,
+		StartTime:       res.StartTime,
+		EndTime:         res.EndTime,
+		MixResourceType: res.MixResourceType,
+		Groups:          TransferModuleToServerResourceGroup(res.Groups),
+		Title:           res.Title,
+		Duration:        res.Duration,
+	}
+}
+
+func TransferModuleToConfigResourceGroup(moduleResourceGroups []*moduletypes.MixResourceGroup) []*config.MixResourceGroup {
+	var groups []*config.MixResourceGroup
+
+	for _, item := range moduleResourceGroups {
+		mediaType := config.ResourceMediaType_video
+		if item.MediaType == moduletypes.ResourceMediaType_audio {
+			mediaType = config.ResourceMediaType_audio
+		}
+		group := &config.MixResourceGroup{
+			Path:           item.Path,
+			MediaType:      mediaType,
+			PersistentLoop: item.PersistentLoop,
+		}
+
+		groups = append(groups, group)
+	}
+
+	return groups
+}
+
 func GetResourceUniqueName(uniqueName string, path string, append ...string) string {
 	if len(uniqueName) != 0 {
 		return uniqueName
@@ -172,3 +284,48 @@ func GetResourceUniqueName(uniqueName string, path string, append ...string) str
 
 	return kptypes.GetUniqueString(path, append...)
 }
+
+const (
+	DirectorySortName    = "name"
+	DirectorySortNatural = "natural"
+	DirectorySortMtime   = "mtime"
+	DirectorySortRandom  = "random"
+)
+
+// DirectorySortOrders the orders of directory files
+var DirectorySortOrders = []string{DirectorySortName, DirectorySortNatural, DirectorySortMtime, DirectorySortRandom}
+
+const (
+	ScheduleBoundaryCut    = "cut"
+	ScheduleBoundaryFinish = "finish"
+)
+
+// scheduleDays the days of week of schedule slot recurrence
+var scheduleDays = map[string][]time.Weekday{
+	"daily":    {time.Sunday, time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday, time.Saturday},
+	"weekdays": {time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday},
+	"weekends": {time.Saturday, time.Sunday},
+	"sun":      {time.Sunday},
+	"mon":      {time.Monday},
+	"tue":      {time.Tuesday},
+	"wed":      {time.Wednesday},
+	"thu":      {time.Thursday},
+	"fri":      {time.Friday},
+	"sat":      {time.Saturday},
+}
+
+const (
+	PlayStatusModePlaylist  = "playlist"
+	PlayStatusModeSchedule  = "schedule"
+	PlayStatusModeLive      = "live"
+	PlayStatusModeInterrupt = "interrupt"
+	PlayStatusModeWaiting   = "waiting"
+)
+
+const (
+	// journal file of playback position in the home directory
+	journalFilePath = "data/resume.json"
+
+	// interval of writing the playback position to journal
+	journalInterval = time.Second * 5
+)
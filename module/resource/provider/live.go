@@ -0,0 +1,325 @@
+package provider
+
+import (
+	"context"
+	"time"
+
+	"github.com/bytelang/kplayer/eventbus"
+	kptypes "github.com/bytelang/kplayer/types"
+	moduletypes "github.com/bytelang/kplayer/types/module"
+	svrproto "github.com/bytelang/kplayer/types/server"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	ResourceEventLiveStart = "live_start"
+	ResourceEventLiveDrop  = "live_drop"
+	ResourceEventLiveEnd   = "live_end"
+)
+
+// liveSource the live source taking over the playlist
+type liveSource struct {
+	resource moduletypes.Resource
+
+	// the retries since the source connected last
+	retries uint32
+
+	// the dropped source waiting for retry. the playlist is played meanwhile
+	retrying   bool
+	retryTime  time.Time
+	retryTimer *time.Timer
+}
+
+// ResourceLive take over the playlist with the live source. the playing resource is cut and resumed from the position
+// cut when the live source stopped or dropped. the dropped source is retried with backoff until the retries exhausted.
+// the interrupt playing is not cut, the live source is played after the interrupts finished
+func (p *Provider) ResourceLive(ctx context.Context, args *svrproto.ResourceLiveArgs) (*svrproto.ResourceLiveReply, error) {
+	if len(args.Path) == 0 {
+		return nil, ResourcePathCanNotBeEmpty
+	}
+	if err := checkResourcePath(args.Path); err != nil {
+		return nil, err
+	}
+
+	p.input_mutex.Lock()
+	defer p.input_mutex.Unlock()
+
+	if p.live != nil {
+		return nil, LiveSourceHasStarted
+	}
+	unique := args.Unique
+	if unique == "" {
+		unique = kptypes.GetRandString(6)
+	}
+	if p.inputs.Exist(unique) || p.interruptExist(unique) {
+		return nil, ResourceUniqueHasExisted
+	}
+
+	p.live = &liveSource{resource: moduletypes.Resource{
+		Path:       args.Path,
+		Unique:     unique,
+		Seek:       0,
+		End:        -1,
+		CreateTime: uint64(time.Now().Unix()),
+	}}
+	log.WithFields(log.Fields{"unique": unique, "path": args.Path}).Info("take over playlist with live source")
+
+	reply := &svrproto.ResourceLiveReply{Resource: TransferModuleToServerResource(p.live.resource)}
+	if p.interruptActive != nil || p.takeoverCutting {
+		return reply, nil
+	}
+	if p.waitingResource {
+		p.addResourceToCore(&p.live.resource)
+		return reply, nil
+	}
+
+	live := p.live
+	p.takeoverCutting = true
+	if err := p.cutPlaying(func(err error) {
+		// the playlist is not cut, the live source is dropped
+		if p.live == live && p.takeoverCutting {
+			p.takeoverCutting = false
+			p.live = nil
+			log.WithFields(log.Fields{"unique": unique, "error": err}).Warn("live source dropped on cutting the playlist failed")
+		}
+	}); err != nil {
+		p.takeoverCutting = false
+		p.live = nil
+		return nil, err
+	}
+
+	return reply, nil
+}
+
+// ResourceLiveStop stop the live source and resume the playlist from the position cut
+func (p *Provider) ResourceLiveStop(ctx context.Context, args *svrproto.ResourceLiveStopArgs) (*svrproto.ResourceLiveStopReply, error) {
+	p.input_mutex.Lock()
+	defer p.input_mutex.Unlock()
+
+	live := p.live
+	if live == nil {
+		return nil, LiveSourceNotStarted
+	}
+	p.endLive("stopped")
+
+	reply := &svrproto.ResourceLiveStopReply{Resource: TransferModuleToServerResource(live.resource)}
+
+	// the playlist is playing or resumed after the interrupts finished
+	if live.retrying || p.interruptActive != nil || p.takeoverCutting {
+		return reply, nil
+	}
+	if p.waitingResource {
+		p.resumePlayback()
+		return reply, nil
+	}
+
+	p.takeoverCutting = true
+	if err := p.cutPlaying(func(err error) {
+		// the live source has been ended, the playlist is not cut
+		if p.live == nil && p.takeoverCutting {
+			p.takeoverCutting = false
+			log.WithFields(log.Fields{"unique": live.resource.Unique, "error": err}).Warn("cut the live source stopped failed")
+		}
+	}); err != nil {
+		p.takeoverCutting = false
+		return nil, err
+	}
+
+	return reply, nil
+}
+
+// EndLive stop retrying the dropped live source
+func (p *Provider) EndLive() {
+	p.input_mutex.Lock()
+	defer p.input_mutex.Unlock()
+
+	if p.live != nil && p.live.retryTimer != nil {
+		p.live.retryTimer.Stop()
+	}
+}
+
+// finishLive retry the live source dropped with backoff and resume the playlist meanwhile. the live source is given
+// up after the retries exhausted. return false when the resource is not the live source. the input mutex must be held
+func (p *Provider) finishLive(unique string, errMsg string) bool {
+	live := p.live
+	if live == nil || live.resource.Unique != unique {
+		return false
+	}
+
+	live.resource.EndTime = uint64(time.Now().Unix())
+	live.retries = live.retries + 1
+	logFields := log.WithFields(log.Fields{"unique": unique, "path": live.resource.Path, "error": errMsg, "retries": live.retries})
+
+	maxRetries := kptypes.DefaultLiveMaxRetries
+	if p.liveConfig != nil {
+		maxRetries = p.liveConfig.MaxRetries
+	}
+	if live.retries > maxRetries {
+		logFields.Warn("live source dropped. the retries exhausted, fall back to the playlist")
+		p.endLive("retries exhausted")
+		p.resumePlayback()
+		return true
+	}
+
+	delay := p.liveBackoff(live.retries)
+	live.retrying = true
+	live.retryTime = time.Now().Add(delay)
+	live.retryTimer = time.AfterFunc(delay, func() {
+		p.retryLive(live)
+	})
+	logFields.WithField("delay", delay.String()).Warn("live source dropped. fall back to the playlist until retried")
+	eventbus.PublishEvent(&eventbus.Event{
+		Module: ModuleName,
+		Name:   ResourceEventLiveDrop,
+		Body:   map[string]interface{}{"unique": unique, "path": live.resource.Path, "error": errMsg, "retries": live.retries, "delay": delay.Seconds()},
+	})
+
+	p.resumePlayback()
+	return true
+}
+
+// retryLive cut the playlist and play the dropped live source again
+func (p *Provider) retryLive(live *liveSource) {
+	p.input_mutex.Lock()
+	defer p.input_mutex.Unlock()
+
+	if p.live != live || !live.retrying {
+		return
+	}
+	live.retrying = false
+	live.retryTimer = nil
+	log.WithFields(log.Fields{"unique": live.resource.Unique, "path": live.resource.Path, "retries": live.retries}).Info("retry live source")
+
+	if p.interruptActive != nil || p.takeoverCutting {
+		return
+	}
+	if p.waitingResource {
+		p.addResourceToCore(&live.resource)
+		return
+	}
+
+	p.takeoverCutting = true
+	failed := func(err error) {
+		if p.live != live || !p.takeoverCutting {
+			return
+		}
+		log.WithFields(log.Fields{"unique": live.resource.Unique, "error": err}).Warn("skip playing resource failed")
+
+		// the playlist is not cut, the live source is retried again
+		p.takeoverCutting = false
+		delay := p.liveBackoff(live.retries)
+		live.retrying = true
+		live.retryTime = time.Now().Add(delay)
+		live.retryTimer = time.AfterFunc(delay, func() {
+			p.retryLive(live)
+		})
+	}
+	if err := p.cutPlaying(failed); err != nil {
+		failed(err)
+	}
+}
+
+// checkedLive reset the retries of live source connected. the input mutex must be held
+func (p *Provider) checkedLive(unique string) {
+	live := p.live
+	if live == nil || live.resource.Unique != unique {
+		return
+	}
+
+	live.retries = 0
+	log.WithFields(log.Fields{"unique": unique, "path": live.resource.Path}).Info("live source connected")
+	eventbus.PublishEvent(&eventbus.Event{
+		Module: ModuleName,
+		Name:   ResourceEventLiveStart,
+		Body:   map[string]interface{}{"unique": unique, "path": live.resource.Path, "interrupted": p.interruptedUnique},
+	})
+}
+
+// replayLive play the live source taken over. return false when there is no live source or it is waiting for retry.
+// the input mutex must be held
+func (p *Provider) replayLive() bool {
+	if p.live == nil || p.live.retrying {
+		return false
+	}
+
+	p.addResourceToCore(&p.live.resource)
+	return true
+}
+
+// endLive give up the live source. the input mutex must be held
+func (p *Provider) endLive(reason string) {
+	live := p.live
+	if live.retryTimer != nil {
+		live.retryTimer.Stop()
+	}
+	p.live = nil
+
+	log.WithFields(log.Fields{"unique": live.resource.Unique, "path": live.resource.Path, "reason": reason}).Info("live source ended")
+	eventbus.PublishEvent(&eventbus.Event{
+		Module: ModuleName,
+		Name:   ResourceEventLiveEnd,
+		Body:   map[string]interface{}{"unique": live.resource.Unique, "path": live.resource.Path, "reason": reason},
+	})
+}
+
+// liveBackoff return the delay before the retry of live source, doubled on every retry. the input mutex must be held
+func (p *Provider) liveBackoff(retries uint32) time.Duration {
+	interval, maxBackoff := kptypes.DefaultLiveRetryInterval, kptypes.DefaultLiveMaxBackoff
+	if p.liveConfig != nil {
+		if p.liveConfig.RetryInterval != 0 {
+			interval = p.liveConfig.RetryInterval
+		}
+		maxBackoff = p.liveConfig.MaxBackoff
+	}
+
+	delay := time.Duration(interval) * time.Second
+	for i := uint32(1); i < retries; i++ {
+		delay = delay * 2
+		if maxBackoff > 0 && delay >= time.Duration(maxBackoff)*time.Second {
+			return time.Duration(maxBackoff) * time.Second
+		}
+	}
+
+	return delay
+}
+
+// takenOver whether the playlist is taken over by the interrupt or live source. the input mutex must be held
+func (p *Provider) takenOver() bool {
+	return p.interruptActive != nil || p.live != nil && !p.live.retrying
+}
+
+// PlaybackStatus return the playback mode of resource module and the resource playing
+func (p *Provider) PlaybackStatus() *svrproto.PlayStatusReply {
+	p.input_mutex.Lock()
+	defer p.input_mutex.Unlock()
+
+	reply := &svrproto.PlayStatusReply{Mode: PlayStatusModePlaylist}
+	switch {
+	case p.interruptActive != nil:
+		active := p.interruptActive
+		reply.Mode, reply.Unique = PlayStatusModeInterrupt, active.unique
+		reply.Resource = TransferModuleToServerResource(active.resources[active.index])
+	case p.live != nil && !p.live.retrying:
+		reply.Mode = PlayStatusModeLive
+		reply.Resource = TransferModuleToServerResource(p.live.resource)
+	case p.waitingResource:
+		reply.Mode = PlayStatusModeWaiting
+	default:
+		if p.scheduleActive != nil {
+			reply.Mode, reply.Unique = PlayStatusModeSchedule, p.scheduleActive.unique
+		}
+		if res, err := p.inputs.GetResourceByIndex(p.currentIndex); err == nil {
+			reply.Resource = TransferModuleToServerResource(*res)
+		}
+	}
+
+	if live := p.live; live != nil {
+		reply.Live = TransferModuleToServerResource(live.resource)
+		reply.LiveRetries = live.retries
+		if live.retrying {
+			reply.LiveRetryTime = uint64(live.retryTime.Unix())
+		}
+	}
+
+	return reply
+}
@@ -0,0 +1,192 @@
+package provider
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/bytelang/kplayer/core"
+	"github.com/bytelang/kplayer/eventbus"
+	playprovider "github.com/bytelang/kplayer/module/play/provider"
+	kptypes "github.com/bytelang/kplayer/types"
+	"github.com/bytelang/kplayer/types/config"
+	kpproto "github.com/bytelang/kplayer/types/core/proto"
+	moduletypes "github.com/bytelang/kplayer/types/module"
+	svrproto "github.com/bytelang/kplayer/types/server"
+)
+
+func TestResourceLive(t *testing.T) {
+	livePath := "rtmp://127.0.0.1/live/stream"
+
+	fe := core.NewFakeEngine()
+	pp := playprovider.NewProvider(fe)
+	pp.InitModule(kptypes.DefaultClientContext(), &config.Play{
+		StartPoint: 1,
+		PlayModel:  "loop",
+		Rpc:        &config.Server{},
+		Encode:     &config.Encode{},
+	})
+	p := NewProvider(fe, pp)
+	p.liveConfig = &config.Live{MaxRetries: 1, RetryInterval: 1}
+	for _, unique := range []string{"a", "b"} {
+		if err := p.inputs.AppendResource(moduletypes.Resource{Path: "/video/" + unique + ".mp4", Unique: unique, End: -1}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	pp.SetResourceStatusReporter(p.PlaybackStatus)
+	fe.SetCallBackMessage(func(message *core.Message) {
+		p.ParseMessage(message.KPMessage)
+		p.Trigger(message)
+	})
+	fe.SetCallBackProgress(p.ParseProgress)
+
+	sub, err := eventbus.Subscribe(t.Name(), eventbus.WithKinds(eventbus.KindEvent))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sub.Close()
+
+	resultChan := make(chan int)
+	go func() {
+		resultChan <- fe.Run()
+	}()
+	defer func() {
+		fe.Terminate(0)
+		<-resultChan
+	}()
+	waitPlaying(t, p, "a")
+	fe.Advance(time.Second * 30)
+
+	ctx := context.Background()
+	status := func(mode string) *svrproto.PlayStatusReply {
+		t.Helper()
+		reply, err := pp.PlayStatus(ctx, &svrproto.PlayStatusArgs{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if reply.Mode != mode {
+			t.Fatalf("unexpected mode: %s, expected: %s", reply.Mode, mode)
+		}
+		return reply
+	}
+	status(PlayStatusModePlaylist)
+
+	if _, err := p.ResourceLive(ctx, &svrproto.ResourceLiveArgs{Path: livePath, Unique: "live"}); err != nil {
+		t.Fatal(err)
+	}
+	waitPlaying(t, p, "live")
+	if _, err := p.ResourceLive(ctx, &svrproto.ResourceLiveArgs{Path: livePath}); err != LiveSourceHasStarted {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	status(PlayStatusModeLive)
+
+	// the playlist is resumed from the position cut while the dropped live source waiting for retry
+	fe.SetResourceError(livePath, "connection refused")
+	fe.Advance(core.FakeDefaultResourceDuration)
+	waitPlaying(t, p, "a")
+	reply := status(PlayStatusModePlaylist)
+	if reply.Resource.Unique != "a" || reply.Live == nil || reply.LiveRetries != 1 || reply.LiveRetryTime == 0 {
+		t.Fatalf("unexpected status: %+v", reply)
+	}
+	current, err := p.ResourceCurrent(ctx, &svrproto.ResourceCurrentArgs{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if current.Resource.Seek != 30 {
+		t.Fatalf("unexpected resumed seek: %d", current.Resource.Seek)
+	}
+
+	for _, item := range []struct {
+		name   string
+		unique string
+	}{
+		{ResourceEventLiveStart, "live"},
+		{ResourceEventLiveDrop, "live"},
+		{ResourceEventLiveEnd, "live"},
+	} {
+		select {
+		case envelope := <-sub.C():
+			event := envelope.Event
+			if event.Module != ModuleName || event.Name != item.name || event.Body["unique"] != item.unique {
+				t.Fatalf("unexpected event: %s.%s %v", event.Module, event.Name, event.Body)
+			}
+		case <-time.After(time.Second * 5):
+			t.Fatalf("wait for event %s of %s timeout", item.name, item.unique)
+		}
+	}
+
+	// the live source is given up after the retries exhausted
+	waitPlaying(t, p, "a")
+	if reply := status(PlayStatusModePlaylist); reply.Live != nil || reply.Resource.Unique != "a" {
+		t.Fatalf("unexpected status: %+v", reply)
+	}
+
+	// the playlist is resumed on the live source stopped
+	if _, err := p.ResourceLiveStop(ctx, &svrproto.ResourceLiveStopArgs{}); err != LiveSourceNotStarted {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := p.ResourceLive(ctx, &svrproto.ResourceLiveArgs{Path: "rtmp://127.0.0.1/live/backup", Unique: "backup"}); err != nil {
+		t.Fatal(err)
+	}
+	waitPlaying(t, p, "backup")
+	if _, err := p.ResourceLiveStop(ctx, &svrproto.ResourceLiveStopArgs{}); err != nil {
+		t.Fatal(err)
+	}
+	waitPlaying(t, p, "a")
+	status(PlayStatusModePlaylist)
+}
+
+func TestResourceLiveSkipFailed(t *testing.T) {
+	livePath := "rtmp://127.0.0.1/live/stream"
+
+	fe := core.NewFakeEngine()
+	pp := playprovider.NewProvider(fe)
+	pp.InitModule(kptypes.DefaultClientContext(), &config.Play{
+		StartPoint: 1,
+		PlayModel:  "loop",
+		Rpc:        &config.Server{},
+		Encode:     &config.Encode{},
+	})
+	p := NewProvider(fe, pp)
+	if err := p.inputs.AppendResource(moduletypes.Resource{Path: "/video/a.mp4", Unique: "a", End: -1}); err != nil {
+		t.Fatal(err)
+	}
+	fe.SetCallBackMessage(func(message *core.Message) {
+		p.ParseMessage(message.KPMessage)
+		p.Trigger(message)
+	})
+
+	resultChan := make(chan int)
+	go func() {
+		resultChan <- fe.Run()
+	}()
+	defer func() {
+		fe.Terminate(0)
+		<-resultChan
+	}()
+	waitPlaying(t, p, "a")
+
+	// the live source failed to cut the playlist is not kept
+	ctx := context.Background()
+	fe.SetPromptError(kpproto.EventPromptAction_EVENT_PROMPT_ACTION_PLAYER_SKIP, "skip failed")
+	if _, err := p.ResourceLive(ctx, &svrproto.ResourceLiveArgs{Path: livePath, Unique: "live"}); err != nil {
+		t.Fatal(err)
+	}
+	waitTakeover(t, p, func() bool {
+		return !p.takeoverCutting && p.live == nil
+	})
+
+	fe.SetPromptError(kpproto.EventPromptAction_EVENT_PROMPT_ACTION_PLAYER_SKIP, "")
+	if _, err := p.ResourceLive(ctx, &svrproto.ResourceLiveArgs{Path: livePath, Unique: "live"}); err != nil {
+		t.Fatal(err)
+	}
+	waitPlaying(t, p, "live")
+
+	fe.SetPromptError(kpproto.EventPromptAction_EVENT_PROMPT_ACTION_PLAYER_SKIP, "skip failed")
+	if _, err := p.ResourceLiveStop(ctx, &svrproto.ResourceLiveStopArgs{}); err != nil {
+		t.Fatal(err)
+	}
+	waitTakeover(t, p, func() bool {
+		return !p.takeoverCutting && p.live == nil
+	})
+}
@@ -0,0 +1,429 @@
+package provider
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	kptypes "github.com/bytelang/kplayer/types"
+	"github.com/bytelang/kplayer/types/config"
+	moduletypes "github.com/bytelang/kplayer/types/module"
+)
+
+const (
+	PlaylistFormatM3U  = "m3u"
+	PlaylistFormatM3U8 = "m3u8"
+	PlaylistFormatPLS  = "pls"
+	PlaylistFormatXSPF = "xspf"
+	PlaylistFormatJSON = "json"
+)
+
+const (
+	xspfNamespace         = "http://xspf.org/ns/0/"
+	xspfVlcNamespace      = "http://www.videolan.org/vlc/playlist/ns/0/"
+	xspfVlcApplication    = "http://www.videolan.org/vlc/playlist/0"
+	playlistOptionStart   = "start-time"
+	playlistOptionStop    = "stop-time"
+	m3uVlcOptionDirective = "#EXTVLCOPT:"
+)
+
+// PlaylistImportFormats the formats of playlist file can be imported
+var PlaylistImportFormats = []string{PlaylistFormatM3U, PlaylistFormatM3U8, PlaylistFormatPLS, PlaylistFormatXSPF}
+
+// PlaylistExportFormats the formats of playlist can be exported
+var PlaylistExportFormats = []string{PlaylistFormatM3U, PlaylistFormatM3U8, PlaylistFormatPLS, PlaylistFormatXSPF, PlaylistFormatJSON}
+
+type xspfPlaylist struct {
+	Tracks []xspfTrack `xml:"trackList>track"`
+}
+
+type xspfTrack struct {
+	Location   string          `xml:"location"`
+	Title      string          `xml:"title"`
+	Duration   int64           `xml:"duration"`
+	Extensions []xspfExtension `xml:"extension"`
+}
+
+type xspfExtension struct {
+	Application string   `xml:"application,attr"`
+	Options     []string `xml:"option"`
+}
+
+// the vlc options are written with namespace prefix
+type xspfExportPlaylist struct {
+	XMLName  xml.Name          `xml:"playlist"`
+	Version  string            `xml:"version,attr"`
+	Xmlns    string            `xml:"xmlns,attr"`
+	XmlnsVlc string            `xml:"xmlns:vlc,attr"`
+	Tracks   []xspfExportTrack `xml:"trackList>track"`
+}
+
+type xspfExportTrack struct {
+	Location  string               `xml:"location"`
+	Title     string               `xml:"title,omitempty"`
+	Duration  int64                `xml:"duration,omitempty"`
+	Extension *xspfExportExtension `xml:"extension,omitempty"`
+}
+
+type xspfExportExtension struct {
+	Application string   `xml:"application,attr"`
+	Options     []string `xml:"vlc:option"`
+}
+
+// IsPlaylistFile whether the path is a local playlist file. the url and the hls playlist are regarded as stream
+func IsPlaylistFile(path string) bool {
+	if !kptypes.FileExists(path) {
+		return false
+	}
+	format := strings.TrimPrefix(strings.ToLower(filepath.Ext(path)), ".")
+	if !kptypes.ArrayInString(PlaylistImportFormats, format) {
+		return false
+	}
+	if format == PlaylistFormatM3U || format == PlaylistFormatM3U8 {
+		content, err := ioutil.ReadFile(path)
+		if err != nil || bytes.Contains(content, []byte("#EXT-X-")) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// ParsePlaylistFile return the resources listed in playlist file. the format is detected by file extension when it is
+// empty. the relative paths are relative to the playlist file. the title and duration are kept as resource metadata
+func ParsePlaylistFile(path string, format string) ([]moduletypes.Resource, error) {
+	if format == "" {
+		format = strings.TrimPrefix(strings.ToLower(filepath.Ext(path)), ".")
+	}
+	if !kptypes.ArrayInString(PlaylistImportFormats, format) {
+		return nil, fmt.Errorf("invalid playlist format %s. available: %s", format, strings.Join(PlaylistImportFormats, ", "))
+	}
+
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	content = bytes.TrimPrefix(content, []byte("\xef\xbb\xbf"))
+
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	dir := filepath.Dir(absPath)
+
+	var resources []moduletypes.Resource
+	switch format {
+	case PlaylistFormatM3U, PlaylistFormatM3U8:
+		resources, err = parseM3U(content, dir)
+	case PlaylistFormatPLS:
+		resources, err = parsePLS(content, dir)
+	case PlaylistFormatXSPF:
+		resources, err = parseXSPF(content, dir)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("parse playlist failed. path: %s, error: %s", path, err)
+	}
+
+	for key := range resources {
+		resources[key].CreateTime = uint64(time.Now().Unix())
+	}
+	return resources, nil
+}
+
+func parseM3U(content []byte, dir string) ([]moduletypes.Resource, error) {
+	var resources []moduletypes.Resource
+	res := moduletypes.Resource{End: -1}
+
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case line == "":
+		case strings.HasPrefix(line, "#EXTINF:"):
+			// #EXTINF:<duration> [attributes],<title>
+			info := strings.SplitN(strings.TrimPrefix(line, "#EXTINF:"), ",", 2)
+			if duration, err := strconv.ParseFloat(strings.Fields(info[0] + " ")[0], 64); err == nil && duration > 0 {
+				res.Duration = int64(duration)
+			}
+			if len(info) == 2 {
+				res.Title = strings.TrimSpace(info[1])
+			}
+		case strings.HasPrefix(line, m3uVlcOptionDirective):
+			setPlaylistOption(&res, strings.TrimPrefix(line, m3uVlcOptionDirective))
+		case strings.HasPrefix(line, "#"):
+		default:
+			res.Path = resolvePlaylistLocation(line, dir)
+			resources = append(resources, res)
+			res = moduletypes.Resource{End: -1}
+		}
+	}
+
+	return resources, scanner.Err()
+}
+
+func parsePLS(content []byte, dir string) ([]moduletypes.Resource, error) {
+	entries := map[int]*moduletypes.Resource{}
+	getEntry := func(index int) *moduletypes.Resource {
+		if _, ok := entries[index]; !ok {
+			entries[index] = &moduletypes.Resource{End: -1}
+		}
+		return entries[index]
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		kv := strings.SplitN(line, "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		key, value := strings.ToLower(strings.TrimSpace(kv[0])), strings.TrimSpace(kv[1])
+
+		for _, field := range []string{"file", "title", "length"} {
+			if !strings.HasPrefix(key, field) {
+				continue
+			}
+			index, err := strconv.Atoi(strings.TrimPrefix(key, field))
+			if err != nil {
+				continue
+			}
+
+			entry := getEntry(index)
+			switch field {
+			case "file":
+				entry.Path = resolvePlaylistLocation(value, dir)
+			case "title":
+				entry.Title = value
+			case "length":
+				if duration, err := strconv.ParseInt(value, 10, 64); err == nil && duration > 0 {
+					entry.Duration = duration
+				}
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	var indexes []int
+	for index := range entries {
+		indexes = append(indexes, index)
+	}
+	sort.Ints(indexes)
+
+	var resources []moduletypes.Resource
+	for _, index := range indexes {
+		if entries[index].Path == "" {
+			return nil, fmt.Errorf("file of entry %d not found", index)
+		}
+		resources = append(resources, *entries[index])
+	}
+
+	return resources, nil
+}
+
+func parseXSPF(content []byte, dir string) ([]moduletypes.Resource, error) {
+	playlist := xspfPlaylist{}
+	if err := xml.Unmarshal(content, &playlist); err != nil {
+		return nil, err
+	}
+
+	var resources []moduletypes.Resource
+	for _, track := range playlist.Tracks {
+		location := strings.TrimSpace(track.Location)
+		if location == "" {
+			return nil, fmt.Errorf("location of track not found")
+		}
+
+		res := moduletypes.Resource{
+			Path:  resolvePlaylistLocation(location, dir),
+			Title: strings.TrimSpace(track.Title),
+			End:   -1,
+		}
+		// the xspf duration is milliseconds
+		if track.Duration > 0 {
+			res.Duration = track.Duration / 1000
+		}
+		for _, extension := range track.Extensions {
+			for _, option := range extension.Options {
+				setPlaylistOption(&res, option)
+			}
+		}
+		resources = append(resources, res)
+	}
+
+	return resources, nil
+}
+
+// setPlaylistOption set the seek and end of resource by the vlc option start-time=<seconds> and stop-time=<seconds>
+func setPlaylistOption(res *moduletypes.Resource, option string) {
+	kv := strings.SplitN(strings.TrimSpace(option), "=", 2)
+	if len(kv) != 2 {
+		return
+	}
+	value, err := strconv.ParseFloat(strings.TrimSpace(kv[1]), 64)
+	if err != nil || value < 0 {
+		return
+	}
+
+	switch kv[0] {
+	case playlistOptionStart:
+		res.Seek = int64(value)
+	case playlistOptionStop:
+		res.End = int64(value)
+	}
+}
+
+// resolvePlaylistLocation return the resource path of playlist location. the file url is converted to local path
+func resolvePlaylistLocation(location string, dir string) string {
+	if u, err := url.Parse(location); err == nil && len(u.Scheme) > 1 {
+		if u.Scheme == "file" {
+			return u.Path
+		}
+		return location
+	}
+	if filepath.IsAbs(location) {
+		return location
+	}
+
+	return filepath.Join(dir, location)
+}
+
+// ExportPlaylist return the playlist of resources. the seek and end are exported as the vlc options in m3u and xspf,
+// the pls cannot keep them. the mix resource is exported by its primary path except json
+func ExportPlaylist(resources []moduletypes.Resource, format string) ([]byte, error) {
+	switch format {
+	case PlaylistFormatM3U, PlaylistFormatM3U8:
+		return exportM3U(resources), nil
+	case PlaylistFormatPLS:
+		return exportPLS(resources), nil
+	case PlaylistFormatXSPF:
+		return exportXSPF(resources)
+	case PlaylistFormatJSON:
+		content, err := json.MarshalIndent(transferModuleToConfigResourceList(resources), "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		return append(content, '\n'), nil
+	}
+
+	return nil, fmt.Errorf("invalid playlist format %s. available: %s", format, strings.Join(PlaylistExportFormats, ", "))
+}
+
+func exportM3U(resources []moduletypes.Resource) []byte {
+	buf := bytes.NewBufferString("#EXTM3U\n")
+	for _, item := range resources {
+		duration := item.Duration
+		if duration <= 0 {
+			duration = -1
+		}
+		fmt.Fprintf(buf, "#EXTINF:%d,%s\n", duration, getResourceTitle(item))
+		for _, option := range getPlaylistOptions(item) {
+			fmt.Fprintf(buf, "%s%s\n", m3uVlcOptionDirective, option)
+		}
+		fmt.Fprintln(buf, item.Path)
+	}
+
+	return buf.Bytes()
+}
+
+func exportPLS(resources []moduletypes.Resource) []byte {
+	buf := bytes.NewBufferString("[playlist]\n")
+	for key, item := range resources {
+		duration := item.Duration
+		if duration <= 0 {
+			duration = -1
+		}
+		fmt.Fprintf(buf, "File%d=%s\n", key+1, item.Path)
+		fmt.Fprintf(buf, "Title%d=%s\n", key+1, getResourceTitle(item))
+		fmt.Fprintf(buf, "Length%d=%d\n", key+1, duration)
+	}
+	fmt.Fprintf(buf, "NumberOfEntries=%d\n", len(resources))
+	fmt.Fprintln(buf, "Version=2")
+
+	return buf.Bytes()
+}
+
+func exportXSPF(resources []moduletypes.Resource) ([]byte, error) {
+	playlist := xspfExportPlaylist{
+		Version:  "1",
+		Xmlns:    xspfNamespace,
+		XmlnsVlc: xspfVlcNamespace,
+	}
+	for _, item := range resources {
+		location := item.Path
+		if u, err := url.Parse(item.Path); err == nil && len(u.Scheme) <= 1 && filepath.IsAbs(item.Path) {
+			location = (&url.URL{Scheme: "file", Path: item.Path}).String()
+		}
+
+		track := xspfExportTrack{
+			Location: location,
+			Title:    item.Title,
+			Duration: item.Duration * 1000,
+		}
+		if options := getPlaylistOptions(item); len(options) != 0 {
+			track.Extension = &xspfExportExtension{Application: xspfVlcApplication, Options: options}
+		}
+		playlist.Tracks = append(playlist.Tracks, track)
+	}
+
+	content, err := xml.MarshalIndent(playlist, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	return append([]byte(xml.Header), append(content, '\n')...), nil
+}
+
+func getPlaylistOptions(res moduletypes.Resource) []string {
+	var options []string
+	if res.Seek > 0 {
+		options = append(options, fmt.Sprintf("%s=%d", playlistOptionStart, res.Seek))
+	}
+	if res.End > 0 {
+		options = append(options, fmt.Sprintf("%s=%d", playlistOptionStop, res.End))
+	}
+
+	return options
+}
+
+func getResourceTitle(res moduletypes.Resource) string {
+	if res.Title != "" {
+		return res.Title
+	}
+
+	return strings.TrimSuffix(filepath.Base(res.Path), filepath.Ext(res.Path))
+}
+
+// transferModuleToConfigResourceList return the resource items of config
+func transferModuleToConfigResourceList(resources []moduletypes.Resource) []interface{} {
+	lists := []interface{}{}
+	for _, item := range resources {
+		if item.MixResourceType {
+			lists = append(lists, &config.MixResource{
+				Unique: item.Unique,
+				Seek:   item.Seek,
+				End:    item.End,
+				Groups: TransferModuleToConfigResourceGroup(item.Groups),
+			})
+			continue
+		}
+		lists = append(lists, &config.SingleResource{
+			Unique: item.Unique,
+			Path:   item.Path,
+			Seek:   item.Seek,
+			End:    item.End,
+		})
+	}
+
+	return lists
+}
@@ -0,0 +1,150 @@
+package provider
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	moduletypes "github.com/bytelang/kplayer/types/module"
+)
+
+func TestParsePlaylistFile(t *testing.T) {
+	dir := t.TempDir()
+	expect := []moduletypes.Resource{
+		{Path: filepath.Join(dir, "video", "a.mp4"), Title: "Track A", Duration: 120, Seek: 10, End: 100},
+		{Path: "/video/b.mp4", Title: "Track B", End: -1},
+		{Path: "rtmp://127.0.0.1/live/c", End: -1},
+	}
+
+	playlists := map[string]string{
+		"list.m3u8": `#EXTM3U
+#EXTINF:120 tvg-id="a",Track A
+#EXTVLCOPT:start-time=10
+#EXTVLCOPT:stop-time=100
+video/a.mp4
+#EXTINF:-1,Track B
+/video/b.mp4
+
+rtmp://127.0.0.1/live/c
+`,
+		"list.pls": `[playlist]
+File1=video/a.mp4
+Title1=Track A
+Length1=120
+File2=/video/b.mp4
+Title2=Track B
+Length2=-1
+File3=rtmp://127.0.0.1/live/c
+NumberOfEntries=3
+Version=2
+`,
+		"list.xspf": `<?xml version="1.0" encoding="UTF-8"?>
+<playlist version="1" xmlns="http://xspf.org/ns/0/" xmlns:vlc="http://www.videolan.org/vlc/playlist/ns/0/">
+  <trackList>
+    <track>
+      <location>video/a.mp4</location>
+      <title>Track A</title>
+      <duration>120000</duration>
+      <extension application="http://www.videolan.org/vlc/playlist/0">
+        <vlc:option>start-time=10</vlc:option>
+        <vlc:option>stop-time=100</vlc:option>
+      </extension>
+    </track>
+    <track><location>file:///video/b.mp4</location><title>Track B</title></track>
+    <track><location>rtmp://127.0.0.1/live/c</location></track>
+  </trackList>
+</playlist>
+`,
+	}
+
+	for name, content := range playlists {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(dir, name)
+			if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+				t.Fatal(err)
+			}
+			if !IsPlaylistFile(path) {
+				t.Fatal("unexpected not playlist file")
+			}
+
+			resources, err := ParsePlaylistFile(path, "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(resources) != len(expect) {
+				t.Fatalf("unexpected resources count: %d", len(resources))
+			}
+			for key, item := range expect {
+				res := resources[key]
+				// pls cannot keep the seek and end
+				if strings.HasSuffix(name, ".pls") {
+					item.Seek, item.End = 0, -1
+				}
+				if res.Path != item.Path || res.Title != item.Title || res.Duration != item.Duration || res.Seek != item.Seek || res.End != item.End {
+					t.Fatalf("unexpected resource %d: %+v", key, res)
+				}
+			}
+		})
+	}
+}
+
+func TestIsPlaylistFile(t *testing.T) {
+	dir := t.TempDir()
+	hls := filepath.Join(dir, "index.m3u8")
+	if err := ioutil.WriteFile(hls, []byte("#EXTM3U\n#EXT-X-TARGETDURATION:10\n#EXTINF:10,\nsegment0.ts\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	for path, expect := range map[string]bool{
+		hls:                                 false,
+		filepath.Join(dir, "not_exist.m3u"): false,
+		"http://127.0.0.1/live/index.m3u8":  false,
+	} {
+		if IsPlaylistFile(path) != expect {
+			t.Fatalf("unexpected playlist file result. path: %s", path)
+		}
+	}
+}
+
+func TestExportPlaylist(t *testing.T) {
+	resources := []moduletypes.Resource{
+		{Path: "/video/a.mp4", Unique: "a", Title: "Track A", Duration: 120, Seek: 10, End: 100},
+		{Path: "rtmp://127.0.0.1/live/b", Unique: "b", End: -1},
+	}
+
+	dir := t.TempDir()
+	for _, format := range []string{PlaylistFormatM3U, PlaylistFormatXSPF, PlaylistFormatPLS} {
+		content, err := ExportPlaylist(resources, format)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// exported playlist can be imported
+		path := filepath.Join(dir, "export."+format)
+		if err := ioutil.WriteFile(path, content, 0644); err != nil {
+			t.Fatal(err)
+		}
+		imported, err := ParsePlaylistFile(path, "")
+		if err != nil {
+			t.Fatalf("import exported %s failed. error: %s, content: %s", format, err, content)
+		}
+		if len(imported) != 2 || imported[0].Path != "/video/a.mp4" || imported[0].Duration != 120 || imported[1].Path != "rtmp://127.0.0.1/live/b" {
+			t.Fatalf("unexpected imported %s: %v", format, imported)
+		}
+		if format != PlaylistFormatPLS && (imported[0].Seek != 10 || imported[0].End != 100) {
+			t.Fatalf("unexpected imported %s seek and end: %v", format, imported[0])
+		}
+	}
+
+	content, err := ExportPlaylist(resources, PlaylistFormatJSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(content), `"unique": "a"`) {
+		t.Fatalf("unexpected json playlist: %s", content)
+	}
+	if _, err := ExportPlaylist(resources, "wpl"); err == nil {
+		t.Fatal("unexpected export invalid format success")
+	}
+}
@@ -3,9 +3,9 @@ package provider
 import (
 	"context"
 	"fmt"
-	"github.com/bytelang/kplayer/core"
 	"github.com/bytelang/kplayer/module"
 	kptypes "github.com/bytelang/kplayer/types"
+	"github.com/bytelang/kplayer/types/config"
 	kpproto "github.com/bytelang/kplayer/types/core/proto"
 	"github.com/bytelang/kplayer/types/core/proto/msg"
 	kpprompt "github.com/bytelang/kplayer/types/core/proto/prompt"
@@ -22,16 +22,8 @@ func (p *Provider) ResourceAdd(ctx context.Context, args *svrproto.ResourceAddAr
 
 	// uri scheme parse
 	if !args.MixResourceType {
-		parseUrl, err := url.Parse(args.Path)
-		if err != nil {
-			return nil, fmt.Errorf("uri scheme invalid. path: %s", args.Path)
-		}
-		if parseUrl.Scheme == "" {
-			// determine whether the file exists
-			_, err := os.Stat(args.Path)
-			if os.IsNotExist(err) {
-				return nil, fmt.Errorf("file not exists. path: %s", args.Path)
-			}
+		if err := checkResourcePath(args.Path); err != nil {
+			return nil, err
 		}
 	} else {
 		for _, item := range args.Groups {
@@ -49,10 +41,18 @@ func (p *Provider) ResourceAdd(ctx context.Context, args *svrproto.ResourceAddAr
 		}
 	}
 
-	if args.End < args.Seek {
+	// end is not set on 0 or less, the resource is played to the end
+	if args.End > 0 && args.End < args.Seek {
 		return nil, fmt.Errorf("end timestamp can not be less than start timestamp")
 	}
 
+	// append the files of directory
+	if !args.MixResourceType {
+		if stat, err := os.Stat(args.Path); err == nil && stat.IsDir() {
+			return p.resourceAddDirectory(args)
+		}
+	}
+
 	// append to playlist
 	primaryPath := args.Path
 	moduleGroups := TransferServerToModuleResourceGroup(args.Groups)
@@ -73,6 +73,7 @@ func (p *Provider) ResourceAdd(ctx context.Context, args *svrproto.ResourceAddAr
 	if err := p.inputs.AppendResource(moduleResource); err != nil {
 		return nil, err
 	}
+	p.resumeWaitingResource()
 
 	reply := &svrproto.ResourceAddReply{Resource: &svrproto.Resource{}}
 	reply.Resource.Unique = moduleResource.Unique
@@ -85,6 +86,57 @@ func (p *Provider) ResourceAdd(ctx context.Context, args *svrproto.ResourceAddAr
 	return reply, nil
 }
 
+// resourceAddDirectory append the files of directory path with the unique suffixed by their number.
+// the input mutex must be held
+func (p *Provider) resourceAddDirectory(args *svrproto.ResourceAddArgs) (*svrproto.ResourceAddReply, error) {
+	files, err := ScanDirectoryResource(&config.DirectoryResource{
+		Directory:      args.Path,
+		Recursive:      args.Recursive,
+		Include:        args.Include,
+		Exclude:        args.Exclude,
+		Sort:           args.Sort,
+		Hidden:         args.Hidden,
+		FollowSymlinks: args.FollowSymlinks,
+	}, p.allowExtensions)
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("directory has no resource of allowed extensions. path: %s", args.Path)
+	}
+
+	var resources []moduletypes.Resource
+	for key, f := range files {
+		res := moduletypes.Resource{
+			Path:       f,
+			Unique:     fmt.Sprintf("%s-%d", args.Unique, key+1),
+			End:        -1,
+			CreateTime: uint64(time.Now().Unix()),
+		}
+		if p.inputs.Exist(res.Unique) {
+			return nil, fmt.Errorf("%s. unique: %s", ResourceUniqueHasExisted, res.Unique)
+		}
+		resources = append(resources, res)
+	}
+
+	reply := &svrproto.ResourceAddReply{}
+	for _, item := range resources {
+		if err := p.inputs.AppendResource(item); err != nil {
+			return nil, fmt.Errorf("%s. path: %s, unique: %s", err, item.Path, item.Unique)
+		}
+		reply.Resources = append(reply.Resources, &svrproto.Resource{
+			Path:   item.Path,
+			Unique: item.Unique,
+			Seek:   item.Seek,
+			End:    item.End,
+		})
+	}
+	reply.Resource = reply.Resources[0]
+	p.resumeWaitingResource()
+
+	return reply, nil
+}
+
 func (p *Provider) ResourceRemove(ctx context.Context, args *svrproto.ResourceRemoveArgs) (*svrproto.ResourceRemoveReply, error) {
 	p.input_mutex.Lock()
 	defer p.input_mutex.Unlock()
@@ -106,6 +158,7 @@ func (p *Provider) ResourceRemove(ctx context.Context, args *svrproto.ResourceRe
 	if index < p.currentIndex {
 		p.currentIndex = p.currentIndex - 1
 	}
+	delete(p.hotFolderUniques, res.Unique)
 
 	reply := &svrproto.ResourceRemoveReply{Resource: &svrproto.ResourceRemoveReply_Resource{}}
 	reply.Resource.Path = res.Path
@@ -141,6 +194,8 @@ func (p *Provider) ResourceList(ctx context.Context, args *svrproto.ResourceList
 			EndTime:         item.EndTime,
 			MixResourceType: item.MixResourceType,
 			Groups:          groups,
+			Title:           item.Title,
+			Duration:        item.Duration,
 		})
 
 	}
@@ -177,6 +232,8 @@ func (p *Provider) ResourceListAll(ctx context.Context, args *svrproto.ResourceL
 			EndTime:         item.EndTime,
 			MixResourceType: item.MixResourceType,
 			Groups:          groups,
+			Title:           item.Title,
+			Duration:        item.Duration,
 		})
 
 	}
@@ -186,12 +243,7 @@ func (p *Provider) ResourceListAll(ctx context.Context, args *svrproto.ResourceL
 	return reply, nil
 }
 
-func (p *Provider) CoreResourceList() (*svrproto.ResourceListReply, error) {
-	coreKplayer := core.GetLibKplayerInstance()
-	if err := coreKplayer.SendPrompt(kpproto.EventPromptAction_EVENT_PROMPT_ACTION_RESOURCE_LIST, &kpprompt.EventPromptResourceList{}); err != nil {
-		return nil, err
-	}
-
+func (p *Provider) CoreResourceList(ctx context.Context) (*svrproto.ResourceListReply, error) {
 	resourceListMsg := &msg.EventMessageResourceList{}
 	keeperCtx := module.NewKeeperContext(kptypes.GetRandString(), kpproto.EventMessageAction_EVENT_MESSAGE_ACTION_RESOURCE_LIST, func(msg string) bool {
 		kptypes.UnmarshalProtoMessage(msg, resourceListMsg)
@@ -203,8 +255,15 @@ func (p *Provider) CoreResourceList() (*svrproto.ResourceListReply, error) {
 		return nil, err
 	}
 
+	coreKplayer := p.engine
+	if err := coreKplayer.SendCorrelatedPrompt(keeperCtx.GetId(), kpproto.EventPromptAction_EVENT_PROMPT_ACTION_RESOURCE_LIST, &kpprompt.EventPromptResourceList{}); err != nil {
+		return nil, err
+	}
+
 	// wait context
-	keeperCtx.Wait()
+	if err := keeperCtx.Wait(ctx); err != nil {
+		return nil, err
+	}
 	if len(resourceListMsg.Error) != 0 {
 		return nil, fmt.Errorf("%s", resourceListMsg.Error)
 	}
@@ -244,11 +303,6 @@ func (p *Provider) CoreResourceList() (*svrproto.ResourceListReply, error) {
 }
 
 func (p *Provider) ResourceCurrent(ctx context.Context, args *svrproto.ResourceCurrentArgs) (*svrproto.ResourceCurrentReply, error) {
-	coreKplayer := core.GetLibKplayerInstance()
-	if err := coreKplayer.SendPrompt(kpproto.EventPromptAction_EVENT_PROMPT_ACTION_RESOURCE_CURRENT, &kpprompt.EventPromptResourceCurrent{}); err != nil {
-		return nil, err
-	}
-
 	resourceCurrentMsg := &msg.EventMessageResourceCurrent{}
 	keeperCtx := module.NewKeeperContext(kptypes.GetRandString(), kpproto.EventMessageAction_EVENT_MESSAGE_ACTION_RESOURCE_CURRENT, func(msg string) bool {
 		kptypes.UnmarshalProtoMessage(msg, resourceCurrentMsg)
@@ -260,13 +314,20 @@ func (p *Provider) ResourceCurrent(ctx context.Context, args *svrproto.ResourceC
 		return nil, err
 	}
 
+	coreKplayer := p.engine
+	if err := coreKplayer.SendCorrelatedPrompt(keeperCtx.GetId(), kpproto.EventPromptAction_EVENT_PROMPT_ACTION_RESOURCE_CURRENT, &kpprompt.EventPromptResourceCurrent{}); err != nil {
+		return nil, err
+	}
+
 	// wait context
-	keeperCtx.Wait()
+	if err := keeperCtx.Wait(ctx); err != nil {
+		return nil, err
+	}
 	if len(resourceCurrentMsg.Error) != 0 {
 		return nil, fmt.Errorf("%s", resourceCurrentMsg.Error)
 	}
 
-	currentRes, err := p.inputs.GetResourceByIndex(p.currentIndex)
+	currentRes, err := p.currentResource(resourceCurrentMsg.Resource.Unique)
 	if err != nil {
 		return nil, err
 	}
@@ -300,6 +361,8 @@ func (p *Provider) ResourceCurrent(ctx context.Context, args *svrproto.ResourceC
 			EndTime:         currentRes.EndTime,
 			MixResourceType: currentRes.MixResourceType,
 			Groups:          groups,
+			Title:           currentRes.Title,
+			Duration:        currentRes.Duration,
 		},
 		Duration:       resourceCurrentMsg.Duration,
 		DurationFormat: fmt.Sprintf("%d:%d:%d", uint64(resourceDuration.Hours()), uint64(resourceDuration.Minutes())%60, uint64(resourceDuration.Seconds())%60),
@@ -310,35 +373,26 @@ func (p *Provider) ResourceCurrent(ctx context.Context, args *svrproto.ResourceC
 	return reply, nil
 }
 
-func (p *Provider) ResourceSeek(ctx context.Context, args *svrproto.ResourceSeekArgs) (*svrproto.ResourceSeekReply, error) {
+// currentResource return the copy of resource playing, of interrupt, live source or playlist. the playlist and the
+// takeover resources are changed by core messages, interrupts, hot folder and schedule concurrently
+func (p *Provider) currentResource(unique string) (moduletypes.Resource, error) {
 	p.input_mutex.Lock()
 	defer p.input_mutex.Unlock()
 
-	var seekRes *moduletypes.Resource
-	var seekIndex int
-	var err error
-	if len(args.Unique) != 0 {
-		seekRes, seekIndex, err = p.inputs.GetResourceByUnique(args.Unique)
-		if err != nil {
-			return nil, err
-		}
-	} else {
-		seekRes, err = p.inputs.GetResourceByIndex(p.currentIndex)
-		if err != nil {
-			return nil, err
-		}
+	if res := p.takeoverResource(unique); res != nil {
+		return *res, nil
+	}
+	res, err := p.inputs.GetResourceByIndex(p.currentIndex)
+	if err != nil {
+		return moduletypes.Resource{}, err
 	}
 
-	// send prompt
-	coreKplayer := core.GetLibKplayerInstance()
-	if err := coreKplayer.SendPrompt(kpproto.EventPromptAction_EVENT_PROMPT_ACTION_RESOURCE_SEEK, &kpprompt.EventPromptResourceSeek{
-		Resource: &kpproto.PromptResource{
-			Path:   seekRes.Path,
-			Unique: seekRes.Unique,
-			Seek:   args.Seek,
-			End:    -1,
-		},
-	}); err != nil {
+	return *res, nil
+}
+
+func (p *Provider) ResourceSeek(ctx context.Context, args *svrproto.ResourceSeekArgs) (*svrproto.ResourceSeekReply, error) {
+	seekRes, err := p.seekResource(args.Unique)
+	if err != nil {
 		return nil, err
 	}
 
@@ -353,8 +407,23 @@ func (p *Provider) ResourceSeek(ctx context.Context, args *svrproto.ResourceSeek
 		return nil, err
 	}
 
+	// send prompt
+	coreKplayer := p.engine
+	if err := coreKplayer.SendCorrelatedPrompt(keeperCtx.GetId(), kpproto.EventPromptAction_EVENT_PROMPT_ACTION_RESOURCE_SEEK, &kpprompt.EventPromptResourceSeek{
+		Resource: &kpproto.PromptResource{
+			Path:   seekRes.Path,
+			Unique: seekRes.Unique,
+			Seek:   args.Seek,
+			End:    -1,
+		},
+	}); err != nil {
+		return nil, err
+	}
+
 	// wait context
-	keeperCtx.Wait()
+	if err := keeperCtx.Wait(ctx); err != nil {
+		return nil, err
+	}
 	if len(resourceSeek.Error) != 0 {
 		return nil, fmt.Errorf("%s", resourceSeek.Error)
 	}
@@ -388,7 +457,350 @@ func (p *Provider) ResourceSeek(ctx context.Context, args *svrproto.ResourceSeek
 		},
 	}
 
-	// update current resource index
-	p.currentIndex = seekIndex
+	// update current resource index. the playlist may be changed while waiting
+	p.input_mutex.Lock()
+	if _, index, err := p.inputs.GetResourceByUnique(seekRes.Unique); err == nil {
+		p.currentIndex = index
+	}
+	p.input_mutex.Unlock()
+
+	return reply, nil
+}
+
+// seekResource return the copy of resource to seek, the playing one when unique is empty. the input mutex is not held
+// while waiting for the seek reply, the messages of core are handled meanwhile
+func (p *Provider) seekResource(unique string) (moduletypes.Resource, error) {
+	p.input_mutex.Lock()
+	defer p.input_mutex.Unlock()
+
+	if len(unique) != 0 {
+		res, _, err := p.inputs.GetResourceByUnique(unique)
+		if err != nil {
+			return moduletypes.Resource{}, err
+		}
+		return *res, nil
+	}
+
+	res, err := p.inputs.GetResourceByIndex(p.currentIndex)
+	if err != nil {
+		return moduletypes.Resource{}, err
+	}
+
+	return *res, nil
+}
+
+// ResourceImport append the resources of playlist file to playlist. nothing is appended when any resource invalid
+func (p *Provider) ResourceImport(ctx context.Context, args *svrproto.ResourceImportArgs) (*svrproto.ResourceImportReply, error) {
+	resources, err := ParsePlaylistFile(args.Path, args.Format)
+	if err != nil {
+		return nil, err
+	}
+	if len(resources) == 0 {
+		return nil, fmt.Errorf("playlist is empty. path: %s", args.Path)
+	}
+	for _, item := range resources {
+		if err := checkResourcePath(item.Path); err != nil {
+			return nil, err
+		}
+	}
+
+	p.input_mutex.Lock()
+	defer p.input_mutex.Unlock()
+
+	reply := &svrproto.ResourceImportReply{}
+	for _, item := range resources {
+		item = setResourceUniqueName(item)
+		if err := p.inputs.AppendResource(item); err != nil {
+			return nil, fmt.Errorf("%s. path: %s, unique: %s", err, item.Path, item.Unique)
+		}
+
+		p.resumeWaitingResource()
+
+		reply.Resources = append(reply.Resources, &svrproto.Resource{
+			Path:       item.Path,
+			Unique:     item.Unique,
+			Seek:       item.Seek,
+			End:        item.End,
+			CreateTime: item.CreateTime,
+			Title:      item.Title,
+			Duration:   item.Duration,
+		})
+	}
+
+	return reply, nil
+}
+
+// ResourceExport return the playlist of all resources in the format, with the seek in config
+func (p *Provider) ResourceExport(ctx context.Context, args *svrproto.ResourceExportArgs) (*svrproto.ResourceExportReply, error) {
+	p.input_mutex.Lock()
+	resources := p.withConfiguredSeek(p.inputs.resources)
+	p.input_mutex.Unlock()
+
+	content, err := ExportPlaylist(resources, args.Format)
+	if err != nil {
+		return nil, err
+	}
+
+	return &svrproto.ResourceExportReply{Content: string(content)}, nil
+}
+
+// ResourceMove move the resource to index of playlist. the current index is kept on the playing resource
+func (p *Provider) ResourceMove(ctx context.Context, args *svrproto.ResourceMoveArgs) (*svrproto.ResourceMoveReply, error) {
+	p.input_mutex.Lock()
+	defer p.input_mutex.Unlock()
+
+	from, err := p.inputs.MoveResource(args.Unique, int(args.Index))
+	if err != nil {
+		return nil, err
+	}
+	p.currentIndex = movedIndex(p.currentIndex, from, int(args.Index))
+
+	res, index, err := p.inputs.GetResourceByUnique(args.Unique)
+	if err != nil {
+		return nil, err
+	}
+
+	return &svrproto.ResourceMoveReply{Resource: TransferModuleToServerResource(*res), Index: int64(index)}, nil
+}
+
+// ResourceInsert insert the resource before or after the resource of unique, or at index of playlist
+func (p *Provider) ResourceInsert(ctx context.Context, args *svrproto.ResourceInsertArgs) (*svrproto.ResourceInsertReply, error) {
+	p.input_mutex.Lock()
+	defer p.input_mutex.Unlock()
+
+	index := int(args.Index)
+	switch {
+	case args.Before != "":
+		_, beforeIndex, err := p.inputs.GetResourceByUnique(args.Before)
+		if err != nil {
+			return nil, fmt.Errorf("%s. unique: %s", err, args.Before)
+		}
+		index = beforeIndex
+	case args.After != "":
+		_, afterIndex, err := p.inputs.GetResourceByUnique(args.After)
+		if err != nil {
+			return nil, fmt.Errorf("%s. unique: %s", err, args.After)
+		}
+		index = afterIndex + 1
+	}
+	if index < 0 || index > len(p.inputs.resources) {
+		return nil, ResourceIndexOutOfRange
+	}
+
+	res, err := p.insertResource(index, args.Path, args.Unique, args.Seek, args.End)
+	if err != nil {
+		return nil, err
+	}
+
+	return &svrproto.ResourceInsertReply{Resource: TransferModuleToServerResource(res), Index: int64(index)}, nil
+}
+
+// ResourcePlayNext play the resource after the current resource. the resource in playlist is moved, otherwise the
+// resource of path is inserted. in random model the resource is picked up next instead of the random one
+func (p *Provider) ResourcePlayNext(ctx context.Context, args *svrproto.ResourcePlayNextArgs) (*svrproto.ResourcePlayNextReply, error) {
+	p.input_mutex.Lock()
+	defer p.input_mutex.Unlock()
+
+	// the current resource is not playing when waiting for resource added
+	next := p.currentIndex + 1
+	if p.waitingResource {
+		next = p.currentIndex
+	}
+
+	var res moduletypes.Resource
+	from := -1
+	if _, index, err := p.inputs.GetResourceByUnique(args.Unique); err == nil {
+		from = index
+	}
+	switch {
+	case from != -1 && args.Path != "":
+		return nil, fmt.Errorf("%s. unique: %s", ResourceUniqueHasExisted, args.Unique)
+	case from != -1:
+		if from == p.currentIndex && !p.waitingResource {
+			return nil, CannotPlayNextCurrent
+		}
+
+		to := next
+		if from < next {
+			to = next - 1
+		}
+		if _, err := p.inputs.MoveResource(args.Unique, to); err != nil {
+			return nil, err
+		}
+		p.currentIndex = movedIndex(p.currentIndex, from, to)
+		if p.waitingResource {
+			p.currentIndex = to
+		}
+		res = p.inputs.resources[to]
+	case args.Path == "":
+		return nil, fmt.Errorf("%s. unique: %s", ResourceNotFound, args.Unique)
+	default:
+		if next > len(p.inputs.resources) {
+			next = len(p.inputs.resources)
+		}
+		inserted, err := p.insertResource(next, args.Path, args.Unique, args.Seek, args.End)
+		if err != nil {
+			return nil, err
+		}
+		res = inserted
+	}
+
+	if p.playProvider.GetPlayModel() == config.PLAY_MODEL_RANDOM {
+		p.playNextUnique = res.Unique
+	}
+	p.resumeWaitingResource()
+
+	_, index, err := p.inputs.GetResourceByUnique(res.Unique)
+	if err != nil {
+		return nil, err
+	}
+
+	return &svrproto.ResourcePlayNextReply{Resource: TransferModuleToServerResource(res), Index: int64(index)}, nil
+}
+
+// ResourceSchedule return the slot playing, the slot pending and the next slot of schedule
+func (p *Provider) ResourceSchedule(ctx context.Context, args *svrproto.ResourceScheduleArgs) (*svrproto.ResourceScheduleReply, error) {
+	p.input_mutex.Lock()
+	defer p.input_mutex.Unlock()
+
+	reply := &svrproto.ResourceScheduleReply{}
+	if p.scheduleActive != nil {
+		reply.Active = p.scheduleActive.unique
+	}
+	if p.schedulePending != nil {
+		reply.Pending = p.schedulePending.unique
+	}
+	if slot, start := nextScheduleSlot(p.scheduleSlots, time.Now()); slot != nil {
+		reply.Next = slot.unique
+		reply.NextStartTime = uint64(start.Unix())
+	}
+
 	return reply, nil
 }
+
+// JumpResource select the resource played after the current resource. the previous resource is the one before the
+// current resource in playlist, or the last one of history in random model. the selected resource is played at once
+// when the player is waiting for resource added. return whether the current resource is playing
+func (p *Provider) JumpResource(previous bool, index int64, unique string) (*svrproto.Resource, bool, error) {
+	p.input_mutex.Lock()
+	defer p.input_mutex.Unlock()
+
+	var target int
+	var err error
+	switch {
+	case previous:
+		target, err = p.previousIndex()
+	case unique != "":
+		_, target, err = p.inputs.GetResourceByUnique(unique)
+	default:
+		target = int(index)
+		if target < 0 || target >= len(p.inputs.resources) {
+			err = ResourceIndexOutOfRange
+		}
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	res := p.inputs.resources[target]
+
+	if p.waitingResource {
+		p.currentIndex = target
+		p.addNextResourceToCore()
+		return TransferModuleToServerResource(res), false, nil
+	}
+
+	p.jumpUnique, p.jumpPrevious = res.Unique, previous
+	return TransferModuleToServerResource(res), true, nil
+}
+
+// previousIndex return the index of previous resource of play model. the input mutex must be held
+func (p *Provider) previousIndex() (int, error) {
+	if p.playProvider.GetPlayModel() == config.PLAY_MODEL_RANDOM {
+		for len(p.randomModeUniqueNameHistory) != 0 {
+			last := p.randomModeUniqueNameHistory[len(p.randomModeUniqueNameHistory)-1]
+			p.randomModeUniqueNameHistory = p.randomModeUniqueNameHistory[:len(p.randomModeUniqueNameHistory)-1]
+			if _, index, err := p.inputs.GetResourceByUnique(last); err == nil {
+				return index, nil
+			}
+		}
+		return 0, NoPreviousResource
+	}
+
+	previous := p.currentIndex - 1
+	if previous >= len(p.inputs.resources) {
+		previous = len(p.inputs.resources) - 1
+	}
+	if previous >= 0 {
+		return previous, nil
+	}
+
+	// the loop continues from the last resource
+	if p.playProvider.GetPlayModel() == config.PLAY_MODEL_LOOP && len(p.inputs.resources) != 0 {
+		return len(p.inputs.resources) - 1, nil
+	}
+
+	return 0, NoPreviousResource
+}
+
+// insertResource insert the resource of path at index. the current index is kept on the playing resource.
+// the input mutex must be held
+func (p *Provider) insertResource(index int, path string, unique string, seek int64, end int64) (moduletypes.Resource, error) {
+	if err := checkResourcePath(path); err != nil {
+		return moduletypes.Resource{}, err
+	}
+
+	// end is not set on 0 or less, the resource is played to the end
+	if end > 0 && end < seek {
+		return moduletypes.Resource{}, fmt.Errorf("end timestamp can not be less than start timestamp")
+	}
+
+	res := moduletypes.Resource{
+		Path:       path,
+		Unique:     unique,
+		Seek:       seek,
+		End:        end,
+		CreateTime: uint64(time.Now().Unix()),
+	}
+	if err := p.inputs.InsertResource(index, res); err != nil {
+		return moduletypes.Resource{}, err
+	}
+
+	// the resource at current index is not playing when waiting for resource added
+	if index < p.currentIndex || (index == p.currentIndex && !p.waitingResource) {
+		p.currentIndex = p.currentIndex + 1
+	}
+	p.resumeWaitingResource()
+
+	return res, nil
+}
+
+// movedIndex return the index of resource after the resource moved from one index to another
+func movedIndex(index int, from int, to int) int {
+	switch {
+	case index == from:
+		return to
+	case from < index && index <= to:
+		return index - 1
+	case to <= index && index < from:
+		return index + 1
+	}
+
+	return index
+}
+
+// checkResourcePath return error when the uri is invalid or the local file not exists
+func checkResourcePath(path string) error {
+	parseUrl, err := url.Parse(path)
+	if err != nil {
+		return fmt.Errorf("uri scheme invalid. path: %s", path)
+	}
+	if parseUrl.Scheme == "" {
+		// determine whether the file exists
+		_, err := os.Stat(path)
+		if os.IsNotExist(err) {
+			return fmt.Errorf("file not exists. path: %s", path)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,292 @@
+package provider
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/bytelang/kplayer/core"
+	playprovider "github.com/bytelang/kplayer/module/play/provider"
+	kptypes "github.com/bytelang/kplayer/types"
+	"github.com/bytelang/kplayer/types/config"
+	moduletypes "github.com/bytelang/kplayer/types/module"
+	svrproto "github.com/bytelang/kplayer/types/server"
+)
+
+func newArrangeProvider(t *testing.T, playModel string, uniques ...string) *Provider {
+	fe := core.NewFakeEngine()
+	pp := playprovider.NewProvider(fe)
+	pp.InitModule(kptypes.DefaultClientContext(), &config.Play{
+		StartPoint: 1,
+		PlayModel:  playModel,
+		Rpc:        &config.Server{},
+		Encode:     &config.Encode{},
+	})
+
+	p := NewProvider(fe, pp)
+	for _, unique := range uniques {
+		if err := p.inputs.AppendResource(moduletypes.Resource{Path: "/video/" + unique + ".mp4", Unique: unique}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return p
+}
+
+func playlistUniques(p *Provider) []string {
+	var uniques []string
+	for _, item := range p.inputs.resources {
+		uniques = append(uniques, item.Unique)
+	}
+	return uniques
+}
+
+// waitPlaying wait for the resource of unique started playing
+func waitPlaying(t *testing.T, p *Provider, unique string) {
+	t.Helper()
+	for i := 0; i < 200; i++ {
+		reply, err := p.ResourceCurrent(context.Background(), &svrproto.ResourceCurrentArgs{})
+		if err == nil && reply.Resource.Unique == unique && reply.Resource.StartTime != 0 {
+			return
+		}
+		time.Sleep(time.Millisecond * 10)
+	}
+	t.Fatalf("wait playing resource %s timeout", unique)
+}
+
+func TestResourceArrange(t *testing.T) {
+	dir := t.TempDir()
+	writeDirectoryFiles(t, dir, "new.mp4")
+	path := filepath.Join(dir, "new.mp4")
+
+	ctx := context.Background()
+	p := newArrangeProvider(t, "list", "a", "b", "c", "d", "e")
+	p.currentIndex = 2
+	check := func(uniques []string, current string) {
+		t.Helper()
+		if !reflect.DeepEqual(playlistUniques(p), uniques) {
+			t.Fatalf("unexpected playlist: %v", playlistUniques(p))
+		}
+		if p.inputs.resources[p.currentIndex].Unique != current {
+			t.Fatalf("unexpected current resource: %s", p.inputs.resources[p.currentIndex].Unique)
+		}
+	}
+
+	// move
+	if _, err := p.ResourceMove(ctx, &svrproto.ResourceMoveArgs{Unique: "e", Index: 0}); err != nil {
+		t.Fatal(err)
+	}
+	check([]string{"e", "a", "b", "c", "d"}, "c")
+	if _, err := p.ResourceMove(ctx, &svrproto.ResourceMoveArgs{Unique: "c", Index: 0}); err != nil {
+		t.Fatal(err)
+	}
+	check([]string{"c", "e", "a", "b", "d"}, "c")
+	if _, err := p.ResourceMove(ctx, &svrproto.ResourceMoveArgs{Unique: "c", Index: 5}); err != ResourceIndexOutOfRange {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// insert
+	reply, err := p.ResourceInsert(ctx, &svrproto.ResourceInsertArgs{Path: path, Unique: "x", After: "b"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reply.Index != 4 {
+		t.Fatalf("unexpected index: %d", reply.Index)
+	}
+	check([]string{"c", "e", "a", "b", "x", "d"}, "c")
+	if _, err := p.ResourceInsert(ctx, &svrproto.ResourceInsertArgs{Path: path, Unique: "y", Before: "c"}); err != nil {
+		t.Fatal(err)
+	}
+	check([]string{"y", "c", "e", "a", "b", "x", "d"}, "c")
+	if _, err := p.ResourceInsert(ctx, &svrproto.ResourceInsertArgs{Path: path, Unique: "z", Before: "missing"}); err == nil {
+		t.Fatal("expected resource not found")
+	}
+	if _, err := p.ResourceInsert(ctx, &svrproto.ResourceInsertArgs{Path: path, Unique: "z", Index: 8}); err != ResourceIndexOutOfRange {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// play next
+	if _, err := p.ResourcePlayNext(ctx, &svrproto.ResourcePlayNextArgs{Unique: "d"}); err != nil {
+		t.Fatal(err)
+	}
+	check([]string{"y", "c", "d", "e", "a", "b", "x"}, "c")
+	if _, err := p.ResourcePlayNext(ctx, &svrproto.ResourcePlayNextArgs{Unique: "y"}); err != nil {
+		t.Fatal(err)
+	}
+	check([]string{"c", "y", "d", "e", "a", "b", "x"}, "c")
+	if _, err := p.ResourcePlayNext(ctx, &svrproto.ResourcePlayNextArgs{Unique: "c"}); err != CannotPlayNextCurrent {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := p.ResourcePlayNext(ctx, &svrproto.ResourcePlayNextArgs{Unique: "z", Path: path}); err != nil {
+		t.Fatal(err)
+	}
+	check([]string{"c", "z", "y", "d", "e", "a", "b", "x"}, "c")
+	if _, err := p.ResourcePlayNext(ctx, &svrproto.ResourcePlayNextArgs{Unique: "missing"}); err == nil {
+		t.Fatal("expected resource not found")
+	}
+}
+
+func TestMovedIndex(t *testing.T) {
+	for _, item := range []struct {
+		index, from, to, expected int
+	}{
+		{2, 2, 0, 0},
+		{2, 0, 4, 1},
+		{2, 4, 0, 3},
+		{2, 3, 4, 2},
+		{2, 0, 1, 2},
+	} {
+		if index := movedIndex(item.index, item.from, item.to); index != item.expected {
+			t.Errorf("unexpected index of %d moved from %d to %d: %d", item.index, item.from, item.to, index)
+		}
+	}
+}
+
+func TestPlayJump(t *testing.T) {
+	fe := core.NewFakeEngine()
+	pp := playprovider.NewProvider(fe)
+	pp.InitModule(kptypes.DefaultClientContext(), &config.Play{
+		StartPoint: 1,
+		PlayModel:  "list",
+		Rpc:        &config.Server{},
+		Encode:     &config.Encode{},
+	})
+	p := NewProvider(fe, pp)
+	for _, unique := range []string{"a", "b", "c", "d"} {
+		if err := p.inputs.AppendResource(moduletypes.Resource{Path: "/video/" + unique + ".mp4", Unique: unique, End: -1}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	pp.SetResourceJumper(p.JumpResource)
+	fe.SetCallBackMessage(func(message *core.Message) {
+		p.ParseMessage(message.KPMessage)
+		p.Trigger(message)
+		pp.Trigger(message)
+	})
+
+	resultChan := make(chan int)
+	go func() {
+		resultChan <- fe.Run()
+	}()
+	defer func() {
+		fe.Terminate(0)
+		<-resultChan
+	}()
+
+	waitPlaying(t, p, "a")
+
+	ctx := context.Background()
+	if _, err := pp.PlayPrevious(ctx, &svrproto.PlayPreviousArgs{}); err != NoPreviousResource {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := pp.PlayJump(ctx, &svrproto.PlayJumpArgs{Unique: "c"}); err != nil {
+		t.Fatal(err)
+	}
+	waitPlaying(t, p, "c")
+	if _, err := pp.PlayPrevious(ctx, &svrproto.PlayPreviousArgs{}); err != nil {
+		t.Fatal(err)
+	}
+	waitPlaying(t, p, "b")
+	if _, err := pp.PlayJump(ctx, &svrproto.PlayJumpArgs{Index: 3}); err != nil {
+		t.Fatal(err)
+	}
+	waitPlaying(t, p, "d")
+	if _, err := pp.PlayJump(ctx, &svrproto.PlayJumpArgs{Index: 4}); err != ResourceIndexOutOfRange {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestPlayRandomPrevious(t *testing.T) {
+	fe := core.NewFakeEngine()
+	pp := playprovider.NewProvider(fe)
+	pp.InitModule(kptypes.DefaultClientContext(), &config.Play{
+		StartPoint: 1,
+		PlayModel:  "random",
+		Rpc:        &config.Server{},
+		Encode:     &config.Encode{},
+	})
+	p := NewProvider(fe, pp)
+	uniques := []string{"a", "b", "c", "d"}
+	for _, unique := range uniques {
+		if err := p.inputs.AppendResource(moduletypes.Resource{Path: "/video/" + unique + ".mp4", Unique: unique, End: -1}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	pp.SetResourceJumper(p.JumpResource)
+	fe.SetCallBackMessage(func(message *core.Message) {
+		p.ParseMessage(message.KPMessage)
+		p.Trigger(message)
+		pp.Trigger(message)
+	})
+
+	resultChan := make(chan int)
+	go func() {
+		resultChan <- fe.Run()
+	}()
+	defer func() {
+		fe.Terminate(0)
+		<-resultChan
+	}()
+
+	waitPlaying(t, p, "a")
+	played := []string{"a"}
+	waitNext := func() string {
+		t.Helper()
+		for i := 0; i < 200; i++ {
+			reply, err := p.ResourceCurrent(context.Background(), &svrproto.ResourceCurrentArgs{})
+			if err == nil && reply.Resource.Unique != played[len(played)-1] && reply.Resource.StartTime != 0 {
+				return reply.Resource.Unique
+			}
+			time.Sleep(time.Millisecond * 10)
+		}
+		t.Fatalf("wait playing next resource timeout. played: %v", played)
+		return ""
+	}
+
+	// every resource is played once in a round, the history keeps the played order
+	for len(played) < len(uniques) {
+		fe.Advance(core.FakeDefaultResourceDuration)
+		played = append(played, waitNext())
+	}
+	for _, unique := range uniques {
+		if !kptypes.ArrayInString(played, unique) {
+			t.Fatalf("unexpected random round: %v", played)
+		}
+	}
+	if !reflect.DeepEqual(p.randomModeUniqueNameHistory, played[:len(played)-1]) {
+		t.Fatalf("unexpected random history: %v, played: %v", p.randomModeUniqueNameHistory, played)
+	}
+
+	// the previous resources are played in reverse order
+	ctx := context.Background()
+	for i := len(played) - 2; i >= 0; i-- {
+		if _, err := pp.PlayPrevious(ctx, &svrproto.PlayPreviousArgs{}); err != nil {
+			t.Fatal(err)
+		}
+		waitPlaying(t, p, played[i])
+	}
+	if _, err := pp.PlayPrevious(ctx, &svrproto.PlayPreviousArgs{}); err != NoPreviousResource {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestPreviousIndex(t *testing.T) {
+	p := newArrangeProvider(t, "loop", "a", "b", "c")
+	if index, err := p.previousIndex(); err != nil || index != 2 {
+		t.Fatalf("unexpected previous index of loop model: %d, %v", index, err)
+	}
+
+	// the previous resource of random model is popped from history
+	p = newArrangeProvider(t, "random", "a", "b", "c")
+	p.randomModeUniqueNameHistory = []string{"b", "missing"}
+	if index, err := p.previousIndex(); err != nil || index != 1 {
+		t.Fatalf("unexpected previous index of random model: %d, %v", index, err)
+	}
+	if len(p.randomModeUniqueNameHistory) != 0 {
+		t.Fatalf("unexpected random history: %v", p.randomModeUniqueNameHistory)
+	}
+	if _, err := p.previousIndex(); err != NoPreviousResource {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
@@ -14,6 +14,7 @@ import (
 	moduletypes "github.com/bytelang/kplayer/types/module"
 	svrproto "github.com/bytelang/kplayer/types/server"
 	log "github.com/sirupsen/logrus"
+	"google.golang.org/protobuf/types/known/anypb"
 	"math/rand"
 	"path/filepath"
 	"sort"
@@ -33,6 +34,15 @@ type ProviderI interface {
 	ResourceListAll(context.Context, *svrproto.ResourceListAllArgs) (*svrproto.ResourceListAllReply, error)
 	ResourceCurrent(context.Context, *svrproto.ResourceCurrentArgs) (*svrproto.ResourceCurrentReply, error)
 	ResourceSeek(context.Context, *svrproto.ResourceSeekArgs) (*svrproto.ResourceSeekReply, error)
+	ResourceImport(context.Context, *svrproto.ResourceImportArgs) (*svrproto.ResourceImportReply, error)
+	ResourceExport(context.Context, *svrproto.ResourceExportArgs) (*svrproto.ResourceExportReply, error)
+	ResourceMove(context.Context, *svrproto.ResourceMoveArgs) (*svrproto.ResourceMoveReply, error)
+	ResourceInsert(context.Context, *svrproto.ResourceInsertArgs) (*svrproto.ResourceInsertReply, error)
+	ResourcePlayNext(context.Context, *svrproto.ResourcePlayNextArgs) (*svrproto.ResourcePlayNextReply, error)
+	ResourceSchedule(context.Context, *svrproto.ResourceScheduleArgs) (*svrproto.ResourceScheduleReply, error)
+	ResourceInterrupt(context.Context, *svrproto.ResourceInterruptArgs) (*svrproto.ResourceInterruptReply, error)
+	ResourceLive(context.Context, *svrproto.ResourceLiveArgs) (*svrproto.ResourceLiveReply, error)
+	ResourceLiveStop(context.Context, *svrproto.ResourceLiveStopArgs) (*svrproto.ResourceLiveStopReply, error)
 }
 
 var _ ProviderI = &Provider{}
@@ -41,6 +51,9 @@ type Provider struct {
 	module.ModuleKeeper
 	svrproto.UnimplementedResourceGreeterServer
 
+	// core engine
+	engine core.Engine
+
 	// module provider
 	playProvider playprovider.ProviderI
 
@@ -53,32 +66,142 @@ type Provider struct {
 	// set resource seek on replayed need set the resource attribute
 	resetInputs map[string]int64
 
+	// the last known position of playing resource
+	currentDuration int64
+	currentSeek     int64
+
 	input_mutex sync.Mutex
 
 	// random history list
 	randomModeUniqueNameList    []string
 	randomModeUniqueNameHistory []string
+
+	// the resource played next instead of the random one
+	playNextUnique string
+
+	// the resource selected by jump. played on the current resource finished
+	jumpUnique   string
+	jumpPrevious bool
+
+	// playback position journal
+	journalStop chan bool
+	journalDone chan bool
+
+	// hot folder and the unique to file path of resources added from it
+	hotFolder        *config.HotFolder
+	hotFolderUniques map[string]string
+	hotFolderOn      bool
+	hotFolderStop    chan bool
+	hotFolderDone    chan bool
+
+	// the player is waiting for resource added
+	waitingResource bool
+
+	// the live directories by key
+	liveDirectories map[string]*liveDirectory
+
+	// the interrupt playing and the interrupts queued in order of priority. the playlist resource cut by them is
+	// resumed after all of them finished
+	interruptActive   *interrupt
+	interrupts        []*interrupt
+	interruptedUnique string
+
+	// the live source taking over the playlist
+	live       *liveSource
+	liveConfig *config.Live
+
+	// the playing resource is cutting for the interrupt or live source
+	takeoverCutting bool
+
+	// the slots of schedule. the configured playlist is kept aside while the slot playing
+	schedule        *config.Schedule
+	scheduleSlots   []*scheduleSlot
+	scheduleActive  *scheduleSlot
+	schedulePending *scheduleSlot
+	scheduleLists   []moduletypes.Resource
+	scheduleOn      bool
+	scheduleStop    chan bool
+	scheduleDone    chan bool
 }
 
 var _ ProviderI = &Provider{}
 
-func NewProvider(playProvider playprovider.ProviderI) *Provider {
-	return &Provider{
+func NewProvider(engine core.Engine, playProvider playprovider.ProviderI) *Provider {
+	p := &Provider{
+		engine:       engine,
 		playProvider: playProvider,
 		resetInputs:  make(map[string]int64),
+
+		hotFolderUniques: make(map[string]string),
+		liveDirectories:  make(map[string]*liveDirectory),
 	}
+	p.SetKeeperEngine(engine)
+
+	return p
 }
 
 func (p *Provider) InitModule(ctx *kptypes.ClientContext, cfg *config.Resource) {
 	// initialize attribute
 	p.currentIndex = int(p.playProvider.GetStartPoint()) - 1
 	p.allowExtensions = cfg.Extensions
+	p.hotFolder = cfg.HotFolder
+	p.liveConfig = cfg.Live
+
+	// the live directory is placed after the resources of items before it
+	anchor := ""
+	for _, list := range cfg.Lists {
+		resources, err := parseResourceList([]*anypb.Any{list}, p.allowExtensions)
+		if err != nil {
+			log.WithField("error", err).Fatal("add resource to playlist failed")
+		}
+		for _, item := range resources {
+			item = setResourceUniqueName(item)
+			if err := p.inputs.AppendResource(item); err != nil {
+				log.WithFields(log.Fields{"path": item.Path, "unique": item.Unique, "error": err, "mix": item.MixResourceType}).Fatal("add resource to playlist failed")
+			}
+		}
+
+		if res, err := kptypes.GetResourceItemByAny(list); err == nil {
+			if directory, ok := res.(*config.DirectoryResource); ok && directory.Live {
+				key := liveDirectoryKey(directory)
+				if _, ok := p.liveDirectories[key]; ok {
+					log.WithFields(log.Fields{"directory": directory.Directory, "key": key}).Fatal(LiveDirectoryHasExisted)
+				}
+				p.liveDirectories[key] = &liveDirectory{config: directory, anchor: anchor}
+			}
+		}
+		if len(p.inputs.resources) != 0 {
+			anchor = p.inputs.resources[len(p.inputs.resources)-1].Unique
+		}
+	}
+
+	if p.playProvider.GetPlayModel() == config.PLAY_MODEL_RANDOM && len(p.inputs.resources) != 0 {
+		p.currentIndex = rand.Intn(len(p.inputs.resources))
+	}
 
-	for _, item := range cfg.Lists {
+	// the slot started before is played at once
+	slots, err := parseSchedule(cfg.Schedule)
+	if err != nil {
+		log.WithField("error", err).Fatal("parse schedule failed")
+	}
+	p.schedule = cfg.Schedule
+	p.scheduleSlots = slots
+	if slot := currentScheduleSlot(slots, time.Now()); slot != nil {
+		p.schedulePending = slot
+		p.applyScheduleSlot()
+	}
+}
+
+// parseResourceList expand the resource list of config. the directory is expanded to its files of allowed extensions,
+// the playlist file is expanded to its entries. the files of live directory are marked with its key.
+// the unique of resource is kept empty when it is not configured
+func parseResourceList(lists []*anypb.Any, allowExtensions []string) ([]moduletypes.Resource, error) {
+	var resources []moduletypes.Resource
+	for _, item := range lists {
 		// parse resource item
 		res, err := kptypes.GetResourceItemByAny(item)
 		if err != nil {
-			log.WithField("content", item.String()).Fatal("not in the expected format")
+			return nil, fmt.Errorf("not in the expected format. content: %s", item.String())
 		}
 
 		switch assertRes := res.(type) {
@@ -93,32 +216,56 @@ func (p *Provider) InitModule(ctx *kptypes.ClientContext, cfg *config.Resource)
 					if len(ext) > 1 {
 						ext = ext[1:]
 					}
-					if p.allowExtensions != nil && !kptypes.ArrayInString(p.allowExtensions, ext) {
+					if allowExtensions != nil && !kptypes.ArrayInString(allowExtensions, ext) {
 						continue
 					}
 
-					if err := p.inputs.AppendResource(moduletypes.Resource{
+					resources = append(resources, moduletypes.Resource{
 						Path:       f,
-						Unique:     GetResourceUniqueName("", f),
 						Seek:       0,
 						End:        -1,
 						CreateTime: uint64(time.Now().Unix()),
-					}); err != nil {
-						log.WithFields(log.Fields{"path": assertRes.Path, "unique": assertRes.Unique, "error": err}).Fatal("add resource to playlist failed")
-					}
+					})
 				}
 				continue
 			}
 
+			// add resources of playlist file
+			if IsPlaylistFile(assertRes.Path) {
+				playlist, err := ParsePlaylistFile(assertRes.Path, "")
+				if err != nil {
+					return nil, err
+				}
+				resources = append(resources, playlist...)
+				continue
+			}
+
 			// add resource file
-			if err := p.inputs.AppendResource(moduletypes.Resource{
+			resources = append(resources, moduletypes.Resource{
 				Path:       assertRes.Path,
-				Unique:     GetResourceUniqueName(assertRes.Unique, assertRes.Path),
+				Unique:     assertRes.Unique,
 				Seek:       assertRes.Seek,
 				End:        assertRes.End,
 				CreateTime: uint64(time.Now().Unix()),
-			}); err != nil {
-				log.WithFields(log.Fields{"path": assertRes.Path, "unique": assertRes.Unique, "error": err, "type": "single"}).Fatal("add resource to playlist failed")
+			})
+		case *config.DirectoryResource:
+			files, err := ScanDirectoryResource(assertRes, allowExtensions)
+			if err != nil {
+				return nil, fmt.Errorf("scan directory failed. directory: %s, error: %s", assertRes.Directory, err)
+			}
+
+			key := ""
+			if assertRes.Live {
+				key = liveDirectoryKey(assertRes)
+			}
+			for _, f := range files {
+				resources = append(resources, moduletypes.Resource{
+					Path:          f,
+					Seek:          0,
+					End:           -1,
+					CreateTime:    uint64(time.Now().Unix()),
+					LiveDirectory: key,
+				})
 			}
 		case *config.MixResource:
 			groups := TransferConfigToModuleResourceGroup(assertRes.Groups)
@@ -131,28 +278,40 @@ func (p *Provider) InitModule(ctx *kptypes.ClientContext, cfg *config.Resource)
 				}
 			}
 
-			if err := p.inputs.AppendResource(moduletypes.Resource{
+			resources = append(resources, moduletypes.Resource{
 				Path:            primaryResourceGroup.Path,
-				Unique:          GetResourceUniqueName(assertRes.Unique, primaryResourceGroup.Path, "MIX"),
+				Unique:          assertRes.Unique,
 				Seek:            assertRes.Seek,
 				End:             assertRes.End,
 				CreateTime:      uint64(time.Now().Unix()),
 				MixResourceType: true,
 				Groups:          groups,
-			}); err != nil {
-				log.WithFields(log.Fields{"path": primaryResourceGroup, "unique": assertRes.Unique, "groups": assertRes.Groups, "error": err, "type": "mix"}).Fatal("add resource to playlist failed")
-			}
+			})
 		default:
-			log.WithField("error", "invalid resource type").Fatal(item)
+			return nil, fmt.Errorf("invalid resource type. content: %s", item.String())
 		}
 	}
 
-	if p.playProvider.GetPlayModel() == config.PLAY_MODEL_RANDOM {
-		p.currentIndex = rand.Intn(len(p.inputs.resources))
+	return resources, nil
+}
+
+// setResourceUniqueName generate the unique of resource by path when it is not configured
+func setResourceUniqueName(resource moduletypes.Resource) moduletypes.Resource {
+	if resource.MixResourceType {
+		resource.Unique = GetResourceUniqueName(resource.Unique, resource.Path, "MIX")
+	} else {
+		resource.Unique = GetResourceUniqueName(resource.Unique, resource.Path)
 	}
+
+	return resource
 }
 
 func (p *Provider) ValidateConfig() error {
+	// the playlist waits for the files of hot folder or the schedule slot
+	if len(p.inputs.resources) == 0 && (p.hotFolder != nil && p.hotFolder.Path != "" || len(p.scheduleSlots) != 0) {
+		return nil
+	}
+
 	if p.currentIndex < 0 {
 		return fmt.Errorf("start point invalid. cannot less than 1")
 	} else if p.currentIndex >= len(p.inputs.resources) {
@@ -171,23 +330,115 @@ func (p *Provider) ValidateConfig() error {
 	return nil
 }
 
+// effectiveResourceConfig resource config with the single and mix resource items unpacked
+type effectiveResourceConfig struct {
+	Lists      []interface{}     `json:"lists"`
+	Extensions []string          `json:"extensions,omitempty"`
+	HotFolder  *config.HotFolder `json:"hot_folder,omitempty"`
+	Schedule   *config.Schedule  `json:"schedule,omitempty"`
+	Live       *config.Live      `json:"live,omitempty"`
+}
+
+// GetEffectiveConfig return the resource config of the playlist. the directories and playlist files are expanded,
+// the live directories are kept in place of their files, the resources added from hot folder are excluded.
+// the configured playlist is returned while the schedule slot playing. the resource resumed at a position keeps
+// the seek in config
+func (p *Provider) GetEffectiveConfig() interface{} {
+	p.input_mutex.Lock()
+	defer p.input_mutex.Unlock()
+
+	var keys []string
+	filled := map[string]bool{}
+	for key := range p.liveDirectories {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	resources := p.withConfiguredSeek(p.configuredResources())
+	for _, item := range resources {
+		filled[item.LiveDirectory] = true
+	}
+
+	// the live directory without file in playlist is placed after its anchor
+	lists := []interface{}{}
+	added := map[string]bool{}
+	addLiveDirectories := func(anchor string) {
+		for _, key := range keys {
+			if !filled[key] && !added[key] && p.liveDirectories[key].anchor == anchor {
+				lists = append(lists, p.liveDirectories[key].config)
+				added[key] = true
+			}
+		}
+	}
+
+	addLiveDirectories("")
+	for _, item := range resources {
+		if _, ok := p.hotFolderUniques[item.Unique]; ok {
+			addLiveDirectories(item.Unique)
+			continue
+		}
+		if dir, ok := p.liveDirectories[item.LiveDirectory]; ok {
+			if !added[item.LiveDirectory] {
+				lists = append(lists, dir.config)
+				added[item.LiveDirectory] = true
+			}
+		} else {
+			lists = append(lists, transferModuleToConfigResourceList([]moduletypes.Resource{item})...)
+		}
+		addLiveDirectories(item.Unique)
+	}
+	for _, key := range keys {
+		if !added[key] {
+			lists = append(lists, p.liveDirectories[key].config)
+		}
+	}
+
+	return effectiveResourceConfig{
+		Lists:      lists,
+		Extensions: p.allowExtensions,
+		HotFolder:  p.hotFolder,
+		Schedule:   p.schedule,
+		Live:       p.liveConfig,
+	}
+}
+
 func (p *Provider) ParseMessage(message *kpproto.KPMessage) {
 	switch message.Action {
 	case kpproto.EventMessageAction_EVENT_MESSAGE_ACTION_PLAYER_STARTED:
+		p.input_mutex.Lock()
+		defer p.input_mutex.Unlock()
+
+		// the interrupt and live source are played before the playlist
+		p.takeoverCutting = false
+		if p.replayInterrupt() || p.replayLive() {
+			break
+		}
+
+		// the slot of schedule started before the player
+		p.applyScheduleSlot()
 		if len(p.inputs.resources) == 0 {
 			log.Info("the resource list is empty. waiting to add a resource")
+			p.waitingResource = true
+			break
+		}
+		p.refreshLiveDirectories("", false)
+		if !p.checkCurrentIndex() {
 			break
 		}
-
-		p.input_mutex.Lock()
-		defer p.input_mutex.Unlock()
 		p.addNextResourceToCore()
 	case kpproto.EventMessageAction_EVENT_MESSAGE_ACTION_RESOURCE_START:
+		p.input_mutex.Lock()
+		defer p.input_mutex.Unlock()
+
 		msg := &kpmsg.EventMessageResourceStart{}
 		kptypes.UnmarshalProtoMessage(message.Body, msg)
 		log.WithFields(log.Fields{"path": msg.Resource.Path, "unique": msg.Resource.Unique}).
 			Debug("start play resource")
 
+		if res := p.takeoverResource(msg.Resource.Unique); res != nil {
+			res.StartTime = uint64(time.Now().Unix())
+			p.currentDuration, p.currentSeek = 0, msg.Resource.Seek
+			break
+		}
 		res, _, err := p.inputs.GetResourceByUnique(msg.Resource.Unique)
 		if err != nil {
 			log.WithFields(log.Fields{"unique": msg.Resource.Unique, "path": msg.Resource.Path}).Warn(err)
@@ -200,7 +451,9 @@ func (p *Provider) ParseMessage(message *kpproto.KPMessage) {
 		// reset resource seek attribute
 		if seek, ok := p.resetInputs[msg.Resource.Unique]; ok {
 			res.Seek = seek
+			delete(p.resetInputs, msg.Resource.Unique)
 		}
+		p.currentDuration, p.currentSeek = 0, msg.Resource.Seek
 	case kpproto.EventMessageAction_EVENT_MESSAGE_ACTION_RESOURCE_CHECKED:
 		msg := &kpmsg.EventMessageResourceChecked{}
 		kptypes.UnmarshalProtoMessage(message.Body, msg)
@@ -217,6 +470,15 @@ func (p *Provider) ParseMessage(message *kpproto.KPMessage) {
 			logFields["hit_cache"] = msg.HitCache
 		}
 		log.WithFields(logFields).Info("checked play resource")
+
+		p.input_mutex.Lock()
+		defer p.input_mutex.Unlock()
+
+		p.currentDuration = int64(msg.InputAttribute.Duration)
+		if msg.Resource.End > 0 && msg.Resource.End < p.currentDuration {
+			p.currentDuration = msg.Resource.End
+		}
+		p.checkedLive(msg.Resource.Unique)
 	case kpproto.EventMessageAction_EVENT_MESSAGE_ACTION_RESOURCE_FINISH:
 		msg := &kpmsg.EventMessageResourceFinish{}
 		kptypes.UnmarshalProtoMessage(message.Body, msg)
@@ -233,6 +495,11 @@ func (p *Provider) ParseMessage(message *kpproto.KPMessage) {
 		p.input_mutex.Lock()
 		defer p.input_mutex.Unlock()
 
+		// the interrupt and live source are played in place of the next resource
+		if p.finishInterrupt(msg.Resource.Unique) || p.finishCut(msg.Resource.Unique) || p.finishLive(msg.Resource.Unique, msg.Error) {
+			return
+		}
+
 		// get resource
 		res, _, err := p.inputs.GetResourceByUnique(msg.Resource.Unique)
 		if err != nil {
@@ -240,51 +507,197 @@ func (p *Provider) ParseMessage(message *kpproto.KPMessage) {
 			break
 		}
 		res.EndTime = uint64(time.Now().Unix())
+		p.currentDuration, p.currentSeek = 0, 0
+		previousLiveDirectory := res.LiveDirectory
 
-		// play_model
-		switch p.playProvider.GetPlayModel() {
-		case config.PLAY_MODEL_LIST:
-			p.currentIndex = p.currentIndex + 1
-			if p.currentIndex >= len(p.inputs.resources) {
-				log.Info("the playlist has been play completed")
-				stopCorePlay()
+		// the file of hot folder is moved out of playlist
+		removed := p.finishHotFolderResource(msg.Resource.Unique, len(msg.Error) != 0)
+
+		// the pending slot of schedule is played instead of the next resource
+		if p.applyScheduleSlot() {
+			if !p.checkCurrentIndex() {
 				return
 			}
-		case config.PLAY_MODEL_LOOP:
-			p.currentIndex = p.currentIndex + 1
-			if p.currentIndex >= len(p.inputs.resources) {
-				p.currentIndex = 0
-				log.Debug("running mode on [%s]. will a new loop will take place...", strings.ToLower(p.playProvider.GetPlayModel().String()))
-			}
-		case config.PLAY_MODEL_QUEUE:
-			p.currentIndex = p.currentIndex + 1
-			if p.currentIndex >= len(p.inputs.resources) {
-				log.Infof("running mode on [%s]. wait for the resource file to be added...", strings.ToLower(p.playProvider.GetPlayModel().String()))
-				return // wait for new resource
+			p.addNextResourceToCore()
+			return
+		}
+		if removed && len(p.inputs.resources) == 0 {
+			log.Info("the playlist is empty. wait for the resource file to be added...")
+			p.currentIndex = 0
+			p.waitingResource = true
+			return
+		}
+
+		// the resource selected by jump is played instead of the next one of play model
+		jumpIndex := -1
+		if _, index, err := p.inputs.GetResourceByUnique(p.jumpUnique); p.jumpUnique != "" && err == nil {
+			jumpIndex = index
+		}
+		jumpPrevious := p.jumpPrevious
+		p.jumpUnique, p.jumpPrevious = "", false
+
+		// play_model
+		restarted := false
+		if jumpIndex != -1 {
+			// the previous resource of random model has been taken from history
+			if p.playProvider.GetPlayModel() == config.PLAY_MODEL_RANDOM && !jumpPrevious && !removed {
+				p.randomModeUniqueNameHistory = append(p.randomModeUniqueNameHistory, msg.Resource.Unique)
 			}
-		case config.PLAY_MODEL_RANDOM:
-			// refresh list
-			p.randomModeUniqueNameList = []string{}
-			for len(p.randomModeUniqueNameList) == 0 {
-				for _, item := range p.inputs.resources {
-					if !kptypes.ArrayInString(p.randomModeUniqueNameHistory, item.Unique) {
-						p.randomModeUniqueNameList = append(p.randomModeUniqueNameList, item.Unique)
+			p.currentIndex = jumpIndex
+		} else {
+			switch p.playProvider.GetPlayModel() {
+			case config.PLAY_MODEL_LIST:
+				// the playlist completed is checked by checkCurrentIndex
+				p.currentIndex = p.currentIndex + 1
+			case config.PLAY_MODEL_LOOP:
+				p.currentIndex = p.currentIndex + 1
+				if p.currentIndex >= len(p.inputs.resources) {
+					p.currentIndex = 0
+					restarted = true
+					log.Debugf("running mode on [%s]. will a new loop will take place...", strings.ToLower(p.playProvider.GetPlayModel().String()))
+				}
+			case config.PLAY_MODEL_QUEUE:
+				p.currentIndex = p.currentIndex + 1
+				if p.currentIndex >= len(p.inputs.resources) {
+					log.Infof("running mode on [%s]. wait for the resource file to be added...", strings.ToLower(p.playProvider.GetPlayModel().String()))
+					p.waitingResource = true
+					return // wait for new resource
+				}
+			case config.PLAY_MODEL_RANDOM:
+				// the finished resource is excluded from the list until all resources played
+				if !removed {
+					p.randomModeUniqueNameHistory = append(p.randomModeUniqueNameHistory, p.inputs.resources[p.currentIndex].Unique)
+				}
+
+				// refresh list
+				p.randomModeUniqueNameList = []string{}
+				for len(p.randomModeUniqueNameList) == 0 {
+					for _, item := range p.inputs.resources {
+						if !kptypes.ArrayInString(p.randomModeUniqueNameHistory, item.Unique) {
+							p.randomModeUniqueNameList = append(p.randomModeUniqueNameList, item.Unique)
+						}
+					}
+
+					if len(p.randomModeUniqueNameList) == 0 {
+						p.randomModeUniqueNameHistory = []string{}
 					}
 				}
 
-				if len(p.randomModeUniqueNameList) == 0 {
-					p.randomModeUniqueNameHistory = []string{}
+				// random resource of list
+				if _, index, err := p.inputs.GetResourceByUnique(p.playNextUnique); p.playNextUnique != "" && err == nil {
+					p.currentIndex = index
+				} else {
+					pick := p.randomModeUniqueNameList[rand.Intn(len(p.randomModeUniqueNameList))]
+					if _, index, err := p.inputs.GetResourceByUnique(pick); err == nil {
+						p.currentIndex = index
+					}
 				}
+				p.playNextUnique = ""
 			}
+		}
 
-			// random index
-			p.randomModeUniqueNameHistory = append(p.randomModeUniqueNameHistory, p.inputs.resources[p.currentIndex].Unique)
-			p.currentIndex = rand.Intn(len(p.randomModeUniqueNameList))
+		// the files of live directory are refreshed before played
+		p.refreshLiveDirectories(previousLiveDirectory, restarted)
+		if !p.checkCurrentIndex() {
+			return
 		}
 		p.addNextResourceToCore()
 	}
 }
 
+// ParseProgress record the position of playing resource. the progress percent is relative to the resource end
+func (p *Provider) ParseProgress(percent float64, bitRate int) {
+	p.input_mutex.Lock()
+	defer p.input_mutex.Unlock()
+
+	if p.currentDuration > 0 {
+		p.currentSeek = int64(percent / 100 * float64(p.currentDuration))
+	}
+}
+
+// ResumeRunning replay the current resource from the last known position on the core restarted. the resource of
+// interrupt is replayed from the beginning, the live source is reconnected
+func (p *Provider) ResumeRunning() {
+	p.input_mutex.Lock()
+	defer p.input_mutex.Unlock()
+
+	if p.currentIndex < 0 || p.currentIndex >= len(p.inputs.resources) {
+		return
+	}
+	// the playlist resource has been set to the position cut by interrupt or live source
+	if p.takenOver() {
+		return
+	}
+	res, err := p.inputs.GetResourceByIndex(p.currentIndex)
+	if err != nil {
+		log.WithField("index", p.currentIndex).Warn(err)
+		return
+	}
+
+	if p.currentSeek > res.Seek {
+		if _, ok := p.resetInputs[res.Unique]; !ok {
+			p.resetInputs[res.Unique] = res.Seek
+		}
+		res.Seek = p.currentSeek
+	}
+	log.WithFields(log.Fields{"unique": res.Unique, "path": res.Path, "seek": res.Seek}).Info("resume play resource")
+}
+
+// configuredSeek return the seek of resource in config. the playing resource is set to the position resumed
+// by interrupt, journal or watchdog until it is replayed. the input mutex must be held
+func (p *Provider) configuredSeek(res *moduletypes.Resource) int64 {
+	if seek, ok := p.resetInputs[res.Unique]; ok {
+		return seek
+	}
+
+	return res.Seek
+}
+
+// withConfiguredSeek return the copy of resources with the seek in config. the input mutex must be held
+func (p *Provider) withConfiguredSeek(resources []moduletypes.Resource) []moduletypes.Resource {
+	copied := make([]moduletypes.Resource, 0, len(resources))
+	for key := range resources {
+		item := resources[key]
+		item.Seek = p.configuredSeek(&resources[key])
+		copied = append(copied, item)
+	}
+
+	return copied
+}
+
+// checkCurrentIndex keep the current index in playlist after the playlist changed. return false when there is no
+// resource to play. the input mutex must be held
+func (p *Provider) checkCurrentIndex() bool {
+	if len(p.inputs.resources) == 0 {
+		log.Info("the playlist is empty. wait for the resource file to be added...")
+		p.currentIndex = 0
+		p.waitingResource = true
+		return false
+	}
+	if p.currentIndex < len(p.inputs.resources) {
+		return true
+	}
+
+	switch p.playProvider.GetPlayModel() {
+	case config.PLAY_MODEL_LIST:
+		if len(p.scheduleSlots) != 0 {
+			log.Info("the playlist has been play completed. wait for the next schedule slot...")
+			p.waitingResource = true
+			return false
+		}
+		log.Info("the playlist has been play completed")
+		p.stopCorePlay()
+		return false
+	case config.PLAY_MODEL_QUEUE:
+		log.Infof("running mode on [%s]. wait for the resource file to be added...", strings.ToLower(p.playProvider.GetPlayModel().String()))
+		p.waitingResource = true
+		return false
+	}
+	p.currentIndex = 0
+
+	return true
+}
+
 func (p *Provider) addNextResourceToCore() {
 	currentResource, err := p.inputs.GetResourceByIndex(p.currentIndex)
 	if err != nil {
@@ -292,6 +705,13 @@ func (p *Provider) addNextResourceToCore() {
 		return
 	}
 
+	p.addResourceToCore(currentResource)
+}
+
+// addResourceToCore add the resource to core. the input mutex must be held
+func (p *Provider) addResourceToCore(currentResource *moduletypes.Resource) {
+	p.waitingResource = false
+
 	encodePath := currentResource.Path
 
 	// protocol url encode
@@ -317,7 +737,7 @@ func (p *Provider) addNextResourceToCore() {
 		})
 	}
 
-	if err := core.GetLibKplayerInstance().SendPrompt(kpproto.EventPromptAction_EVENT_PROMPT_ACTION_RESOURCE_ADD, &prompt.EventPromptResourceAdd{
+	if err := p.engine.SendPrompt(kpproto.EventPromptAction_EVENT_PROMPT_ACTION_RESOURCE_ADD, &prompt.EventPromptResourceAdd{
 		Resource: &kpproto.PromptResource{
 			Path:      encodePath,
 			Unique:    currentResource.Unique,
@@ -331,8 +751,8 @@ func (p *Provider) addNextResourceToCore() {
 	}
 }
 
-func stopCorePlay() {
-	if err := core.GetLibKplayerInstance().SendPrompt(kpproto.EventPromptAction_EVENT_PROMPT_ACTION_PLAYER_STOP, &prompt.EventPromptPlayerStop{}); err != nil {
+func (p *Provider) stopCorePlay() {
+	if err := p.engine.SendPrompt(kpproto.EventPromptAction_EVENT_PROMPT_ACTION_PLAYER_STOP, &prompt.EventPromptPlayerStop{}); err != nil {
 		log.Warn(err)
 	}
 }
@@ -0,0 +1,190 @@
+package provider
+
+import (
+	"context"
+	"reflect"
+
+	kptypes "github.com/bytelang/kplayer/types"
+	"github.com/bytelang/kplayer/types/config"
+	moduletypes "github.com/bytelang/kplayer/types/module"
+	svrproto "github.com/bytelang/kplayer/types/server"
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/protobuf/types/known/anypb"
+)
+
+// ReloadConfig apply the changed resource config to the playlist. the resources are matched by path, seek, end and
+// mix groups, and by unique when it is configured. the playing resource cannot be removed, it is kept in playlist.
+// the resources added from hot folder are kept, the hot folder is restarted on changed. the new live directory is
+// placed at the end of playlist. the configured playlist kept aside is replaced while the schedule slot playing,
+// the changed schedule takes effect from the next slot. the changed live config takes effect from the next retry
+func (p *Provider) ReloadConfig(ctx context.Context, cfg *config.Resource) error {
+	resources, err := parseResourceList(cfg.Lists, cfg.Extensions)
+	if err != nil {
+		return err
+	}
+	slots, err := parseSchedule(cfg.Schedule)
+	if err != nil {
+		return err
+	}
+
+	p.input_mutex.Lock()
+	if err := p.reloadLiveDirectories(cfg.Lists); err != nil {
+		p.input_mutex.Unlock()
+		return err
+	}
+	p.allowExtensions = cfg.Extensions
+	restartHotFolder := hotFolderChanged(p.hotFolder, cfg.HotFolder)
+	p.hotFolder = cfg.HotFolder
+	restartSchedule := !reflect.DeepEqual(p.schedule, cfg.Schedule)
+	p.schedule = cfg.Schedule
+	p.scheduleSlots = slots
+	p.liveConfig = cfg.Live
+
+	if p.scheduleActive != nil {
+		p.reloadScheduleLists(resources)
+		p.input_mutex.Unlock()
+		p.restartChanged(restartHotFolder, restartSchedule)
+		return nil
+	}
+
+	matched := map[string]bool{}
+	var adds []moduletypes.Resource
+	for _, item := range resources {
+		if res := p.matchResource(item, matched); res != nil {
+			matched[res.Unique] = true
+			continue
+		}
+		adds = append(adds, item)
+	}
+
+	var removes []moduletypes.Resource
+	for _, item := range p.inputs.resources {
+		if _, ok := p.hotFolderUniques[item.Unique]; !ok && !matched[item.Unique] {
+			removes = append(removes, item)
+		}
+	}
+	p.input_mutex.Unlock()
+	p.restartChanged(restartHotFolder, restartSchedule)
+
+	for _, item := range removes {
+		logFields := log.WithFields(log.Fields{"path": item.Path, "unique": item.Unique})
+		if _, err := p.ResourceRemove(ctx, &svrproto.ResourceRemoveArgs{Unique: item.Unique}); err != nil {
+			logFields.WithField("error", err).Error("reload remove resource failed")
+			continue
+		}
+		logFields.Info("reload remove resource success")
+	}
+	for _, item := range adds {
+		item = setResourceUniqueName(item)
+
+		logFields := log.WithFields(log.Fields{"path": item.Path, "unique": item.Unique})
+		if _, err := p.ResourceAdd(ctx, &svrproto.ResourceAddArgs{
+			Path:            item.Path,
+			Unique:          item.Unique,
+			Seek:            item.Seek,
+			End:             item.End,
+			MixResourceType: item.MixResourceType,
+			Groups:          TransferModuleToServerResourceGroup(item.Groups),
+		}); err != nil {
+			logFields.WithField("error", err).Error("reload add resource failed")
+			continue
+		}
+		if item.LiveDirectory != "" {
+			p.input_mutex.Lock()
+			if res, _, err := p.inputs.GetResourceByUnique(item.Unique); err == nil {
+				res.LiveDirectory = item.LiveDirectory
+			}
+			p.input_mutex.Unlock()
+		}
+		logFields.Info("reload add resource success")
+	}
+
+	return nil
+}
+
+// restartChanged restart the hot folder and the schedule on changed
+func (p *Provider) restartChanged(hotFolder bool, schedule bool) {
+	if hotFolder && p.hotFolderOn {
+		p.EndHotFolder()
+		p.StartHotFolder()
+		log.Info("reload hot folder success")
+	}
+	if schedule && p.scheduleOn {
+		p.EndSchedule()
+		p.StartSchedule()
+		log.Info("reload schedule success")
+	}
+}
+
+// reloadScheduleLists replace the configured playlist kept aside while the schedule slot playing. the resources
+// added from hot folder are kept. the input mutex must be held
+func (p *Provider) reloadScheduleLists(resources []moduletypes.Resource) {
+	var lists Resources
+	for _, item := range p.scheduleLists {
+		if _, ok := p.hotFolderUniques[item.Unique]; ok {
+			_ = lists.AppendResource(item)
+		}
+	}
+	for _, item := range resources {
+		item = setResourceUniqueName(item)
+		if err := lists.AppendResource(item); err != nil {
+			log.WithFields(log.Fields{"path": item.Path, "unique": item.Unique}).Warn(err)
+		}
+	}
+	p.scheduleLists = lists.resources
+	log.WithField("count", len(p.scheduleLists)).Info("reload playlist kept aside by schedule slot success")
+}
+
+// matchResource return the resource in playlist of config item which is not matched yet. the input mutex must be held
+func (p *Provider) matchResource(item moduletypes.Resource, matched map[string]bool) *moduletypes.Resource {
+	for key := range p.inputs.resources {
+		res := &p.inputs.resources[key]
+		if matched[res.Unique] || res.Path != item.Path || p.configuredSeek(res) != item.Seek || res.End != item.End {
+			continue
+		}
+		if res.MixResourceType != item.MixResourceType || !reflect.DeepEqual(res.Groups, item.Groups) ||
+			res.LiveDirectory != item.LiveDirectory {
+			continue
+		}
+		if item.Unique != "" && item.Unique != res.Unique {
+			continue
+		}
+
+		return res
+	}
+
+	return nil
+}
+
+// reloadLiveDirectories replace the live directories of config. the anchors of kept directories are unchanged.
+// the input mutex must be held
+func (p *Provider) reloadLiveDirectories(lists []*anypb.Any) error {
+	anchor := ""
+	if resources := p.configuredResources(); len(resources) != 0 {
+		anchor = resources[len(resources)-1].Unique
+	}
+
+	liveDirectories := map[string]*liveDirectory{}
+	for _, list := range lists {
+		res, err := kptypes.GetResourceItemByAny(list)
+		if err != nil {
+			continue
+		}
+		directory, ok := res.(*config.DirectoryResource)
+		if !ok || !directory.Live {
+			continue
+		}
+
+		key := liveDirectoryKey(directory)
+		if _, ok := liveDirectories[key]; ok {
+			return LiveDirectoryHasExisted
+		}
+		liveDirectories[key] = &liveDirectory{config: directory, anchor: anchor}
+		if exist, ok := p.liveDirectories[key]; ok {
+			liveDirectories[key].anchor = exist.anchor
+		}
+	}
+	p.liveDirectories = liveDirectories
+
+	return nil
+}
@@ -0,0 +1,344 @@
+package provider
+
+import (
+	"fmt"
+	"math/rand"
+	"os"
+	"time"
+
+	kptypes "github.com/bytelang/kplayer/types"
+	"github.com/bytelang/kplayer/types/config"
+	moduletypes "github.com/bytelang/kplayer/types/module"
+	log "github.com/sirupsen/logrus"
+)
+
+// scheduleSlot the slot of schedule parsed from config
+type scheduleSlot struct {
+	config   *config.ScheduleSlot
+	unique   string
+	location *time.Location
+
+	hour, minute, second int
+	days                 map[time.Weekday]bool
+	dates                map[string]bool
+}
+
+// occurrence return the start time of slot on the day of date in the slot time zone. byDate is true when the slot
+// occurs on the date by its dates
+func (s *scheduleSlot) occurrence(date time.Time) (start time.Time, byDate bool, ok bool) {
+	date = date.In(s.location)
+	year, month, day := date.Date()
+	start = time.Date(year, month, day, s.hour, s.minute, s.second, 0, s.location)
+
+	if s.dates[start.Format("2006-01-02")] {
+		return start, true, true
+	}
+	if s.days[start.Weekday()] {
+		return start, false, true
+	}
+
+	return start, false, false
+}
+
+// ValidateScheduleSlot return error when the slot of schedule is malformed
+func ValidateScheduleSlot(cfg *config.ScheduleSlot, timezone string) error {
+	_, err := parseScheduleSlot(cfg, timezone)
+	return err
+}
+
+// parseScheduleSlot parse the slot of schedule. the time zone of schedule is used when the slot one is empty
+func parseScheduleSlot(cfg *config.ScheduleSlot, timezone string) (*scheduleSlot, error) {
+	if cfg.Timezone != "" {
+		timezone = cfg.Timezone
+	}
+	location, err := time.LoadLocation(timezone)
+	if err != nil {
+		return nil, fmt.Errorf("invalid time zone %s. error: %s", timezone, err)
+	}
+
+	slot := &scheduleSlot{
+		config:   cfg,
+		unique:   cfg.Unique,
+		location: location,
+		days:     map[time.Weekday]bool{},
+		dates:    map[string]bool{},
+	}
+	if slot.unique == "" {
+		slot.unique = kptypes.ShortNameGenerate(cfg.Start + cfg.Path)[0]
+	}
+
+	clock, err := time.Parse("15:04:05", cfg.Start)
+	if err != nil {
+		if clock, err = time.Parse("15:04", cfg.Start); err != nil {
+			return nil, fmt.Errorf("invalid start time %s. expected HH:MM or HH:MM:SS", cfg.Start)
+		}
+	}
+	slot.hour, slot.minute, slot.second = clock.Clock()
+
+	for _, item := range cfg.Days {
+		days, ok := scheduleDays[item]
+		if !ok {
+			return nil, fmt.Errorf("invalid day %s. available: daily, weekdays, weekends, mon, tue, wed, thu, fri, sat, sun", item)
+		}
+		for _, day := range days {
+			slot.days[day] = true
+		}
+	}
+	for _, item := range cfg.Dates {
+		date, err := time.Parse("2006-01-02", item)
+		if err != nil {
+			return nil, fmt.Errorf("invalid date %s. expected YYYY-MM-DD", item)
+		}
+		slot.dates[date.Format("2006-01-02")] = true
+	}
+	if len(cfg.Days) == 0 && len(cfg.Dates) == 0 {
+		for _, day := range scheduleDays["daily"] {
+			slot.days[day] = true
+		}
+	}
+
+	if cfg.Sort != "" && !kptypes.ArrayInString(DirectorySortOrders, cfg.Sort) {
+		return nil, fmt.Errorf("invalid sort order %s. available: %v", cfg.Sort, DirectorySortOrders)
+	}
+	if cfg.Boundary != "" && cfg.Boundary != ScheduleBoundaryCut && cfg.Boundary != ScheduleBoundaryFinish {
+		return nil, fmt.Errorf("invalid boundary %s. available: %s, %s", cfg.Boundary, ScheduleBoundaryCut, ScheduleBoundaryFinish)
+	}
+
+	return slot, nil
+}
+
+// parseSchedule parse the slots of schedule. the slot unique must be unique
+func parseSchedule(cfg *config.Schedule) ([]*scheduleSlot, error) {
+	if cfg == nil {
+		return nil, nil
+	}
+
+	var slots []*scheduleSlot
+	exists := map[string]bool{}
+	for _, item := range cfg.Slots {
+		slot, err := parseScheduleSlot(item, cfg.Timezone)
+		if err != nil {
+			return nil, fmt.Errorf("schedule slot %s invalid. error: %s", item.Start, err)
+		}
+		if exists[slot.unique] {
+			return nil, fmt.Errorf("%s. unique: %s", ScheduleSlotHasExisted, slot.unique)
+		}
+		exists[slot.unique] = true
+		slots = append(slots, slot)
+	}
+
+	return slots, nil
+}
+
+// currentScheduleSlot return the slot started last before now. the slots of the past week are searched,
+// nil when no slot started in it
+func currentScheduleSlot(slots []*scheduleSlot, now time.Time) *scheduleSlot {
+	var current *scheduleSlot
+	var currentStart time.Time
+	currentByDate := false
+	for _, slot := range slots {
+		for day := 0; day <= 7; day++ {
+			start, byDate, ok := slot.occurrence(now.AddDate(0, 0, -day))
+			if !ok || start.After(now) {
+				continue
+			}
+			if current == nil || start.After(currentStart) || (start.Equal(currentStart) && byDate && !currentByDate) {
+				current, currentStart, currentByDate = slot, start, byDate
+			}
+			break
+		}
+	}
+
+	return current
+}
+
+// nextScheduleSlot return the slot starts first after the time. nil when no slot starts in the next week
+func nextScheduleSlot(slots []*scheduleSlot, after time.Time) (*scheduleSlot, time.Time) {
+	var next *scheduleSlot
+	var nextStart time.Time
+	nextByDate := false
+	for _, slot := range slots {
+		for day := -1; day <= 7; day++ {
+			start, byDate, ok := slot.occurrence(after.AddDate(0, 0, day))
+			if !ok || !start.After(after) {
+				continue
+			}
+			if next == nil || start.Before(nextStart) || (start.Equal(nextStart) && byDate && !nextByDate) {
+				next, nextStart, nextByDate = slot, start, byDate
+			}
+			break
+		}
+	}
+
+	return next, nextStart
+}
+
+// loadScheduleSlot return the resources of playlist file or directory of slot. the resource unique is numbered after
+// the slot unique when it is not configured, as the slot is loaded each time it starts
+func loadScheduleSlot(slot *scheduleSlot, allowExtensions []string) ([]moduletypes.Resource, error) {
+	stat, err := os.Stat(slot.config.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	var resources []moduletypes.Resource
+	if stat.IsDir() {
+		files, err := ScanDirectoryResource(&config.DirectoryResource{
+			Directory: slot.config.Path,
+			Recursive: slot.config.Recursive,
+			Sort:      slot.config.Sort,
+		}, allowExtensions)
+		if err != nil {
+			return nil, err
+		}
+		for _, f := range files {
+			resources = append(resources, moduletypes.Resource{
+				Path:       f,
+				Seek:       0,
+				End:        -1,
+				CreateTime: uint64(time.Now().Unix()),
+			})
+		}
+	} else if IsPlaylistFile(slot.config.Path) {
+		if resources, err = ParsePlaylistFile(slot.config.Path, ""); err != nil {
+			return nil, err
+		}
+	} else {
+		return nil, fmt.Errorf("not playlist file or directory")
+	}
+
+	// the duplicated resource is played once
+	var items Resources
+	for key, item := range resources {
+		if item.Unique == "" {
+			item.Unique = fmt.Sprintf("%s-%d", slot.unique, key+1)
+		}
+		if err := items.AppendResource(item); err != nil {
+			log.WithFields(log.Fields{"path": item.Path, "unique": item.Unique, "slot": slot.unique}).Warn(err)
+		}
+	}
+
+	return items.resources, nil
+}
+
+// StartSchedule start the slots of schedule on time until EndSchedule called. it does nothing when no slot configured
+func (p *Provider) StartSchedule() {
+	p.scheduleOn = true
+
+	p.input_mutex.Lock()
+	slots := p.scheduleSlots
+	p.input_mutex.Unlock()
+	if len(slots) == 0 {
+		return
+	}
+
+	p.scheduleStop = make(chan bool)
+	p.scheduleDone = make(chan bool)
+	go func(stop chan bool, done chan bool) {
+		defer close(done)
+
+		now := time.Now()
+		for {
+			slot, start := nextScheduleSlot(slots, now)
+			if slot == nil {
+				<-stop
+				return
+			}
+			log.WithFields(log.Fields{"slot": slot.unique, "start": start.Format(time.RFC3339)}).Debug("wait for the next schedule slot")
+
+			timer := time.NewTimer(time.Until(start))
+			select {
+			case <-stop:
+				timer.Stop()
+				return
+			case <-timer.C:
+			}
+
+			p.startScheduleSlot(slot)
+			now = start
+		}
+	}(p.scheduleStop, p.scheduleDone)
+}
+
+// EndSchedule stop starting the slots of schedule
+func (p *Provider) EndSchedule() {
+	p.scheduleOn = false
+	if p.scheduleStop == nil {
+		return
+	}
+
+	close(p.scheduleStop)
+	<-p.scheduleDone
+	p.scheduleStop = nil
+}
+
+// startScheduleSlot play the slot on the playing resource finished. the playing resource is skipped on the boundary
+// of cut. the slot is played at once when the player is waiting for resource
+func (p *Provider) startScheduleSlot(slot *scheduleSlot) {
+	p.input_mutex.Lock()
+	defer p.input_mutex.Unlock()
+
+	log.WithFields(log.Fields{"slot": slot.unique, "path": slot.config.Path, "boundary": slot.config.Boundary}).Info("schedule slot started")
+	p.schedulePending = slot
+	if p.waitingResource {
+		if !p.applyScheduleSlot() || !p.checkCurrentIndex() {
+			return
+		}
+		p.addNextResourceToCore()
+		return
+	}
+
+	// the interrupt and live source are not cut. the slot is played after them finished
+	if slot.config.Boundary == ScheduleBoundaryCut && !p.takenOver() && !p.takeoverCutting {
+		// the slot kept pending is played on the playing resource finished when the cut failed
+		failed := func(err error) {
+			log.WithFields(log.Fields{"slot": slot.unique, "error": err}).Warn("skip playing resource failed")
+		}
+		if err := p.cutPlaying(failed); err != nil {
+			failed(err)
+		}
+	}
+}
+
+// applyScheduleSlot replace the playlist with the resources of pending slot. the configured playlist is kept aside
+// while the slot playing. return false when no slot pending or the slot cannot be loaded. the input mutex must be held
+func (p *Provider) applyScheduleSlot() bool {
+	slot := p.schedulePending
+	if slot == nil {
+		return false
+	}
+	p.schedulePending = nil
+
+	logFields := log.WithFields(log.Fields{"slot": slot.unique, "path": slot.config.Path})
+	resources, err := loadScheduleSlot(slot, p.allowExtensions)
+	if err != nil {
+		logFields.WithField("error", err).Warn("load schedule slot failed. the playlist is kept")
+		return false
+	}
+
+	if p.scheduleActive == nil {
+		p.scheduleLists = p.inputs.resources
+	}
+	p.inputs.resources = resources
+	p.scheduleActive = slot
+
+	p.currentIndex = 0
+	if p.playProvider.GetPlayModel() == config.PLAY_MODEL_RANDOM && len(resources) != 0 {
+		p.currentIndex = rand.Intn(len(resources))
+	}
+	p.randomModeUniqueNameHistory = []string{}
+	p.playNextUnique = ""
+	p.jumpUnique, p.jumpPrevious = "", false
+
+	logFields.WithField("count", len(resources)).Info("play schedule slot")
+	return true
+}
+
+// configuredResources return the resources of the configured playlist. the input mutex must be held
+func (p *Provider) configuredResources() []moduletypes.Resource {
+	if p.scheduleActive != nil {
+		return p.scheduleLists
+	}
+
+	return p.inputs.resources
+}
@@ -0,0 +1,180 @@
+package provider
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/bytelang/kplayer/core"
+	playprovider "github.com/bytelang/kplayer/module/play/provider"
+	kptypes "github.com/bytelang/kplayer/types"
+	"github.com/bytelang/kplayer/types/config"
+	svrproto "github.com/bytelang/kplayer/types/server"
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes"
+	"google.golang.org/protobuf/types/known/anypb"
+)
+
+func TestScheduleSlots(t *testing.T) {
+	slots, err := parseSchedule(&config.Schedule{
+		Timezone: "Asia/Shanghai",
+		Slots: []*config.ScheduleSlot{
+			{Unique: "morning", Start: "08:00", Days: []string{"weekdays"}, Path: "/morning"},
+			{Unique: "news", Start: "12:00:30", Path: "/news"},
+			{Unique: "holiday", Start: "08:00", Dates: []string{"2026-12-25"}, Path: "/holiday"},
+			{Unique: "night", Start: "20:00", Timezone: "UTC", Days: []string{"sat"}, Path: "/night"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	location, err := time.LoadLocation("Asia/Shanghai")
+	if err != nil {
+		t.Fatal(err)
+	}
+	at := func(value string) time.Time {
+		date, err := time.ParseInLocation("2006-01-02 15:04:05", value, location)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return date
+	}
+
+	// 2026-12-25 is friday
+	for _, item := range []struct {
+		now      string
+		expected string
+	}{
+		{"2026-12-24 09:00:00", "morning"},
+		{"2026-12-24 12:00:29", "morning"},
+		{"2026-12-24 12:00:30", "news"},
+		{"2026-12-25 09:00:00", "holiday"},
+		{"2026-12-26 09:00:00", "news"},
+		{"2026-12-27 05:00:00", "night"},
+	} {
+		slot := currentScheduleSlot(slots, at(item.now))
+		if slot == nil || slot.unique != item.expected {
+			t.Errorf("unexpected current slot at %s: %v", item.now, slot)
+		}
+	}
+
+	for _, item := range []struct {
+		after    string
+		expected string
+		start    string
+	}{
+		{"2026-12-24 12:00:30", "holiday", "2026-12-25 08:00:00"},
+		{"2026-12-26 13:00:00", "night", "2026-12-27 04:00:00"},
+	} {
+		slot, start := nextScheduleSlot(slots, at(item.after))
+		if slot == nil || slot.unique != item.expected || !start.Equal(at(item.start)) {
+			t.Errorf("unexpected next slot after %s: %v, %s", item.after, slot, start)
+		}
+	}
+	if slot := currentScheduleSlot(nil, time.Now()); slot != nil {
+		t.Fatalf("unexpected current slot: %v", slot)
+	}
+
+	for _, slot := range []*config.ScheduleSlot{
+		{Start: "8am", Path: "/a"},
+		{Start: "08:00", Days: []string{"someday"}, Path: "/a"},
+		{Start: "08:00", Dates: []string{"2026-13-01"}, Path: "/a"},
+		{Start: "08:00", Timezone: "Nowhere/City", Path: "/a"},
+		{Start: "08:00", Boundary: "fade", Path: "/a"},
+	} {
+		if err := ValidateScheduleSlot(slot, ""); err == nil {
+			t.Errorf("expected invalid slot: %v", slot)
+		}
+	}
+	if _, err := parseSchedule(&config.Schedule{Slots: []*config.ScheduleSlot{
+		{Unique: "a", Start: "08:00", Path: "/a"},
+		{Unique: "a", Start: "09:00", Path: "/b"},
+	}}); err == nil {
+		t.Fatal("expected slot unique existed")
+	}
+}
+
+func TestScheduleBoundary(t *testing.T) {
+	blockDir, newsDir := t.TempDir(), t.TempDir()
+	writeDirectoryFiles(t, blockDir, "x.mp4", "y.mp4")
+	writeDirectoryFiles(t, newsDir, "news.mp4")
+
+	var lists []*anypb.Any
+	for _, item := range []proto.Message{
+		&config.SingleResource{Path: "/video/a.mp4", Unique: "a"},
+		&config.SingleResource{Path: "/video/b.mp4", Unique: "b"},
+	} {
+		any, err := ptypes.MarshalAny(item)
+		if err != nil {
+			t.Fatal(err)
+		}
+		lists = append(lists, any)
+	}
+
+	fe := core.NewFakeEngine()
+	pp := playprovider.NewProvider(fe)
+	pp.InitModule(kptypes.DefaultClientContext(), &config.Play{
+		StartPoint: 1,
+		PlayModel:  "loop",
+		Rpc:        &config.Server{},
+		Encode:     &config.Encode{},
+	})
+	p := NewProvider(fe, pp)
+	p.InitModule(kptypes.DefaultClientContext(), &config.Resource{Lists: lists, Extensions: []string{"mp4"}})
+	slots, err := parseSchedule(&config.Schedule{Slots: []*config.ScheduleSlot{
+		{Unique: "block", Start: "08:00", Path: blockDir, Boundary: ScheduleBoundaryFinish},
+		{Unique: "news", Start: "12:00", Path: newsDir, Boundary: ScheduleBoundaryCut},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.scheduleSlots = slots
+	fe.SetCallBackMessage(func(message *core.Message) {
+		p.ParseMessage(message.KPMessage)
+		p.Trigger(message)
+	})
+
+	resultChan := make(chan int)
+	go func() {
+		resultChan <- fe.Run()
+	}()
+	defer func() {
+		fe.Terminate(0)
+		<-resultChan
+	}()
+	waitPlaying(t, p, "a")
+
+	// the slot of finish boundary is played after the playing resource finished
+	p.startScheduleSlot(slots[0])
+	reply, err := p.ResourceSchedule(context.Background(), &svrproto.ResourceScheduleArgs{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reply.Pending != "block" || reply.Active != "" || reply.Next == "" {
+		t.Fatalf("unexpected schedule: %v", reply)
+	}
+	waitPlaying(t, p, "a")
+	fe.Advance(core.FakeDefaultResourceDuration)
+	waitPlaying(t, p, "block-1")
+	if len(p.inputs.resources) != 2 || p.inputs.resources[1].Path != filepath.Join(blockDir, "y.mp4") {
+		t.Fatalf("unexpected playlist: %v", p.inputs.resources)
+	}
+
+	// the configured playlist is kept aside
+	cfg := p.GetEffectiveConfig().(effectiveResourceConfig)
+	if len(cfg.Lists) != 2 {
+		t.Fatalf("unexpected effective config: %v", cfg.Lists)
+	}
+
+	// the slot of cut boundary skips the playing resource
+	p.startScheduleSlot(slots[1])
+	waitPlaying(t, p, "news-1")
+	reply, err = p.ResourceSchedule(context.Background(), &svrproto.ResourceScheduleArgs{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reply.Pending != "" || reply.Active != "news" {
+		t.Fatalf("unexpected schedule: %v", reply)
+	}
+}
@@ -0,0 +1,139 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httputil"
+	"reflect"
+	"strings"
+
+	autherror "github.com/bytelang/kplayer/types/error"
+	"github.com/bytelang/kplayer/types/server"
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// channelPathPrefix http requests under /channels/{name}/ are proxied to the channel
+const channelPathPrefix = "/channels/"
+
+// channelHandler proxy the http requests of channels to their http server, websocket included
+func (h *httpServer) channelHandler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if h.channels == nil || !strings.HasPrefix(r.URL.Path, channelPathPrefix) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		// the channel requests are authorized as the requests of the gateway
+		if h.authOn && r.Header.Get(server.AUTHORIZATION_METADATA_KEY) != h.authToken {
+			http.Error(w, autherror.AuthTokenInvalid.Error(), http.StatusUnauthorized)
+			return
+		}
+
+		parts := strings.SplitN(strings.TrimPrefix(r.URL.Path, channelPathPrefix), "/", 2)
+		_, httpEndpoint, err := h.channels.GetChannelEndpoint(parts[0])
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		path := "/"
+		if len(parts) == 2 {
+			path = path + parts[1]
+		}
+
+		proxy := &httputil.ReverseProxy{
+			Director: func(req *http.Request) {
+				req.URL.Scheme = "http"
+				req.URL.Host = httpEndpoint
+				req.URL.Path = path
+				req.URL.RawPath = ""
+				req.Host = httpEndpoint
+			},
+			ErrorHandler: func(w http.ResponseWriter, req *http.Request, err error) {
+				log.WithFields(log.Fields{"channel": parts[0], "error": err}).Warn("proxy channel request failed")
+				http.Error(w, err.Error(), http.StatusBadGateway)
+			},
+		}
+		proxy.ServeHTTP(w, r)
+	})
+}
+
+// channelInterceptor forward the grpc requests carrying channel metadata to the grpc server of channel
+func (h *httpServer) channelInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	names := md.Get(server.CHANNEL_METADATA_KEY)
+	if h.channels == nil || len(names) == 0 || names[0] == "" {
+		return handler(ctx, req)
+	}
+
+	conn, err := h.getChannelConn(names[0])
+	if err != nil {
+		return nil, err
+	}
+
+	// the reply type is the result of service method
+	method := info.FullMethod[strings.LastIndex(info.FullMethod, "/")+1:]
+	fn := reflect.ValueOf(info.Server).MethodByName(method)
+	if !fn.IsValid() {
+		return nil, status.Errorf(codes.Unimplemented, "method %s not implemented", method)
+	}
+	reply := reflect.New(fn.Type().Out(0).Elem()).Interface()
+
+	if err := conn.Invoke(metadata.NewOutgoingContext(ctx, forwardMetadata(md)), info.FullMethod, req, reply); err != nil {
+		return nil, err
+	}
+
+	return reply, nil
+}
+
+// forwardMetadata return the incoming metadata without the channel key and the transport reserved keys
+func forwardMetadata(md metadata.MD) metadata.MD {
+	forward := metadata.MD{}
+	for key, values := range md {
+		if key == strings.ToLower(server.CHANNEL_METADATA_KEY) || strings.HasPrefix(key, ":") || strings.HasPrefix(key, "grpc-") {
+			continue
+		}
+		switch key {
+		case "content-type", "user-agent", "te":
+			continue
+		}
+		forward[key] = values
+	}
+
+	return forward
+}
+
+func (h *httpServer) getChannelConn(name string) (*grpc.ClientConn, error) {
+	h.channelLock.Lock()
+	defer h.channelLock.Unlock()
+
+	if conn, ok := h.channelConns[name]; ok {
+		return conn, nil
+	}
+
+	grpcEndpoint, _, err := h.channels.GetChannelEndpoint(name)
+	if err != nil {
+		return nil, status.Error(codes.NotFound, err.Error())
+	}
+	conn, err := grpc.Dial(grpcEndpoint, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return nil, status.Error(codes.Unavailable, err.Error())
+	}
+	h.channelConns[name] = conn
+
+	return conn, nil
+}
+
+func (h *httpServer) closeChannelConns() {
+	h.channelLock.Lock()
+	defer h.channelLock.Unlock()
+
+	for name, conn := range h.channelConns {
+		_ = conn.Close()
+		delete(h.channelConns, name)
+	}
+}
@@ -0,0 +1,62 @@
+package server
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/bytelang/kplayer/types/server"
+)
+
+type testChannelRouter struct {
+	httpEndpoint string
+}
+
+func (r *testChannelRouter) GetChannelEndpoint(name string) (string, string, error) {
+	return "", r.httpEndpoint, nil
+}
+
+func TestChannelHandlerAuth(t *testing.T) {
+	channel := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.URL.Path))
+	}))
+	defer channel.Close()
+
+	h := &httpServer{
+		authOn:    true,
+		authToken: "token",
+		channels:  &testChannelRouter{httpEndpoint: strings.TrimPrefix(channel.URL, "http://")},
+	}
+	gateway := httptest.NewServer(h.channelHandler(http.NotFoundHandler()))
+	defer gateway.Close()
+
+	for _, item := range []struct {
+		token  string
+		status int
+	}{
+		{"", http.StatusUnauthorized},
+		{"invalid", http.StatusUnauthorized},
+		{"token", http.StatusOK},
+	} {
+		req, err := http.NewRequest("GET", gateway.URL+"/channels/news/play/duration", nil)
+		assertError(t, err)
+		if item.token != "" {
+			req.Header.Set(server.AUTHORIZATION_METADATA_KEY, item.token)
+		}
+
+		resp, err := getClient().Do(req)
+		assertError(t, err)
+		body, err := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		assertError(t, err)
+
+		if resp.StatusCode != item.status {
+			t.Fatalf("unexpected status: %d, token: %s, body: %s", resp.StatusCode, item.token, body)
+		}
+		if item.status == http.StatusOK && string(body) != "/play/duration" {
+			t.Fatalf("unexpected proxied path: %s", body)
+		}
+	}
+}
@@ -48,7 +48,7 @@ func TestPlayPause(t *testing.T) {
 	}
 
 	// validate seek
-	time.Sleep(time.Second * 5)
+	advance(time.Second * 5)
 	_, pauseSeek := getCurrentResourceSeek(t)
 
 	if pauseSeek-preSeek > 3 {
@@ -101,6 +101,20 @@ func TestPlayInformation(t *testing.T) {
 	t.Log(string(body))
 }
 
+func TestPlayProgress(t *testing.T) {
+	resp, err := getClient().Get(Host + "play/progress")
+	assertError(t, err)
+
+	body, err := ioutil.ReadAll(resp.Body)
+	assertError(t, err)
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatal(string(body))
+	}
+
+	t.Log(string(body))
+}
+
 func TestPlaySkip(t *testing.T) {
 	// add resource
 	{
@@ -157,7 +171,7 @@ func TestPlaySkip(t *testing.T) {
 	t.Log(string(body))
 
 	// wait a moment
-	time.Sleep(time.Second * 5)
+	advance(time.Second * 5)
 	// validate resource
 	{
 		req, err := http.NewRequest("GET", Host+"resource/current", nil)
@@ -189,6 +203,10 @@ func TestPlaySkip(t *testing.T) {
 
 		t.Log(string(body))
 	}
+
+	// skip back to the resource played before, the added resource can be removed then
+	skipResource(t)
+	removeResource("resource-1", t)
 }
 
 func TestPlayStop(t *testing.T) {
@@ -214,3 +232,20 @@ func TestPlayStop(t *testing.T) {
 
 	t.Log(string(body))
 }
+
+// skipResource skip the resource playing and wait for the next one started
+func skipResource(t *testing.T) {
+	req, err := http.NewRequest("POST", Host+"play/skip", nil)
+	assertError(t, err)
+
+	resp, err := getClient().Do(req)
+	assertError(t, err)
+
+	body, err := ioutil.ReadAll(resp.Body)
+	assertError(t, err)
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatal(string(body))
+	}
+	advance(time.Second)
+}
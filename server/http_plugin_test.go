@@ -56,7 +56,7 @@ func TestPluginAdd(t *testing.T) {
 }
 
 func TestPluginUpdate(t *testing.T) {
-	time.Sleep(time.Second * 1)
+	advance(time.Second * 1)
 	postData := struct {
 		Unique string            `json:"unique"`
 		Params map[string]string `json:"params"`
@@ -59,7 +59,7 @@ func TestResourceAdd(t *testing.T) {
 	}
 
 	// remove
-	defer removeResource("resource-1", t)
+	removeResource("resource-1", t)
 
 	// validate
 	{
@@ -174,7 +174,7 @@ func TestMixResourceAdd(t *testing.T) {
 func TestResourceSeekStart(t *testing.T) {
 	uniqueName, preSeek := getCurrentResourceSeek(t)
 	if preSeek <= 5 {
-		time.Sleep(time.Second * 5)
+		advance(time.Second * 5)
 	}
 
 	postData := struct {
@@ -200,7 +200,7 @@ func TestResourceSeekStart(t *testing.T) {
 
 	t.Log(string(body))
 
-	time.Sleep(time.Second * 3)
+	advance(time.Second * 3)
 
 	// validate
 	{
@@ -218,7 +218,11 @@ func TestResourceSeekStart(t *testing.T) {
 }
 
 func TestMixResourceSeekUnique(t *testing.T) {
-	defer removeResource("resource-1", t)
+	// skip back to the resource played before, the resource seeked can be removed then
+	defer func() {
+		skipResource(t)
+		removeResource("resource-1", t)
+	}()
 	{
 		postData := struct {
 			Unique          string                   `json:"unique"`
@@ -278,7 +282,7 @@ func TestMixResourceSeekUnique(t *testing.T) {
 
 	t.Log(string(body))
 
-	time.Sleep(time.Second * 3)
+	advance(time.Second * 3)
 
 	// validate
 	{
@@ -293,13 +297,10 @@ func TestMixResourceSeekUnique(t *testing.T) {
 
 func TestMixResourceSeekStart(t *testing.T) {
 	TestMixResourceSeekUnique(t)
-	defer func() {
-		removeResource("resource-1", t)
-	}()
 
 	uniqueName, preSeek := getCurrentResourceSeek(t)
 	if preSeek <= 5 {
-		time.Sleep(time.Second * 5)
+		advance(time.Second * 5)
 	}
 
 	postData := struct {
@@ -325,7 +326,7 @@ func TestMixResourceSeekStart(t *testing.T) {
 
 	t.Log(string(body))
 
-	time.Sleep(time.Second * 3)
+	advance(time.Second * 3)
 
 	// validate
 	{
@@ -347,7 +348,7 @@ func TestMixResourceSeekDuration(t *testing.T) {
 
 	uniqueName, preSeek := getCurrentResourceSeek(t)
 	if preSeek <= 5 {
-		time.Sleep(time.Second * 5)
+		advance(time.Second * 5)
 	}
 
 	postData := struct {
@@ -374,7 +375,7 @@ func TestMixResourceSeekDuration(t *testing.T) {
 	t.Log(string(body))
 
 	// validate
-	time.Sleep(time.Second * 3)
+	advance(time.Second * 3)
 	{
 		queryUniqueName, seek := getCurrentResourceSeek(t)
 		if queryUniqueName != uniqueName {
@@ -390,7 +391,7 @@ func TestMixResourceSeekDuration(t *testing.T) {
 func TestResourceSeekDuration(t *testing.T) {
 	uniqueName, preSeek := getCurrentResourceSeek(t)
 	if preSeek <= 5 {
-		time.Sleep(time.Second * 5)
+		advance(time.Second * 5)
 	}
 
 	postData := struct {
@@ -417,7 +418,7 @@ func TestResourceSeekDuration(t *testing.T) {
 	t.Log(string(body))
 
 	// validate
-	time.Sleep(time.Second * 3)
+	advance(time.Second * 3)
 	{
 		queryUniqueName, seek := getCurrentResourceSeek(t)
 		if queryUniqueName != uniqueName {
@@ -2,14 +2,13 @@ package server
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
-	"github.com/bytelang/kplayer/cmd"
+	"github.com/bytelang/kplayer/eventbus"
 	"github.com/bytelang/kplayer/module"
-	outputprovider "github.com/bytelang/kplayer/module/output/provider"
 	playprovider "github.com/bytelang/kplayer/module/play/provider"
-	pluginprovider "github.com/bytelang/kplayer/module/plugin/provider"
-	resourceprovider "github.com/bytelang/kplayer/module/resource/provider"
 	kptypes "github.com/bytelang/kplayer/types"
+	kpproto "github.com/bytelang/kplayer/types/core/proto"
 	autherror "github.com/bytelang/kplayer/types/error"
 	"github.com/bytelang/kplayer/types/server"
 	"github.com/gorilla/websocket"
@@ -25,11 +24,18 @@ import (
 	"google.golang.org/protobuf/encoding/protojson"
 	"net"
 	"net/http"
+	"strings"
+	"sync"
 )
 
 type httpServer struct {
 	authOn    bool
 	authToken string
+
+	// channels served by child processes
+	channels     server.ChannelRouter
+	channelConns map[string]*grpc.ClientConn
+	channelLock  sync.Mutex
 }
 
 func NewHttpServer() *httpServer {
@@ -42,15 +48,14 @@ type Validator interface {
 	Validate() error
 }
 
-func (h *httpServer) StartServer(stopChan chan bool, mm module.ModuleManager, authOn bool, authToken string) {
+func (h *httpServer) StartServer(stopChan chan bool, mm module.ModuleManager, channels server.ChannelRouter, authOn bool, authToken string) {
 	h.authToken = authToken
 	h.authOn = authOn
+	h.channels = channels
+	h.channelConns = make(map[string]*grpc.ClientConn)
 
 	// modules
 	playModule := mm.GetModule(playprovider.ModuleName).(playprovider.ProviderI)
-	//outputModule := mm.GetModule(outputprovider.ModuleName).(outputprovider.ProviderI)
-	//pluginModule := mm.GetModule(pluginprovider.ModuleName).(pluginprovider.ProviderI)
-	//resourceModule := mm.GetModule(resourceprovider.ModuleName).(resourceprovider.ProviderI)
 
 	grpcEndpoint := fmt.Sprintf("%s:%d", playModule.GetRPCParams().Address, playModule.GetRPCParams().GrpcPort)
 	httpEndpoint := fmt.Sprintf("%s:%d", playModule.GetRPCParams().Address, playModule.GetRPCParams().HttpPort)
@@ -83,7 +88,7 @@ func (h *httpServer) StartServer(stopChan chan bool, mm module.ModuleManager, au
 	grpcSvc := grpc.NewServer(
 		grpc_middleware.WithUnaryServerChain(
 			grpc_recovery.UnaryServerInterceptor(opts...),
-			grpc_middleware.ChainUnaryServer(reqValidatorInterceptor),
+			grpc_middleware.ChainUnaryServer(reqValidatorInterceptor, h.channelInterceptor),
 		),
 		grpc_middleware.WithStreamServerChain(grpc_recovery.StreamServerInterceptor(opts...)),
 	)
@@ -96,15 +101,11 @@ func (h *httpServer) StartServer(stopChan chan bool, mm module.ModuleManager, au
 			log.WithField("error", err).Fatal("start grpc gateway server failed")
 		}
 
-		playServer := mm.GetModule(playprovider.ModuleName).(server.PlayGreeterServer)
-		outputServer := mm.GetModule(outputprovider.ModuleName).(server.OutputGreeterServer)
-		pluginServer := mm.GetModule(pluginprovider.ModuleName).(server.PluginGreeterServer)
-		resourceServer := mm.GetModule(resourceprovider.ModuleName).(server.ResourceGreeterServer)
-
-		server.RegisterPlayGreeterServer(grpcSvc, playServer)
-		server.RegisterOutputGreeterServer(grpcSvc, outputServer)
-		server.RegisterPluginGreeterServer(grpcSvc, pluginServer)
-		server.RegisterResourceGreeterServer(grpcSvc, resourceServer)
+		for _, m := range mm.GetOrderedModules() {
+			if serviceModule, ok := m.(module.ServiceAppModule); ok {
+				serviceModule.RegisterGrpcService(grpcSvc)
+			}
+		}
 
 		err = grpcSvc.Serve(listen)
 		if err != nil {
@@ -140,7 +141,7 @@ func (h *httpServer) StartServer(stopChan chan bool, mm module.ModuleManager, au
 			runtime.WithErrorHandler(protoErrorHandle),
 			runtime.WithIncomingHeaderMatcher(func(key string) (string, bool) {
 				switch key {
-				case server.AUTHORIZATION_METADATA_KEY:
+				case server.AUTHORIZATION_METADATA_KEY, server.CHANNEL_METADATA_KEY:
 					return key, true
 				}
 				return "", false
@@ -170,19 +171,34 @@ func (h *httpServer) StartServer(stopChan chan bool, mm module.ModuleManager, au
 				}
 			}
 
-			// subscribe message
+			// subscribe message. the actions and the backpressure policy can be chosen by query
 			websocketName := "websocket-" + conn.RemoteAddr().String()
-			sub, err := cmd.SubscribeMessage(websocketName)
+			subscribeOpts, err := parseSubscribeOptions(r)
+			if err != nil {
+				conn.WriteMessage(websocket.TextMessage, []byte(err.Error()))
+				return
+			}
+			sub, err := eventbus.Subscribe(websocketName, subscribeOpts...)
 			if err != nil {
 				log.WithFields(log.Fields{"error": err, "address": conn.RemoteAddr()}).Error("subscribe message failed")
+				return
 			}
-			defer cmd.CancelSubscribeMessage(websocketName)
-			for {
-				message := <-sub
-				jsonRawMessage, err := kptypes.ParseMessageToJson(&message)
-				if err != nil {
-					log.WithFields(log.Fields{"error": err, "message": message}).Fatal("message cannot encode to json")
-					break
+			defer sub.Close()
+			for envelope := range sub.C() {
+				var jsonRawMessage string
+				switch envelope.Kind {
+				case eventbus.KindMessage:
+					jsonRawMessage, err = kptypes.ParseCorrelatedMessageToJson(envelope.Message.KPMessage, envelope.Message.CorrelationId)
+					if err != nil {
+						log.WithFields(log.Fields{"error": err, "message": envelope.Message}).Fatal("message cannot encode to json")
+					}
+				case eventbus.KindEvent:
+					data, err := json.Marshal(envelope.Event)
+					if err != nil {
+						log.WithFields(log.Fields{"error": err, "event": envelope.Event}).Error("event cannot encode to json")
+						continue
+					}
+					jsonRawMessage = string(data)
 				}
 
 				err = conn.WriteMessage(websocket.TextMessage, []byte(jsonRawMessage))
@@ -192,22 +208,27 @@ func (h *httpServer) StartServer(stopChan chan bool, mm module.ModuleManager, au
 				}
 			}
 		})
+		mux.HandlePath("GET", "/eventbus/metrics", func(w http.ResponseWriter, r *http.Request, pathParams map[string]string) {
+			if h.authOn && r.Header.Get(server.AUTHORIZATION_METADATA_KEY) != h.authToken {
+				http.Error(w, autherror.AuthTokenInvalid.Error(), http.StatusUnauthorized)
+				return
+			}
+
+			data, err := json.Marshal(eventbus.GetMetrics())
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write(data)
+		})
 		opts := []grpc.DialOption{grpc.WithTransportCredentials(insecure.NewCredentials())}
-		err = server.RegisterPlayGreeterHandlerFromEndpoint(ctx, mux, grpcEndpoint, opts)
-		if err != nil {
-			log.WithField("error", err).Panic("register grpc gateway server failed")
-		}
-		err = server.RegisterOutputGreeterHandlerFromEndpoint(ctx, mux, grpcEndpoint, opts)
-		if err != nil {
-			log.WithField("error", err).Panic("register grpc gateway server failed")
-		}
-		err = server.RegisterPluginGreeterHandlerFromEndpoint(ctx, mux, grpcEndpoint, opts)
-		if err != nil {
-			log.WithField("error", err).Panic("register grpc gateway server failed")
-		}
-		err = server.RegisterResourceGreeterHandlerFromEndpoint(ctx, mux, grpcEndpoint, opts)
-		if err != nil {
-			log.WithField("error", err).Panic("register grpc gateway server failed")
+		for _, m := range mm.GetOrderedModules() {
+			if serviceModule, ok := m.(module.ServiceAppModule); ok {
+				if err := serviceModule.RegisterGatewayHandler(ctx, mux, grpcEndpoint, opts); err != nil {
+					log.WithFields(log.Fields{"module": m.GetModuleName(), "error": err}).Panic("register grpc gateway server failed")
+				}
+			}
 		}
 
 		p, _ := runtime.NewPattern(1, []int{2, 0, 2, 1, 4, 1, 5, 2}, []string{"v1", "operations", "name"}, "")
@@ -215,7 +236,7 @@ func (h *httpServer) StartServer(stopChan chan bool, mm module.ModuleManager, au
 			w.Write([]byte("hello"))
 			w.WriteHeader(200)
 		})
-		httpSvc.Handler = mux
+		httpSvc.Handler = h.channelHandler(mux)
 		httpSvc.Addr = httpEndpoint
 
 		// Start http server
@@ -230,6 +251,7 @@ func (h *httpServer) StartServer(stopChan chan bool, mm module.ModuleManager, au
 	<-stopChan
 	grpcSvc.Stop()
 	_ = httpSvc.Close()
+	h.closeChannelConns()
 }
 
 func protoErrorHandle(ctx context.Context, mux *runtime.ServeMux, marshaler runtime.Marshaler, writer http.ResponseWriter, request *http.Request, err error) {
@@ -264,3 +286,31 @@ func protoErrorHandle(ctx context.Context, mux *runtime.ServeMux, marshaler runt
 	writer.WriteHeader(runtime.HTTPStatusFromCode(s.Code()))
 	_, _ = writer.Write(buf)
 }
+
+// parseSubscribeOptions read the subscribe options of websocket from query. such as
+// /websocket?actions=EVENT_MESSAGE_ACTION_RESOURCE_START,EVENT_MESSAGE_ACTION_RESOURCE_FINISH&policy=drop_newest
+func parseSubscribeOptions(r *http.Request) ([]eventbus.SubscribeOption, error) {
+	var opts []eventbus.SubscribeOption
+
+	query := r.URL.Query()
+	if actions := query.Get("actions"); actions != "" {
+		var filter []kpproto.EventMessageAction
+		for _, item := range strings.Split(actions, ",") {
+			action, ok := kpproto.EventMessageAction_value[strings.TrimSpace(item)]
+			if !ok {
+				return nil, fmt.Errorf("message action invalid: %s", item)
+			}
+			filter = append(filter, kpproto.EventMessageAction(action))
+		}
+		opts = append(opts, eventbus.WithActions(filter...))
+	}
+	if policyName := query.Get("policy"); policyName != "" {
+		policy, err := eventbus.ParsePolicy(policyName)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, eventbus.WithPolicy(policy))
+	}
+
+	return opts, nil
+}
@@ -0,0 +1,248 @@
+package server
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/bytelang/kplayer/app"
+	"github.com/bytelang/kplayer/core"
+	"github.com/bytelang/kplayer/eventbus"
+	"github.com/bytelang/kplayer/module"
+	kptypes "github.com/bytelang/kplayer/types"
+	kpproto "github.com/bytelang/kplayer/types/core/proto"
+	log "github.com/sirupsen/logrus"
+)
+
+// the resource playing through the tests, long enough not to be finished by the clock advanced
+const testPlayingResource = "playing.flv"
+
+var (
+	testEngine        *core.FakeEngine
+	testModuleManager module.ModuleManager
+	testEngineResult  chan int
+)
+
+// TestMain serve the grpc gateway in process. the modules run on the fake engine, the tests move its clock by advance
+func TestMain(m *testing.M) {
+	os.Exit(runTestServer(m))
+}
+
+func runTestServer(m *testing.M) int {
+	wd, err := os.Getwd()
+	if err != nil {
+		log.Fatal(err)
+	}
+	home, err := ioutil.TempDir("", "kplayer-server-test")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer os.RemoveAll(home)
+	if err := os.Chdir(home); err != nil {
+		log.Fatal(err)
+	}
+	defer os.Chdir(wd)
+
+	grpcPort, httpPort := getFreePort(), getFreePort()
+	files := map[string]string{
+		"config.yaml": fmt.Sprintf(`version: 2.0.0
+play:
+  play_model: loop
+  rpc:
+    address: 127.0.0.1
+    grpc_port: %d
+    http_port: %d
+resource:
+  lists:
+  - %s
+`, grpcPort, httpPort, testPlayingResource),
+		testPlayingResource:    "",
+		"short.flv":            "",
+		"show-time":            "",
+		"plugin/show-time.kpe": "",
+		"resource/font.ttf":    "",
+	}
+	for name, content := range files {
+		if err := os.MkdirAll(filepath.Dir(name), os.ModePerm); err != nil {
+			log.Fatal(err)
+		}
+		if err := ioutil.WriteFile(name, []byte(content), 0644); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	testEngine = core.NewFakeEngine()
+	testEngine.SetResourceDuration(testPlayingResource, time.Hour*24)
+	testEngine.SetOutputError("/invalid", "open output failed")
+	// the core serving the api tests refuses to stop, the playback goes on for the following tests
+	testEngine.SetPromptError(kpproto.EventPromptAction_EVENT_PROMPT_ACTION_PLAYER_STOP, "player cannot be stopped")
+	testModuleManager = app.NewModuleManager(testEngine)
+	initTestModules(testModuleManager)
+
+	testEngine.SetCallBackMessage(func(message *core.Message) {
+		for _, item := range testModuleManager.GetOrderedModules() {
+			copyMsg := *message.KPMessage
+			item.ParseMessage(&copyMsg)
+
+			copyMsg = *message.KPMessage
+			item.TriggerMessage(&core.Message{KPMessage: &copyMsg, CorrelationId: message.CorrelationId})
+		}
+		eventbus.PublishMessage(message)
+	})
+	testEngine.SetCallBackProgress(func(percent float64, bitRate int) {
+		for _, item := range testModuleManager.GetOrderedModules() {
+			if m, ok := item.(module.ProgressAppModule); ok {
+				m.ParseProgress(percent, bitRate)
+			}
+		}
+	})
+
+	testModuleManager.BeginRunning()
+	defer testModuleManager.EndRunning()
+	runTestEngine()
+
+	stopChan := make(chan bool)
+	go NewHttpServer().StartServer(stopChan, testModuleManager, nil, false, "")
+	Host = fmt.Sprintf("http://127.0.0.1:%d/", httpPort)
+	waitTestServer(httpPort)
+
+	code := m.Run()
+
+	testEngine.Terminate(0)
+	<-testEngineResult
+	return code
+}
+
+// TestPlayStopRestart the core stopped is started again as restarted by watchdog
+func TestPlayStopRestart(t *testing.T) {
+	testEngine.SetPromptError(kpproto.EventPromptAction_EVENT_PROMPT_ACTION_PLAYER_STOP, "")
+	defer testEngine.SetPromptError(kpproto.EventPromptAction_EVENT_PROMPT_ACTION_PLAYER_STOP, "player cannot be stopped")
+
+	req, err := http.NewRequest("POST", Host+"play/stop", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := getClient().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatal(string(body))
+	}
+
+	t.Log(string(body))
+
+	// the following tests run on the core started again
+	restartTestEngine(t)
+}
+
+// initTestModules initialize the modules with the config file in working directory
+func initTestModules(mm module.ModuleManager) {
+	loadedConfig, err := app.LoadConfig(app.DefaultConfigFileName, false)
+	if err != nil {
+		log.Fatal(err)
+	}
+	clientCtx := kptypes.DefaultClientContext()
+	clientCtx.Viper = loadedConfig.Viper
+	clientCtx.Config = loadedConfig.Config
+
+	for _, m := range mm.GetOrderedModules() {
+		section, err := loadedConfig.GetModuleSection(m.GetModuleName())
+		if err != nil {
+			log.Fatal(err)
+		}
+		modifyData, err := m.InitConfig(clientCtx, section)
+		if err != nil {
+			log.Fatal(err)
+		}
+		clientCtx.Viper.Set(m.GetModuleName(), modifyData)
+		if err := m.ValidateConfig(); err != nil {
+			log.Fatal(err)
+		}
+	}
+}
+
+// runTestEngine run the fake engine and wait for the resource playing
+func runTestEngine() {
+	sub, err := eventbus.Subscribe("server-test", eventbus.WithKinds(eventbus.KindMessage))
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer sub.Close()
+
+	testEngineResult = make(chan int, 1)
+	go func() {
+		testEngineResult <- testEngine.Run()
+	}()
+
+	timeout := time.After(time.Second * 5)
+	for {
+		select {
+		case envelope := <-sub.C():
+			if envelope.Message.Action == kpproto.EventMessageAction_EVENT_MESSAGE_ACTION_RESOURCE_START {
+				return
+			}
+		case <-timeout:
+			log.Fatal("wait for the test resource playing timeout")
+		}
+	}
+}
+
+// restartTestEngine run the stopped engine again. the modules restore their state as on the core restarted by watchdog
+func restartTestEngine(t *testing.T) {
+	select {
+	case <-testEngineResult:
+	case <-time.After(time.Second * 5):
+		t.Fatal("wait for the engine stopped timeout")
+	}
+
+	testEngine.Initialization()
+	for _, m := range testModuleManager.GetOrderedModules() {
+		if resumeModule, ok := m.(module.ResumeAppModule); ok {
+			resumeModule.ResumeRunning()
+		}
+	}
+	runTestEngine()
+}
+
+// advance move the clock of engine forward, then wait for the messages delivered to modules
+func advance(d time.Duration) {
+	testEngine.Advance(d)
+	time.Sleep(time.Millisecond * 100)
+}
+
+func getFreePort() int {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer listener.Close()
+
+	return listener.Addr().(*net.TCPAddr).Port
+}
+
+func waitTestServer(port int) {
+	deadline := time.Now().Add(time.Second * 5)
+	for time.Now().Before(deadline) {
+		conn, err := net.Dial("tcp", fmt.Sprintf("127.0.0.1:%d", port))
+		if err == nil {
+			conn.Close()
+			return
+		}
+		time.Sleep(time.Millisecond * 50)
+	}
+
+	log.Fatal("wait for the test server listening timeout")
+}
@@ -9,7 +9,8 @@ import (
 	"time"
 )
 
-const Host = "http://127.0.0.1:4156/"
+// Host the http gateway address the tests request
+var Host = "http://127.0.0.1:4156/"
 
 func assertError(t *testing.T, err error, msg ...string) {
 	if err != nil {
@@ -25,17 +25,17 @@ func (ae ApiError) Error() string {
 	return string(ae)
 }
 
-func GetTlsHttpClient() *http.Client {
+func GetTlsHttpClient() (*http.Client, error) {
 	config, err := GetTlsClientConfig()
 	if err != nil {
-		panic(err)
+		return nil, err
 	}
 
 	transPort := &http.Transport{
 		TLSClientConfig: config,
 	}
 
-	return &http.Client{Transport: transPort, Timeout: time.Second * 10}
+	return &http.Client{Transport: transPort, Timeout: time.Second * 10}, nil
 }
 
 func GetApiRequestUrl(path string) string {
@@ -60,7 +60,11 @@ func RequestHttpGet(host string, params proto.Message, message proto.Message) er
 	}
 
 	// request
-	resp, err := GetTlsHttpClient().Get(fmt.Sprintf("%s?%s", host, query))
+	client, err := GetTlsHttpClient()
+	if err != nil {
+		return err
+	}
+	resp, err := client.Get(fmt.Sprintf("%s?%s", host, query))
 	if err != nil {
 		return err
 	}
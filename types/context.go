@@ -21,6 +21,8 @@ const (
 	ClientContextKey        KplayerContextKey = "client.context"
 	ModuleManagerContextKey KplayerContextKey = "module.manager"
 	ServerCreatorContextKey KplayerContextKey = "server.creator"
+
+	ConfigReloaderContextKey KplayerContextKey = "config.reloader"
 )
 
 type ClientContext struct {
@@ -29,6 +31,11 @@ type ClientContext struct {
 	Config *config.KPConfig
 }
 
+// ConfigReloader reload the config file of running kplayer until the context done
+type ConfigReloader interface {
+	WatchConfig(ctx context.Context, configFileName string)
+}
+
 func DefaultClientContext() *ClientContext {
 	return &ClientContext{
 		Output: os.Stdout,
@@ -6,8 +6,7 @@ import (
 )
 
 // GetCorePluginVersion
-func GetCorePluginVersion() string {
-	coreKplayer := core.GetLibKplayerInstance()
+func GetCorePluginVersion(coreKplayer core.Engine) string {
 	version := coreKplayer.GetInformation().PluginVersion
 	versionArr := strings.Split(version, ".")
 	for key, item := range versionArr {
@@ -25,6 +25,24 @@ const (
 	DefaultRPCAddress string = "127.0.0.1"
 	DefaultHttpPort   uint32 = 4156
 	DefaultRPCPort    uint32 = 4155
+	DefaultRPCTimeout uint32 = 30
+)
+
+const (
+	DefaultWatchdogMaxRestarts  uint32 = 5
+	DefaultWatchdogStallTimeout uint32 = 30
+	DefaultWatchdogMaxBackoff   uint32 = 60
+)
+
+const (
+	DefaultHotFolderScanInterval uint32 = 2
+	DefaultHotFolderStableTime   uint32 = 5
+)
+
+const (
+	DefaultLiveMaxRetries    uint32 = 5
+	DefaultLiveRetryInterval uint32 = 5
+	DefaultLiveMaxBackoff    uint32 = 60
 )
 
 // ErrorCode contains the exit code for server exit.
@@ -1,6 +1,8 @@
 package types
 
 import (
+	"encoding/json"
+
 	kpproto "github.com/bytelang/kplayer/types/core/proto"
 	"github.com/bytelang/kplayer/types/core/proto/msg"
 	"github.com/gogo/protobuf/proto"
@@ -42,3 +44,27 @@ type messageJson struct {
 func ParseMessageToJson(message proto.Message) (string, error) {
 	return MarshalProtoMessage(message)
 }
+
+// ParseCorrelatedMessageToJson encode message with the correlation id of the prompt which caused it
+func ParseCorrelatedMessageToJson(message proto.Message, correlationId string) (string, error) {
+	data, err := MarshalProtoMessage(message)
+	if err != nil {
+		return "", err
+	}
+	if correlationId == "" {
+		return data, nil
+	}
+
+	fields := map[string]json.RawMessage{}
+	if err := json.Unmarshal([]byte(data), &fields); err != nil {
+		return "", err
+	}
+	fields["correlation_id"], _ = json.Marshal(correlationId)
+
+	result, err := json.Marshal(fields)
+	if err != nil {
+		return "", err
+	}
+
+	return string(result), nil
+}
@@ -6,6 +6,14 @@ import (
 
 const AUTHORIZATION_METADATA_KEY = "Authorization"
 
+// CHANNEL_METADATA_KEY route the grpc request to the named channel
+const CHANNEL_METADATA_KEY = "Kplayer-Channel"
+
+// ChannelRouter resolve the rpc endpoints of the named channels
+type ChannelRouter interface {
+	GetChannelEndpoint(name string) (grpcEndpoint string, httpEndpoint string, err error)
+}
+
 type ServerCreator interface {
-	StartServer(stopChan chan bool, mm module.ModuleManager, authOn bool, authToken string)
+	StartServer(stopChan chan bool, mm module.ModuleManager, channels ChannelRouter, authOn bool, authToken string)
 }
@@ -27,6 +27,12 @@ var (
 )
 
 func init() {
+	// secrets are injected by ldflags on release build. go test and development builds run without them
+	if len(CipherKey) == 0 {
+		log.Warn("tls secrets are not injected on build. the requests of api server are unavailable")
+		return
+	}
+
 	RawTlsRootCert = TlsSecretDecode(TlsRootCert)
 	if err := LoadClientToken(TlsClientToken); err != nil {
 		log.Fatalf("cannot load client default token: %s", err)
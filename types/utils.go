@@ -302,6 +302,14 @@ func GetResourceItemByAny(item *anypb.Any) (interface{}, error) {
 		}
 	}
 
+	// directory resource
+	{
+		directoryResource := &config.DirectoryResource{}
+		if err = ptypes.UnmarshalAny(item, directoryResource); err == nil {
+			return directoryResource, nil
+		}
+	}
+
 	return nil, fmt.Errorf("any type unmarshal failed. %s", err)
 }
 